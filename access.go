@@ -0,0 +1,49 @@
+package toolcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithAccessTracking records when each entry was last read and how many
+// times, reported as EntryInfo.LastAccess and AccessCount by Snapshot and
+// carried through WriteSnapshot and ReadSnapshot. Use it to find cold
+// entries and tune TTLs. Each hit then costs two atomic updates; Set does
+// not count as an access.
+func WithAccessTracking() MemoryCacheOption {
+	return func(c *MemoryCache) {
+		c.trackAccess = true
+	}
+}
+
+// accessStats holds an entry's recency and frequency counters. They are
+// updated atomically because hits hold only the read lock.
+type accessStats struct {
+	lastAccess  atomic.Int64 // Unix nanoseconds; 0 if never read
+	accessCount atomic.Uint64
+}
+
+// touch records a read of entry at now if tracking is enabled.
+func (c *MemoryCache) touch(entry *cacheEntry, now time.Time) {
+	if c.trackAccess {
+		entry.access.lastAccess.Store(now.UnixNano())
+		entry.access.accessCount.Add(1)
+	}
+}
+
+// load returns the recorded last access time and count.
+func (a *accessStats) load() (time.Time, uint64) {
+	var last time.Time
+	if ns := a.lastAccess.Load(); ns != 0 {
+		last = time.Unix(0, ns).UTC()
+	}
+	return last, a.accessCount.Load()
+}
+
+// restore sets the counters, e.g. from a snapshot.
+func (a *accessStats) restore(last time.Time, count uint64) {
+	if !last.IsZero() {
+		a.lastAccess.Store(last.UnixNano())
+	}
+	a.accessCount.Store(count)
+}
@@ -0,0 +1,95 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessTracking_Snapshot(t *testing.T) {
+	for name, opts := range map[string][]MemoryCacheOption{
+		"default": nil,
+		"pooled":  {WithEntryPooling()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			clock := NewManualClock(start)
+			c := NewMemoryCacheWithOptions(DefaultPolicy(), append(opts, WithClock(clock), WithAccessTracking())...)
+			ctx := context.Background()
+
+			_ = c.Set(ctx, "cold", []byte("c"), time.Hour)
+			_ = c.Set(ctx, "hot", []byte("h"), time.Hour)
+			for range 3 {
+				clock.Advance(time.Second)
+				_, _ = c.Get(ctx, "hot")
+			}
+			_, _ = c.GetString(ctx, "hot")
+
+			infos, err := c.Snapshot(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			byKey := make(map[string]EntryInfo, len(infos))
+			for _, info := range infos {
+				byKey[info.Key] = info
+			}
+			if cold := byKey["cold"]; cold.AccessCount != 0 || !cold.LastAccess.IsZero() {
+				t.Errorf("cold = %+v, want no accesses", cold)
+			}
+			hot := byKey["hot"]
+			if hot.AccessCount != 4 {
+				t.Errorf("hot AccessCount = %d, want 4", hot.AccessCount)
+			}
+			if want := start.Add(3 * time.Second); !hot.LastAccess.Equal(want) {
+				t.Errorf("hot LastAccess = %v, want %v", hot.LastAccess, want)
+			}
+		})
+	}
+}
+
+func TestAccessTracking_DisabledByDefault(t *testing.T) {
+	c := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	_ = c.Set(ctx, "k", []byte("v"), time.Hour)
+	_, _ = c.Get(ctx, "k")
+
+	infos, _ := c.Snapshot(ctx)
+	if len(infos) != 1 || infos[0].AccessCount != 0 || !infos[0].LastAccess.IsZero() {
+		t.Errorf("Snapshot() = %+v, want no access data without tracking", infos)
+	}
+	data, _ := json.Marshal(infos[0])
+	if strings.Contains(string(data), "last_access") || strings.Contains(string(data), "access_count") {
+		t.Errorf("untracked fields should be omitted: %s", data)
+	}
+}
+
+func TestAccessTracking_SnapshotRoundTrip(t *testing.T) {
+	clock := NewManualClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	src := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(clock), WithAccessTracking())
+	ctx := context.Background()
+	_ = src.Set(ctx, "k", []byte("v"), time.Hour)
+	clock.Advance(time.Minute)
+	_, _ = src.Get(ctx, "k")
+	_, _ = src.Get(ctx, "k")
+
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"access_count":2`) || !strings.Contains(buf.String(), `"last_access":"2026-01-01T00:01:00Z"`) {
+		t.Errorf("snapshot missing access data: %s", buf.String())
+	}
+
+	dst := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(clock), WithAccessTracking())
+	if _, err := dst.ReadSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = dst.Get(ctx, "k")
+	infos, _ := dst.Snapshot(ctx)
+	if len(infos) != 1 || infos[0].AccessCount != 3 {
+		t.Errorf("restored entry = %+v, want counts carried over", infos)
+	}
+}
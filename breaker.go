@@ -0,0 +1,158 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrExecutorCircuitOpen is returned (wrapped) when a tool's executor
+// breaker is open and the call is short-circuited without running it.
+var ErrExecutorCircuitOpen = errors.New("toolcache: executor circuit open")
+
+// WithExecutorBreaker enables a per-tool circuit around the executor. After
+// threshold consecutive executor errors for a tool, calls that would run
+// its executor fail fast with ErrExecutorCircuitOpen for cooldown. Cache
+// hits are still served while the circuit is open. Once cooldown elapses a
+// single trial call is let through: success closes the circuit, failure
+// re-opens it for another cooldown.
+//
+// Context cancellation and deadline errors do not count as failures; a
+// trial call ending with one, or panicking, lets the next call try again.
+func WithExecutorBreaker(threshold int, cooldown time.Duration) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		if threshold < 1 {
+			m.breaker = nil
+			return
+		}
+		m.breaker = &executorBreaker{
+			threshold: threshold,
+			cooldown:  cooldown,
+			tools:     make(map[string]*toolBreaker),
+		}
+	}
+}
+
+type toolBreaker struct {
+	failures  int
+	openUntil time.Time
+	trial     bool // a half-open trial call is in flight
+}
+
+// executorBreaker tracks consecutive executor failures per tool. Only tools
+// with at least one outstanding failure are tracked.
+type executorBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	tools     map[string]*toolBreaker
+}
+
+// allow reports whether the executor for toolID may run now.
+func (b *executorBreaker) allow(toolID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tb, ok := b.tools[toolID]
+	if !ok || tb.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(tb.openUntil) || tb.trial {
+		return false
+	}
+	tb.trial = true
+	return true
+}
+
+func (b *executorBreaker) record(toolID string, err error) {
+	if isContextError(err) {
+		b.endTrial(toolID)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil || isDoNotCache(err) || errors.Is(err, ErrNotModified) {
+		delete(b.tools, toolID)
+		return
+	}
+
+	tb, ok := b.tools[toolID]
+	if !ok {
+		tb = &toolBreaker{}
+		b.tools[toolID] = tb
+	}
+	tb.failures++
+	tb.trial = false
+	if tb.failures >= b.threshold {
+		tb.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// endTrial lets another trial call through after one ended without a
+// verdict, such as a canceled or panicking call.
+func (b *executorBreaker) endTrial(toolID string) {
+	b.mu.Lock()
+	if tb, ok := b.tools[toolID]; ok {
+		tb.trial = false
+	}
+	b.mu.Unlock()
+}
+
+func (b *executorBreaker) isOpen(toolID string) bool {
+	tb, ok := b.tools[toolID]
+	return ok && tb.failures >= b.threshold
+}
+
+func (b *executorBreaker) reset(toolID string) {
+	b.mu.Lock()
+	delete(b.tools, toolID)
+	b.mu.Unlock()
+}
+
+// ResetExecutorBreaker closes the executor circuit for toolID immediately.
+func (m *CacheMiddleware) ResetExecutorBreaker(toolID string) {
+	if m.breaker != nil {
+		m.breaker.reset(toolID)
+	}
+}
+
+// runExecutor runs executor through the executor breaker, if enabled.
+func (m *CacheMiddleware) runExecutor(ctx context.Context, toolID string, input any, executor ToolExecutor) ([]byte, error) {
+	if m.breaker == nil {
+		return executor(ctx, toolID, input)
+	}
+	if !m.breaker.allow(toolID) {
+		m.stats.record(toolID, ToolStats{ShortCircuits: 1})
+		return nil, fmt.Errorf("toolcache: tool %q: %w", toolID, ErrExecutorCircuitOpen)
+	}
+	recorded := false
+	defer func() {
+		if !recorded {
+			m.breaker.endTrial(toolID)
+		}
+	}()
+	result, err := executor(ctx, toolID, input)
+	recorded = true
+	m.breaker.record(toolID, err)
+	return result, err
+}
+
+// markOpenCircuits sets CircuitOpen for tools whose breaker is open.
+func (m *CacheMiddleware) markOpenCircuits(perTool map[string]ToolStats) {
+	if m.breaker == nil {
+		return
+	}
+	m.breaker.mu.Lock()
+	defer m.breaker.mu.Unlock()
+	for toolID := range m.breaker.tools {
+		if m.breaker.isOpen(toolID) {
+			stats := perTool[toolID]
+			stats.CircuitOpen = true
+			perTool[toolID] = stats
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errBackend = errors.New("backend down")
+
+func TestExecutorBreaker_TripsAfterThreshold(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithExecutorBreaker(2, time.Hour))
+	exec := &mockExecutor{err: errBackend}
+	ctx := context.Background()
+
+	for i := range 2 {
+		if _, err := mw.Execute(ctx, "flaky", map[string]any{"i": i}, nil, exec.execute); !errors.Is(err, errBackend) {
+			t.Fatalf("call %d: expected backend error, got %v", i, err)
+		}
+	}
+
+	_, err := mw.Execute(ctx, "flaky", map[string]any{"i": 99}, nil, exec.execute)
+	if !errors.Is(err, ErrExecutorCircuitOpen) {
+		t.Fatalf("expected ErrExecutorCircuitOpen, got %v", err)
+	}
+	if exec.calls != 2 {
+		t.Errorf("executor should not run while open, got %d calls", exec.calls)
+	}
+
+	stats := mw.PerToolStats()["flaky"]
+	if !stats.CircuitOpen {
+		t.Error("expected CircuitOpen in PerToolStats")
+	}
+	if stats.ShortCircuits != 1 {
+		t.Errorf("ShortCircuits = %d, want 1", stats.ShortCircuits)
+	}
+}
+
+func TestExecutorBreaker_PerTool(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithExecutorBreaker(1, time.Hour))
+	ctx := context.Background()
+
+	bad := &mockExecutor{err: errBackend}
+	_, _ = mw.Execute(ctx, "bad", "x", nil, bad.execute)
+
+	good := &mockExecutor{result: []byte("ok")}
+	if _, err := mw.Execute(ctx, "good", "x", nil, good.execute); err != nil {
+		t.Fatalf("other tool should be unaffected: %v", err)
+	}
+	if mw.PerToolStats()["good"].CircuitOpen {
+		t.Error("good tool should not report an open circuit")
+	}
+}
+
+func TestExecutorBreaker_ServesHitsWhileOpen(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithExecutorBreaker(1, time.Hour))
+	ctx := context.Background()
+
+	ok := &mockExecutor{result: []byte("cached")}
+	if _, err := mw.Execute(ctx, "t", "warm", nil, ok.execute); err != nil {
+		t.Fatal(err)
+	}
+	bad := &mockExecutor{err: errBackend}
+	_, _ = mw.Execute(ctx, "t", "cold", nil, bad.execute)
+
+	got, err := mw.Execute(ctx, "t", "warm", nil, bad.execute)
+	if err != nil || string(got) != "cached" {
+		t.Fatalf("expected cached hit while open, got %q, %v", got, err)
+	}
+}
+
+func TestExecutorBreaker_HalfOpenAfterCooldown(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithExecutorBreaker(1, 20*time.Millisecond))
+	ctx := context.Background()
+
+	exec := &mockExecutor{err: errBackend}
+	_, _ = mw.Execute(ctx, "t", 1, nil, exec.execute)
+	time.Sleep(30 * time.Millisecond)
+
+	// Trial call fails: circuit re-opens.
+	if _, err := mw.Execute(ctx, "t", 2, nil, exec.execute); !errors.Is(err, errBackend) {
+		t.Fatalf("expected trial call to run, got %v", err)
+	}
+	if _, err := mw.Execute(ctx, "t", 3, nil, exec.execute); !errors.Is(err, ErrExecutorCircuitOpen) {
+		t.Fatalf("expected circuit re-opened, got %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	exec.err, exec.result = nil, []byte("ok")
+	if _, err := mw.Execute(ctx, "t", 4, nil, exec.execute); err != nil {
+		t.Fatalf("trial call should succeed: %v", err)
+	}
+	if mw.PerToolStats()["t"].CircuitOpen {
+		t.Error("successful trial should close the circuit")
+	}
+}
+
+func TestExecutorBreaker_Reset(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithExecutorBreaker(1, time.Hour))
+	ctx := context.Background()
+
+	exec := &mockExecutor{err: errBackend}
+	_, _ = mw.Execute(ctx, "t", 1, nil, exec.execute)
+	mw.ResetExecutorBreaker("t")
+
+	exec.err, exec.result = nil, []byte("ok")
+	if _, err := mw.Execute(ctx, "t", 2, nil, exec.execute); err != nil {
+		t.Fatalf("expected reset circuit to allow calls: %v", err)
+	}
+}
+
+func TestExecutorBreaker_IgnoresContextErrors(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithExecutorBreaker(1, time.Hour))
+	ctx := context.Background()
+
+	exec := &mockExecutor{err: context.DeadlineExceeded}
+	_, _ = mw.Execute(ctx, "t", 1, nil, exec.execute)
+
+	exec.err, exec.result = nil, []byte("ok")
+	if _, err := mw.Execute(ctx, "t", 2, nil, exec.execute); err != nil {
+		t.Fatalf("context errors should not trip the breaker: %v", err)
+	}
+}
+
+func TestExecutorBreaker_NotNegativelyCached(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), negativePolicy(), nil,
+		WithExecutorBreaker(1, time.Hour))
+	ctx := context.Background()
+
+	bad := &mockExecutor{err: errBackend}
+	_, _ = mw.Execute(ctx, "t", 1, nil, bad.execute)
+	_, _ = mw.Execute(ctx, "t", 2, nil, bad.execute) // short-circuited
+	mw.ResetExecutorBreaker("t")
+
+	good := &mockExecutor{result: []byte("ok")}
+	if _, err := mw.Execute(ctx, "t", 2, nil, good.execute); err != nil {
+		t.Fatalf("short-circuit error must not be cached: %v", err)
+	}
+}
+
+func TestExecutorBreaker_WarmAll(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithExecutorBreaker(1, time.Hour), WithWarmConcurrency(1))
+	exec := &mockExecutor{err: errBackend}
+
+	results := mw.WarmAll(context.Background(), []WarmRequest{
+		{ToolID: "t", Input: 1},
+		{ToolID: "t", Input: 2},
+	}, exec.execute)
+	if exec.calls != 1 {
+		t.Errorf("executor calls = %d, want 1: warm-up failures should trip the breaker", exec.calls)
+	}
+	if results[1].Status != WarmFailed || !errors.Is(results[1].Err, ErrExecutorCircuitOpen) {
+		t.Errorf("second request = %+v, want ErrExecutorCircuitOpen", results[1])
+	}
+}
+
+func TestExecutorBreaker_PanickingTrial(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithExecutorBreaker(1, 10*time.Millisecond))
+	ctx := context.Background()
+
+	exec := &mockExecutor{err: errBackend}
+	_, _ = mw.Execute(ctx, "t", 1, nil, exec.execute)
+	time.Sleep(20 * time.Millisecond)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected the trial executor's panic to propagate")
+			}
+		}()
+		_, _ = mw.Execute(ctx, "t", 2, nil, func(context.Context, string, any) ([]byte, error) {
+			panic("trial blew up")
+		})
+	}()
+
+	exec.err, exec.result = nil, []byte("ok")
+	if _, err := mw.Execute(ctx, "t", 3, nil, exec.execute); err != nil {
+		t.Fatalf("a panicking trial should not leave the circuit stuck open: %v", err)
+	}
+	if mw.PerToolStats()["t"].CircuitOpen {
+		t.Error("successful trial should close the circuit")
+	}
+}
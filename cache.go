@@ -8,9 +8,13 @@ import (
 )
 
 var (
-	ErrNilCache   = errors.New("toolcache: cache is nil")
-	ErrInvalidKey = errors.New("toolcache: key is invalid")
-	ErrKeyTooLong = errors.New("toolcache: key exceeds max length")
+	ErrNilCache      = errors.New("toolcache: cache is nil")
+	ErrInvalidKey    = errors.New("toolcache: key is invalid")
+	ErrKeyTooLong    = errors.New("toolcache: key exceeds max length")
+	ErrKeyerPanic    = errors.New("toolcache: keyer panicked")
+	ErrTooManyFields = errors.New("toolcache: input map exceeds field limit")
+	ErrTooDeep       = errors.New("toolcache: input exceeds max nesting depth")
+	ErrInvalidPolicy = errors.New("toolcache: policy is invalid")
 )
 
 const MaxKeyLength = 512
@@ -22,12 +26,79 @@ const MaxKeyLength = 512
 // - Context: methods must honor cancellation/deadlines and return ctx.Err() when canceled.
 // - Errors: invalid keys should return ErrInvalidKey/ErrKeyTooLong via ValidateKey.
 // - Ownership: returned bytes are caller-owned; implementations should copy on write.
+// - Presence: a stored nil or empty value is a hit, distinct from a miss.
 type Cache interface {
 	Get(ctx context.Context, key string) (value []byte, ok bool)
 	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
 	Delete(ctx context.Context, key string) error
 }
 
+// CacheFuncs adapts three closures to the Cache interface, mirroring
+// KeyerFunc, so tests and prototypes can stub a cache inline. A nil GetFunc
+// always misses; a nil SetFunc or DeleteFunc does nothing.
+type CacheFuncs struct {
+	GetFunc    func(ctx context.Context, key string) ([]byte, bool)
+	SetFunc    func(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	DeleteFunc func(ctx context.Context, key string) error
+}
+
+// Get calls GetFunc.
+func (f CacheFuncs) Get(ctx context.Context, key string) ([]byte, bool) {
+	if f.GetFunc == nil {
+		return nil, false
+	}
+	return f.GetFunc(ctx, key)
+}
+
+// Set calls SetFunc.
+func (f CacheFuncs) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if f.SetFunc == nil {
+		return nil
+	}
+	return f.SetFunc(ctx, key, value, ttl)
+}
+
+// Delete calls DeleteFunc.
+func (f CacheFuncs) Delete(ctx context.Context, key string) error {
+	if f.DeleteFunc == nil {
+		return nil
+	}
+	return f.DeleteFunc(ctx, key)
+}
+
+var _ Cache = CacheFuncs{}
+
+// EntryMeta is metadata stored alongside a cached value.
+type EntryMeta struct {
+	// ContentType is the media type of the value, e.g. "application/json".
+	ContentType string
+
+	// ETag identifies the version of the value for conditional
+	// revalidation; see WithETagRevalidation.
+	ETag string
+
+	// Version orders writes to the same key. When non-zero,
+	// MemoryCache.SetWithMeta rejects a write older than the live entry's
+	// version with ErrVersionConflict; zero means unversioned and always
+	// overwrites. See ErrVersionConflict for the full rule.
+	Version uint64
+
+	// Priority protects entries that are expensive to recompute. When
+	// MemoryCache evicts under WithMaxEntries or EvictFraction, entries of
+	// lower priority go first, regardless of recency or expiry. Zero is the
+	// default; negative priorities are evicted before it.
+	Priority int
+}
+
+// MetaCache is implemented by caches that can store EntryMeta alongside
+// values. The middleware uses it when available so metadata survives a
+// cache hit; with a plain Cache, hits return zero EntryMeta.
+type MetaCache interface {
+	Cache
+	GetWithMeta(ctx context.Context, key string) (value []byte, meta EntryMeta, ok bool)
+	SetWithMeta(ctx context.Context, key string, value []byte, meta EntryMeta, ttl time.Duration) error
+}
+
 func ValidateKey(key string) error {
 	if len(key) == 0 || len(strings.TrimSpace(key)) == 0 {
 		return ErrInvalidKey
@@ -2,6 +2,8 @@ package toolcache
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -102,3 +104,139 @@ func TestMaxKeyLength(t *testing.T) {
 		t.Errorf("MaxKeyLength = %d, want 512", MaxKeyLength)
 	}
 }
+
+func TestCacheFuncs_WithMiddleware(t *testing.T) {
+	store := map[string][]byte{}
+	var deleted []string
+	cache := CacheFuncs{
+		GetFunc: func(_ context.Context, key string) ([]byte, bool) {
+			v, ok := store[key]
+			return v, ok
+		},
+		SetFunc: func(_ context.Context, key string, value []byte, _ time.Duration) error {
+			store[key] = value
+			return nil
+		},
+		DeleteFunc: func(_ context.Context, key string) error {
+			deleted = append(deleted, key)
+			delete(store, key)
+			return nil
+		},
+	}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithRevalidate(func(context.Context, string, string, []byte) (bool, error) { return false, nil }))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if len(store) != 1 {
+		t.Fatalf("expected result stored through SetFunc, store has %d entries", len(store))
+	}
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if len(deleted) != 1 {
+		t.Errorf("expected stale entry removed through DeleteFunc, got %v", deleted)
+	}
+}
+
+func TestCacheFuncs_NilFuncs(t *testing.T) {
+	var cache CacheFuncs
+	ctx := context.Background()
+	if err := cache.Set(ctx, "k", []byte("v"), time.Minute); err != nil {
+		t.Errorf("nil SetFunc: %v", err)
+	}
+	if _, ok := cache.Get(ctx, "k"); ok {
+		t.Error("nil GetFunc should miss")
+	}
+	if err := cache.Delete(ctx, "k"); err != nil {
+		t.Errorf("nil DeleteFunc: %v", err)
+	}
+}
+
+// TestKeyErrors_EveryEntryPoint checks that every path accepting a key
+// reports invalid and oversized keys with errors matching the sentinels.
+func TestKeyErrors_EveryEntryPoint(t *testing.T) {
+	ctx := context.Background()
+	long := strings.Repeat("k", MaxKeyLength+1)
+	value := []byte("v")
+	executor := func(context.Context, string, any) ([]byte, error) { return value, nil }
+
+	entryPoints := map[string]func(key string) error{
+		"MemoryCache.Set": func(key string) error {
+			return NewMemoryCache(DefaultPolicy()).Set(ctx, key, value, time.Minute)
+		},
+		"MemoryCache.SetWithMeta": func(key string) error {
+			return NewMemoryCache(DefaultPolicy()).SetWithMeta(ctx, key, value, EntryMeta{}, time.Minute)
+		},
+		"MemoryCache.SetImmutable": func(key string) error {
+			return NewMemoryCache(DefaultPolicy()).SetImmutable(ctx, key, value, time.Minute)
+		},
+		"MemoryCache.SetThunk": func(key string) error {
+			compute := func(context.Context) ([]byte, error) { return value, nil }
+			return NewMemoryCache(DefaultPolicy()).SetThunk(ctx, key, compute, time.Minute, time.Minute)
+		},
+		"MemoryCache.SetWithDeps": func(key string) error {
+			return NewMemoryCache(DefaultPolicy()).SetWithDeps(ctx, key, value, time.Minute, []string{"parent"})
+		},
+		"MemoryCache.ReadSnapshot": func(key string) error {
+			doc := fmt.Sprintf(`{"version":1,"entries":[{"key":%q,"value":"dg==","expires_at":"2100-01-01T00:00:00Z"}]}`, key)
+			_, err := NewMemoryCache(DefaultPolicy()).ReadSnapshot(ctx, strings.NewReader(doc))
+			return err
+		},
+		"SegmentedCache.Set": func(key string) error {
+			return NewSegmentedCache().Set(ctx, key, value, time.Minute)
+		},
+		"ImmutableCacheBuilder.Build": func(key string) error {
+			_, err := NewImmutableCacheBuilder().Add(key, value).Build()
+			return err
+		},
+		"Memoizer.Get": func(key string) error {
+			memo := NewMemoizer[string, string](NewMemoryCache(DefaultPolicy()), func(k string) string { return k }, time.Minute)
+			_, err := memo.Get(ctx, key, func() (string, error) { return "v", nil })
+			return err
+		},
+		"CacheMiddleware.ExecuteWithKey": func(key string) error {
+			mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+			_, err := mw.ExecuteWithKey(ctx, "tool", key, nil, nil, executor)
+			return err
+		},
+	}
+
+	for name, call := range entryPoints {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range []string{"", "   ", "a\nb"} {
+				if err := call(key); !errors.Is(err, ErrInvalidKey) {
+					t.Errorf("key %q: error = %v, want ErrInvalidKey", key, err)
+				}
+			}
+			if err := call(long); !errors.Is(err, ErrKeyTooLong) {
+				t.Errorf("long key: error = %v, want ErrKeyTooLong", err)
+			}
+		})
+	}
+
+	t.Run("CacheMiddleware.Execute", func(t *testing.T) {
+		mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), longKeyer, DefaultPolicy(), nil,
+			WithOversizedKeyMode(OversizedKeyReject))
+		if _, err := mw.Execute(ctx, "tool", "a", nil, executor); !errors.Is(err, ErrKeyTooLong) {
+			t.Errorf("error = %v, want ErrKeyTooLong", err)
+		}
+	})
+
+	t.Run("custom KeyValidator", func(t *testing.T) {
+		errForbidden := errors.New("forbidden prefix")
+		mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+			WithKeyValidator(func(key string) error {
+				if strings.HasPrefix(key, "x") {
+					return errForbidden
+				}
+				return ValidateKey(key)
+			}))
+		_, err := mw.ExecuteWithKey(ctx, "tool", "xkey", nil, nil, executor)
+		if !errors.Is(err, ErrInvalidKey) || !errors.Is(err, errForbidden) {
+			t.Errorf("error = %v, want ErrInvalidKey wrapping the validator's error", err)
+		}
+		if _, err := mw.ExecuteWithKey(ctx, "tool", long, nil, nil, executor); !errors.Is(err, ErrKeyTooLong) || errors.Is(err, ErrInvalidKey) {
+			t.Errorf("long key: error = %v, want only ErrKeyTooLong", err)
+		}
+	})
+}
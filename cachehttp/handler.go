@@ -0,0 +1,117 @@
+// Package cachehttp exposes cache metadata over HTTP for debugging and ops
+// dashboards. It lives outside the core package so toolcache itself does not
+// depend on net/http.
+//
+// The handler never exposes cached values, only keys, sizes and expiries.
+package cachehttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jonwraymond/toolcache"
+)
+
+const (
+	// DefaultLimit is the page size used when the request has no limit.
+	DefaultLimit = 100
+
+	// MaxLimit caps the page size a request may ask for.
+	MaxLimit = 1000
+)
+
+// Source provides entry metadata; *toolcache.MemoryCache implements it.
+type Source interface {
+	Snapshot(ctx context.Context) ([]toolcache.EntryInfo, error)
+}
+
+// Stats summarizes the cache contents.
+type Stats struct {
+	Entries    int   `json:"entries"`
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+// Response is the JSON document served by the handler.
+type Response struct {
+	Stats   Stats       `json:"stats"`
+	Offset  int         `json:"offset"`
+	Limit   int         `json:"limit"`
+	Total   int         `json:"total"`
+	Entries []EntryJSON `json:"entries"`
+}
+
+// EntryJSON describes a single cache entry.
+type EntryJSON struct {
+	Key       string    `json:"key"`
+	Size      int       `json:"size"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewHandler returns an http.Handler that renders cache stats and a
+// paginated, key-sorted list of entries as JSON. Pagination is controlled
+// by the "offset" and "limit" query parameters.
+func NewHandler(src Source) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil || offset < 0 {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+		limit, err := queryInt(r, "limit", DefaultLimit)
+		if err != nil || limit < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+
+		infos, err := src.Snapshot(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := Response{
+			Offset:  offset,
+			Limit:   limit,
+			Total:   len(infos),
+			Entries: []EntryJSON{},
+		}
+		resp.Stats.Entries = len(infos)
+		for _, info := range infos {
+			resp.Stats.TotalBytes += int64(info.Size)
+		}
+
+		if offset < len(infos) {
+			end := min(offset+limit, len(infos))
+			for _, info := range infos[offset:end] {
+				resp.Entries = append(resp.Entries, EntryJSON{
+					Key:       info.Key,
+					Size:      info.Size,
+					ExpiresAt: info.ExpiresAt,
+				})
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	return strconv.Atoi(raw)
+}
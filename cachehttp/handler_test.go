@@ -0,0 +1,129 @@
+package cachehttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolcache"
+)
+
+func newPopulatedCache(t *testing.T, n int) *toolcache.MemoryCache {
+	t.Helper()
+	cache := toolcache.NewMemoryCache(toolcache.DefaultPolicy())
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("toolcache:tool:%02d", i)
+		if err := cache.Set(context.Background(), key, []byte("secret-value"), time.Minute); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	return cache
+}
+
+func TestHandler_JSONShape(t *testing.T) {
+	handler := NewHandler(newPopulatedCache(t, 3))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/cache", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if strings.Contains(rec.Body.String(), "secret-value") {
+		t.Error("response must never include cached values")
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for _, field := range []string{"stats", "offset", "limit", "total", "entries"} {
+		if _, ok := raw[field]; !ok {
+			t.Errorf("missing top-level field %q", field)
+		}
+	}
+
+	var resp Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.Stats.Entries != 3 || resp.Stats.TotalBytes != 3*int64(len("secret-value")) {
+		t.Errorf("stats = %+v", resp.Stats)
+	}
+	if resp.Total != 3 || resp.Limit != DefaultLimit || len(resp.Entries) != 3 {
+		t.Errorf("unexpected page: total=%d limit=%d entries=%d", resp.Total, resp.Limit, len(resp.Entries))
+	}
+	entry := resp.Entries[0]
+	if entry.Key != "toolcache:tool:00" || entry.Size != len("secret-value") || entry.ExpiresAt.IsZero() {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestHandler_Pagination(t *testing.T) {
+	handler := NewHandler(newPopulatedCache(t, 5))
+
+	testCases := []struct {
+		query    string
+		wantKeys []string
+	}{
+		{"?limit=2", []string{"toolcache:tool:00", "toolcache:tool:01"}},
+		{"?offset=2&limit=2", []string{"toolcache:tool:02", "toolcache:tool:03"}},
+		{"?offset=4&limit=2", []string{"toolcache:tool:04"}},
+		{"?offset=10", []string{}},
+	}
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+tc.query, nil))
+
+		var resp Response
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: decode failed: %v", tc.query, err)
+		}
+		if resp.Total != 5 {
+			t.Errorf("%s: total = %d, want 5", tc.query, resp.Total)
+		}
+		if len(resp.Entries) != len(tc.wantKeys) {
+			t.Errorf("%s: got %d entries, want %d", tc.query, len(resp.Entries), len(tc.wantKeys))
+			continue
+		}
+		for i, key := range tc.wantKeys {
+			if resp.Entries[i].Key != key {
+				t.Errorf("%s: entries[%d] = %q, want %q", tc.query, i, resp.Entries[i].Key, key)
+			}
+		}
+	}
+}
+
+func TestHandler_BadRequests(t *testing.T) {
+	handler := NewHandler(newPopulatedCache(t, 1))
+
+	for _, query := range []string{"?offset=-1", "?offset=x", "?limit=0", "?limit=abc"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?limit=5000", nil))
+	var resp Response
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Limit != MaxLimit {
+		t.Errorf("limit = %d, want capped at %d", resp.Limit, MaxLimit)
+	}
+}
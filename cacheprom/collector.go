@@ -0,0 +1,165 @@
+// Package cacheprom exports toolcache metrics to Prometheus. It lives
+// outside the core package so toolcache itself does not depend on the
+// Prometheus client; only programs importing cacheprom do.
+//
+// A Collector reads middleware counters and the entry count when scraped,
+// and counts observer events and evictions as they happen:
+//
+//	col, err := cacheprom.Register(prometheus.DefaultRegisterer, cacheprom.WithEntryCounter(cache))
+//	mw := toolcache.NewCacheMiddleware(cache, nil, policy, nil, toolcache.WithObserver(col))
+//	col.AddMiddleware(mw)
+//	go col.WatchEvictions(cache.EvictionEvents())
+package cacheprom
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jonwraymond/toolcache"
+)
+
+// Namespace prefixes every metric name.
+const Namespace = "toolcache"
+
+// EntryCounter reports how many entries a cache holds;
+// *toolcache.MemoryCache implements it.
+type EntryCounter interface {
+	Len() int
+}
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// WithEntryCounter exports the number of entries in cache as a gauge.
+func WithEntryCounter(cache EntryCounter) Option {
+	return func(c *Collector) {
+		c.entryCounter = cache
+	}
+}
+
+// Collector is a prometheus.Collector for toolcache metrics. It also
+// implements toolcache.Observer, counting the events it receives.
+type Collector struct {
+	mu           sync.Mutex
+	middlewares  []*toolcache.CacheMiddleware
+	entryCounter EntryCounter
+
+	hits    *prometheus.Desc
+	misses  *prometheus.Desc
+	skips   *prometheus.Desc
+	errors  *prometheus.Desc
+	entries *prometheus.Desc
+
+	evictions *prometheus.CounterVec
+	events    *prometheus.CounterVec
+}
+
+// New creates a Collector. Register it with a prometheus.Registerer, or
+// use Register to do both.
+func New(opts ...Option) *Collector {
+	toolLabels := []string{"middleware", "tool"}
+	c := &Collector{
+		hits: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "hits_total"),
+			"Executions served from the cache.", toolLabels, nil),
+		misses: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "misses_total"),
+			"Cacheable executions that ran the executor.", toolLabels, nil),
+		skips: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "skips_total"),
+			"Executions that bypassed the cache.", toolLabels, nil),
+		errors: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "errors_total"),
+			"Executor errors, including short-circuited calls.", toolLabels, nil),
+		entries: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "entries"),
+			"Entries currently stored, including expired entries not yet removed.", nil, nil),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "evictions_total",
+			Help:      "Entries that left the cache, by reason.",
+		}, []string{"reason"}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "events_total",
+			Help:      "Middleware observer events, by kind.",
+		}, []string{"middleware", "kind"}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Register creates a Collector and registers it with reg.
+func Register(reg prometheus.Registerer, opts ...Option) (*Collector, error) {
+	c := New(opts...)
+	if err := reg.Register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// AddMiddleware exports mw's per-tool counters, labeled with the tool ID
+// and the name set with toolcache.WithName; give each middleware added to
+// one Collector a distinct name. Tool cardinality is bounded by
+// toolcache.WithMaxTrackedTools.
+func (c *Collector) AddMiddleware(mw *toolcache.CacheMiddleware) {
+	c.mu.Lock()
+	c.middlewares = append(c.middlewares, mw)
+	c.mu.Unlock()
+}
+
+// Observe counts ev in toolcache_events_total.
+func (c *Collector) Observe(_ context.Context, ev toolcache.Event) {
+	c.events.WithLabelValues(ev.Middleware, ev.Kind.String()).Inc()
+}
+
+// WatchEvictions counts the events from a MemoryCache eviction stream in
+// toolcache_evictions_total until the channel is closed. Run it in its own
+// goroutine with the channel from MemoryCache.EvictionEvents; a nil
+// channel returns immediately.
+func (c *Collector) WatchEvictions(events <-chan toolcache.EvictEvent) {
+	if events == nil {
+		return
+	}
+	for ev := range events {
+		c.evictions.WithLabelValues(ev.Reason.String()).Inc()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.skips
+	ch <- c.errors
+	ch <- c.entries
+	c.evictions.Describe(ch)
+	c.events.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	middlewares := slices.Clone(c.middlewares)
+	c.mu.Unlock()
+
+	for _, mw := range middlewares {
+		name := mw.Stats().Name
+		for toolID, stats := range mw.PerToolStats() {
+			ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), name, toolID)
+			ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), name, toolID)
+			ch <- prometheus.MustNewConstMetric(c.skips, prometheus.CounterValue, float64(stats.Skips), name, toolID)
+			ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(stats.Errors), name, toolID)
+		}
+	}
+	if c.entryCounter != nil {
+		ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(c.entryCounter.Len()))
+	}
+	c.evictions.Collect(ch)
+	c.events.Collect(ch)
+}
+
+var (
+	_ prometheus.Collector = (*Collector)(nil)
+	_ toolcache.Observer   = (*Collector)(nil)
+)
@@ -0,0 +1,100 @@
+package cacheprom
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/jonwraymond/toolcache"
+)
+
+func TestCollector_Metrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	cache := toolcache.NewMemoryCacheWithOptions(toolcache.DefaultPolicy(), toolcache.WithEvictionEvents(16))
+	col, err := Register(reg, WithEntryCounter(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw := toolcache.NewCacheMiddleware(cache, nil, toolcache.DefaultPolicy(), nil,
+		toolcache.WithName("primary"), toolcache.WithObserver(col))
+	col.AddMiddleware(mw)
+
+	watched := make(chan struct{})
+	go func() {
+		col.WatchEvictions(cache.EvictionEvents())
+		close(watched)
+	}()
+
+	ctx := context.Background()
+	executor := func(context.Context, string, any) ([]byte, error) { return []byte("v"), nil }
+	_, _ = mw.Execute(ctx, "fs:read", 1, nil, executor)
+	_, _ = mw.Execute(ctx, "fs:read", 1, nil, executor)
+	_, _ = mw.Execute(ctx, "fs:write", 1, []string{"write"}, executor)
+	_, _ = mw.Execute(ctx, "fs:read", 2, nil, func(context.Context, string, any) ([]byte, error) {
+		return nil, errors.New("boom")
+	})
+	_ = cache.Set(ctx, "extra", []byte("v"), time.Minute)
+	_ = cache.Delete(ctx, "extra")
+	_ = cache.Close()
+	<-watched
+
+	if n, err := testutil.GatherAndCount(reg,
+		"toolcache_hits_total", "toolcache_misses_total", "toolcache_skips_total",
+		"toolcache_errors_total", "toolcache_entries", "toolcache_evictions_total",
+		"toolcache_events_total"); err != nil || n == 0 {
+		t.Fatalf("GatherAndCount() = %d, %v", n, err)
+	}
+
+	expected := `
+# HELP toolcache_entries Entries currently stored, including expired entries not yet removed.
+# TYPE toolcache_entries gauge
+toolcache_entries 1
+# HELP toolcache_errors_total Executor errors, including short-circuited calls.
+# TYPE toolcache_errors_total counter
+toolcache_errors_total{middleware="primary",tool="fs:read"} 1
+toolcache_errors_total{middleware="primary",tool="fs:write"} 0
+# HELP toolcache_evictions_total Entries that left the cache, by reason.
+# TYPE toolcache_evictions_total counter
+toolcache_evictions_total{reason="deleted"} 1
+# HELP toolcache_hits_total Executions served from the cache.
+# TYPE toolcache_hits_total counter
+toolcache_hits_total{middleware="primary",tool="fs:read"} 1
+toolcache_hits_total{middleware="primary",tool="fs:write"} 0
+# HELP toolcache_misses_total Cacheable executions that ran the executor.
+# TYPE toolcache_misses_total counter
+toolcache_misses_total{middleware="primary",tool="fs:read"} 2
+toolcache_misses_total{middleware="primary",tool="fs:write"} 0
+# HELP toolcache_skips_total Executions that bypassed the cache.
+# TYPE toolcache_skips_total counter
+toolcache_skips_total{middleware="primary",tool="fs:read"} 0
+toolcache_skips_total{middleware="primary",tool="fs:write"} 1
+`
+	names := []string{"toolcache_entries", "toolcache_errors_total", "toolcache_evictions_total",
+		"toolcache_hits_total", "toolcache_misses_total", "toolcache_skips_total"}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+
+	// Each Execute that derived a key reported an EventKeyed.
+	if got := testutil.ToFloat64(col.events.WithLabelValues("primary", "keyed")); got != 3 {
+		t.Errorf("keyed events = %v, want 3", got)
+	}
+}
+
+func TestCollector_Empty(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := testutil.GatherAndCount(reg); err != nil || n != 0 {
+		t.Errorf("GatherAndCount() = %d, %v; want no samples without sources", n, err)
+	}
+	if _, err := Register(reg); err == nil {
+		t.Error("registering a second Collector should conflict")
+	}
+}
@@ -0,0 +1,65 @@
+package toolcache
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock supplies the current time to MemoryCache. It lets tests control
+// expiry deterministically instead of sleeping.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock, backed by time.Now.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// WithClock makes the cache read the current time from clock for every
+// expiry decision and TTL computation. A nil clock restores the system
+// clock. Periodic work such as WatchMemoryPressure still ticks in real
+// time; call RelieveMemoryPressure directly to drive it from a test.
+func WithClock(clock Clock) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		if clock == nil {
+			clock = systemClock{}
+		}
+		c.clock = clock
+	}
+}
+
+// ManualClock is a Clock that only moves when told to, for tests.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock returns a ManualClock reading start.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Set moves the clock to t, which may be in the past.
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
@@ -0,0 +1,112 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+
+	clock.Advance(90 * time.Second)
+	if got := clock.Now(); !got.Equal(start.Add(90 * time.Second)) {
+		t.Errorf("Now() after Advance = %v", got)
+	}
+	clock.Set(start)
+	if got := clock.Now(); !got.Equal(start) {
+		t.Errorf("Now() after Set = %v, want %v", got, start)
+	}
+}
+
+func TestWithClock_DrivesExpiry(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(clock), WithEvictionEvents(8))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "short", []byte("s"), time.Minute)
+	_ = cache.Set(ctx, "long", []byte("l"), time.Hour)
+	_ = cache.SetThunk(ctx, "lazy", func(context.Context) ([]byte, error) { return []byte("z"), nil }, time.Minute, time.Hour)
+
+	clock.Advance(time.Minute)
+	if _, ok := cache.Get(ctx, "short"); !ok {
+		t.Fatal("entry should still be fresh exactly at its TTL")
+	}
+
+	clock.Advance(time.Nanosecond)
+	if keys, _ := cache.KeysWithPrefix(ctx, ""); !slices.Equal(keys, []string{"long"}) {
+		t.Errorf("KeysWithPrefix() = %v, want [long]", keys)
+	}
+	infos, _ := cache.Snapshot(ctx)
+	if len(infos) != 1 || infos[0].Key != "long" || !infos[0].ExpiresAt.Equal(clock.Now().Add(59*time.Minute-time.Nanosecond)) {
+		t.Errorf("Snapshot() = %+v, want only long with a clock-based expiry", infos)
+	}
+	if _, ok := cache.Get(ctx, "short"); ok {
+		t.Error("short should have expired")
+	}
+	if _, ok := cache.Get(ctx, "lazy"); ok {
+		t.Error("thunk should have expired before being read")
+	}
+
+	for _, want := range []string{"short", "lazy"} {
+		select {
+		case ev := <-cache.EvictionEvents():
+			if ev.Key != want || ev.Reason != EvictReasonExpired || !ev.At.Equal(clock.Now()) {
+				t.Errorf("event = %+v, want %s expired at the clock time", ev, want)
+			}
+		default:
+			t.Fatalf("missing expiry event for %s", want)
+		}
+	}
+
+	clock.Advance(time.Hour)
+	if _, ok := cache.Get(ctx, "long"); ok {
+		t.Error("long should have expired")
+	}
+}
+
+func TestWithClock_ThunkPromotion(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(clock))
+	ctx := context.Background()
+
+	_ = cache.SetThunk(ctx, "lazy", func(context.Context) ([]byte, error) { return []byte("z"), nil }, time.Hour, time.Minute)
+	clock.Advance(30 * time.Minute)
+	if _, ok := cache.Get(ctx, "lazy"); !ok {
+		t.Fatal("thunk should materialize")
+	}
+
+	clock.Advance(time.Minute + time.Nanosecond)
+	if _, ok := cache.Get(ctx, "lazy"); ok {
+		t.Error("promoted entry should expire promoteTTL after it was read")
+	}
+}
+
+func TestWithClock_ReadSnapshotAcrossClocks(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(NewManualClock(start)))
+	ctx := context.Background()
+	_ = src.Set(ctx, "k", []byte("v"), time.Minute)
+
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	later := NewManualClock(start.Add(2 * time.Minute))
+	dst := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(later))
+	if n, err := dst.ReadSnapshot(ctx, bytes.NewReader(buf.Bytes())); err != nil || n != 0 {
+		t.Errorf("ReadSnapshot() = %d, %v; want the entry expired on the reader's clock", n, err)
+	}
+}
+
+func TestWithClock_NilRestoresSystemClock(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(nil))
+	_ = cache.Set(context.Background(), "k", []byte("v"), time.Minute)
+	if _, ok := cache.Get(context.Background(), "k"); !ok {
+		t.Error("entry should be fresh under the system clock")
+	}
+}
@@ -0,0 +1,115 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// WithCoalesceWindow shares executor results between identical calls. While
+// a cache miss is being executed, other calls for the same key wait for it
+// instead of running the executor again, and a successful result is kept in
+// memory for window afterwards, so bursts of identical calls are served
+// even before the cache Set is visible (e.g. with a remote or eventually
+// consistent cache, or when storing was skipped). Errors are shared only
+// with calls that were already waiting, except cancellation and deadline
+// errors from the running call's own context: a waiter whose context is
+// still live runs the executor again instead.
+//
+// Coalesced calls count as hits. ExecuteWithInfo reports them as cached,
+// without EntryMeta.
+func WithCoalesceWindow(window time.Duration) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		if window <= 0 {
+			m.coalesce = nil
+			return
+		}
+		m.coalesce = &coalescer{window: window, calls: make(map[string]*coalescedCall)}
+	}
+}
+
+type coalescedCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// coalescer tracks in-flight and recently completed executions by key.
+type coalescer struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+// runCoalesced runs executor for key unless an identical call is in flight
+// or completed within the window, in which case shared is true and that
+// call's outcome is returned.
+func (m *CacheMiddleware) runCoalesced(ctx context.Context, toolID, key string, input any, executor ToolExecutor) (result []byte, shared bool, err error) {
+	c := m.coalesce
+	if c == nil {
+		result, err = m.runExecutor(ctx, toolID, input, executor)
+		return result, false, err
+	}
+
+	for {
+		c.mu.Lock()
+		call, ok := c.calls[key]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+		if isContextError(call.err) && ctx.Err() == nil {
+			// The leader gave up on its own context; this call still
+			// wants a result, so it runs or joins the next execution.
+			continue
+		}
+		if call.err != nil && !isDoNotCache(call.err) {
+			return nil, true, call.err
+		}
+		if _, returned, terr := m.transformResult(toolID, call.value); terr == nil {
+			return bytes.Clone(returned), true, call.err
+		}
+		return bytes.Clone(call.value), true, call.err
+	}
+	call := &coalescedCall{done: make(chan struct{}), err: errCoalescedPanicked}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	defer func() {
+		if call.err != nil {
+			c.forget(key, call)
+		} else {
+			time.AfterFunc(c.window, func() { c.forget(key, call) })
+		}
+		close(call.done)
+	}()
+
+	result, err = m.runExecutor(ctx, toolID, input, executor)
+	call.value, call.err = bytes.Clone(result), err
+	return result, false, err
+}
+
+// forget removes call if it is still the one registered for key.
+func (c *coalescer) forget(key string, call *coalescedCall) {
+	c.mu.Lock()
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+	c.mu.Unlock()
+}
+
+var errCoalescedPanicked = errors.New("toolcache: coalesced execution panicked")
+
+// isContextError reports whether err comes from a canceled or expired
+// context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
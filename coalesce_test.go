@@ -0,0 +1,155 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// forgetfulCache never retains anything, so only coalescing can avoid
+// re-execution.
+var forgetfulCache = CacheFuncs{}
+
+func TestCoalesce_StaggeredWithinWindow(t *testing.T) {
+	mw := NewCacheMiddleware(forgetfulCache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithCoalesceWindow(time.Minute))
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	for range 3 {
+		got, err := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+		if err != nil || string(got) != "v" {
+			t.Fatalf("Execute() = %q, %v", got, err)
+		}
+	}
+	if executor.calls != 1 {
+		t.Errorf("calls within the window should coalesce, got %d executions", executor.calls)
+	}
+	if stats := mw.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want 2 hits and 1 miss", stats.ToolStats)
+	}
+
+	_, _ = mw.Execute(ctx, "tool", 2, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("different input must not coalesce, got %d executions", executor.calls)
+	}
+}
+
+func TestCoalesce_OutsideWindow(t *testing.T) {
+	mw := NewCacheMiddleware(forgetfulCache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithCoalesceWindow(5*time.Millisecond))
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	time.Sleep(30 * time.Millisecond)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("calls outside the window should execute, got %d executions", executor.calls)
+	}
+}
+
+func TestCoalesce_ConcurrentInFlight(t *testing.T) {
+	mw := NewCacheMiddleware(forgetfulCache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithCoalesceWindow(time.Minute))
+	var calls atomic.Int32
+	release := make(chan struct{})
+	executor := func(context.Context, string, any) ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("v"), nil
+	}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := mw.Execute(ctx, "tool", 1, nil, executor); err != nil || string(got) != "v" {
+				t.Errorf("Execute() = %q, %v", got, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("executor ran %d times, want 1", got)
+	}
+}
+
+func TestCoalesce_ErrorsNotRemembered(t *testing.T) {
+	mw := NewCacheMiddleware(forgetfulCache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithCoalesceWindow(time.Minute))
+	executor := &mockExecutor{err: errors.New("boom")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("failed calls should not be coalesced after completion, got %d executions", executor.calls)
+	}
+}
+
+func TestCoalesce_SharedResultIsCopied(t *testing.T) {
+	mw := NewCacheMiddleware(forgetfulCache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithCoalesceWindow(time.Minute))
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	first, _ := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	first[0] = 'X'
+	second, _ := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if string(second) != "v" {
+		t.Errorf("coalesced result = %q, want an unaffected copy", second)
+	}
+}
+
+func TestCoalesce_LeaderCanceled(t *testing.T) {
+	mw := NewCacheMiddleware(forgetfulCache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithCoalesceWindow(time.Minute))
+	var calls atomic.Int32
+	started := make(chan struct{})
+	executor := func(ctx context.Context, _ string, _ any) ([]byte, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []byte("v"), nil
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := mw.Execute(leaderCtx, "tool", 1, nil, executor)
+		leaderErr <- err
+	}()
+	<-started
+
+	waiter := make(chan string, 1)
+	go func() {
+		got, err := mw.Execute(context.Background(), "tool", 1, nil, executor)
+		if err != nil {
+			t.Errorf("waiter error = %v, want a result despite the leader's cancellation", err)
+		}
+		waiter <- string(got)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader error = %v, want context.Canceled", err)
+	}
+	if got := <-waiter; got != "v" {
+		t.Errorf("waiter result = %q, want v", got)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("executor ran %d times, want 2", got)
+	}
+}
@@ -0,0 +1,82 @@
+package toolcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// collisionTracker remembers the full digest behind recently generated
+// keys, bounded by an LRU. A key seen again with a different full digest
+// means two distinct inputs truncated to the same hash.
+type collisionTracker struct {
+	mu         sync.Mutex
+	capacity   int
+	lru        *list.List
+	keys       map[string]*list.Element
+	collisions uint64
+}
+
+type trackedKey struct {
+	key    string
+	digest [sha256.Size]byte
+}
+
+func newCollisionTracker(capacity int) *collisionTracker {
+	return &collisionTracker{
+		capacity: capacity,
+		lru:      list.New(),
+		keys:     make(map[string]*list.Element, capacity),
+	}
+}
+
+// observe records that key was derived from a full digest.
+func (t *collisionTracker) observe(key string, digest [sha256.Size]byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if elem, ok := t.keys[key]; ok {
+		tracked := elem.Value.(*trackedKey)
+		if tracked.digest != digest {
+			t.collisions++
+			tracked.digest = digest
+		}
+		t.lru.MoveToFront(elem)
+		return
+	}
+
+	if t.lru.Len() >= t.capacity {
+		oldest := t.lru.Remove(t.lru.Back()).(*trackedKey)
+		delete(t.keys, oldest.key)
+	}
+	t.keys[key] = t.lru.PushFront(&trackedKey{key: key, digest: digest})
+}
+
+func (t *collisionTracker) count() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.collisions
+}
+
+// TrackCollisions makes the keyer remember the full SHA-256 digest behind
+// the last capacity keys it generated and count truncation collisions: the
+// same key produced from inputs with different digests. Use Collisions to
+// judge whether KeyHashBytes is long enough for real traffic. Each tracked
+// key costs roughly its length plus 100 bytes. A capacity below 1 turns
+// tracking off and discards the count.
+func (k *DefaultKeyer) TrackCollisions(capacity int) {
+	if capacity < 1 {
+		k.root().collisions.Store(nil)
+		return
+	}
+	k.root().collisions.Store(newCollisionTracker(capacity))
+}
+
+// Collisions returns the number of truncation collisions observed since
+// TrackCollisions was last called, or 0 if tracking is off.
+func (k *DefaultKeyer) Collisions() uint64 {
+	if t := k.root().collisions.Load(); t != nil {
+		return t.count()
+	}
+	return 0
+}
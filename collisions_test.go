@@ -0,0 +1,74 @@
+package toolcache
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func TestCollisionTracker_CountsCollisions(t *testing.T) {
+	tracker := newCollisionTracker(10)
+	a := sha256.Sum256([]byte("input-a"))
+	b := sha256.Sum256([]byte("input-b"))
+
+	tracker.observe("toolcache:t:0000", a)
+	tracker.observe("toolcache:t:0000", a)
+	if got := tracker.count(); got != 0 {
+		t.Fatalf("same digest counted as collision: %d", got)
+	}
+
+	// Simulate two distinct inputs truncating to the same key.
+	tracker.observe("toolcache:t:0000", b)
+	if got := tracker.count(); got != 1 {
+		t.Errorf("collisions = %d, want 1", got)
+	}
+}
+
+func TestCollisionTracker_Bounded(t *testing.T) {
+	tracker := newCollisionTracker(2)
+	digest := sha256.Sum256([]byte("x"))
+
+	for i := range 5 {
+		tracker.observe(fmt.Sprintf("k%d", i), digest)
+	}
+	if got := tracker.lru.Len(); got != 2 {
+		t.Errorf("tracked %d keys, want 2", got)
+	}
+	if _, ok := tracker.keys["k0"]; ok {
+		t.Error("oldest key should have been evicted")
+	}
+
+	// An evicted key is forgotten, so a different digest is not a collision.
+	tracker.observe("k0", sha256.Sum256([]byte("y")))
+	if got := tracker.count(); got != 0 {
+		t.Errorf("collisions = %d, want 0 after eviction", got)
+	}
+}
+
+func TestKeyer_TrackCollisions(t *testing.T) {
+	keyer := NewDefaultKeyer()
+	if got := keyer.Collisions(); got != 0 {
+		t.Fatalf("Collisions() with tracking off = %d", got)
+	}
+
+	keyer.TrackCollisions(100)
+	for i := range 50 {
+		_, _ = keyer.Key("tool", map[string]any{"i": i})
+		_, _ = keyer.Key("tool", map[string]any{"i": i})
+	}
+	if got := keyer.Collisions(); got != 0 {
+		t.Errorf("distinct inputs reported %d collisions", got)
+	}
+
+	// Forge a collision by recording a different digest under a real key.
+	key, _ := keyer.Key("tool", map[string]any{"i": 1})
+	keyer.collisions.Load().observe(key, sha256.Sum256([]byte("other input")))
+	if got := keyer.Collisions(); got != 1 {
+		t.Errorf("Collisions() = %d, want 1", got)
+	}
+
+	keyer.TrackCollisions(0)
+	if got := keyer.Collisions(); got != 0 {
+		t.Errorf("Collisions() after disabling = %d, want 0", got)
+	}
+}
@@ -0,0 +1,187 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// DedupCacheOption configures a DedupCache.
+type DedupCacheOption func(*DedupCache)
+
+// WithDedupClock sets the time source used for entry expiry.
+// A nil clock restores the system clock.
+func WithDedupClock(clock Clock) DedupCacheOption {
+	return func(c *DedupCache) {
+		if clock == nil {
+			clock = systemClock{}
+		}
+		c.clock = clock
+	}
+}
+
+type dedupEntry struct {
+	sum       [sha256.Size]byte
+	expiresAt time.Time
+}
+
+// dedupBlob is a stored value and the number of keys referencing it.
+type dedupBlob struct {
+	value []byte
+	refs  int
+}
+
+// DedupCache is a content-addressed cache: keys reference values by their
+// SHA-256, so identical results stored under different keys, such as two
+// paths with the same content, are held in memory once. A value is freed
+// when the last key referencing it is deleted, overwritten, flushed or
+// found expired. Each key keeps its own TTL.
+//
+// Expired keys are removed when read or by RemoveExpired; until then they
+// keep their value alive. Set hashes every value, so DedupCache suits
+// duplicate-heavy workloads where the memory saved outweighs that cost.
+type DedupCache struct {
+	mu      sync.Mutex
+	entries map[string]dedupEntry
+	blobs   map[[sha256.Size]byte]*dedupBlob
+	clock   Clock
+}
+
+// NewDedupCache creates an empty DedupCache.
+func NewDedupCache(opts ...DedupCacheOption) *DedupCache {
+	c := &DedupCache{
+		entries: make(map[string]dedupEntry),
+		blobs:   make(map[[sha256.Size]byte]*dedupBlob),
+		clock:   systemClock{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get returns the value under key.
+func (c *DedupCache) Get(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.clock.Now().After(entry.expiresAt) {
+		c.removeLocked(key, entry)
+		return nil, false
+	}
+	return bytes.Clone(c.blobs[entry.sum].value), true
+}
+
+// Set stores value under key, sharing the stored bytes with any other key
+// holding identical content. Keys failing ValidateKey are rejected.
+func (c *DedupCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	sum := sha256.Sum256(value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Take the new reference before dropping the old one, so overwriting a
+	// key with its current content never frees the value.
+	blob, ok := c.blobs[sum]
+	if !ok {
+		blob = &dedupBlob{value: bytes.Clone(value)}
+		c.blobs[sum] = blob
+	}
+	blob.refs++
+	if old, ok := c.entries[key]; ok {
+		c.releaseLocked(old.sum)
+	}
+	c.entries[key] = dedupEntry{sum: sum, expiresAt: c.clock.Now().Add(ttl)}
+	return nil
+}
+
+// Delete removes key, freeing its value if no other key references it.
+func (c *DedupCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		c.removeLocked(key, entry)
+	}
+	return nil
+}
+
+// Flush removes every entry and value.
+func (c *DedupCache) Flush(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	clear(c.blobs)
+	return nil
+}
+
+// RemoveExpired removes every expired key, freeing values no longer
+// referenced, and returns the number of keys removed.
+func (c *DedupCache) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	removed := 0
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			c.removeLocked(key, entry)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of keys and of distinct values stored, including
+// expired keys not yet removed.
+func (c *DedupCache) Len() (keys, values int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), len(c.blobs)
+}
+
+// Size returns the total bytes of the distinct values stored, counting
+// each shared value once.
+func (c *DedupCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := 0
+	for _, blob := range c.blobs {
+		size += len(blob.value)
+	}
+	return size
+}
+
+// removeLocked drops key and its reference. Callers must hold c.mu.
+func (c *DedupCache) removeLocked(key string, entry dedupEntry) {
+	delete(c.entries, key)
+	c.releaseLocked(entry.sum)
+}
+
+// releaseLocked drops one reference to the value with digest sum, freeing
+// it with the last. Callers must hold c.mu.
+func (c *DedupCache) releaseLocked(sum [sha256.Size]byte) {
+	blob := c.blobs[sum]
+	if blob.refs--; blob.refs == 0 {
+		delete(c.blobs, sum)
+	}
+}
+
+var (
+	_ Cache     = (*DedupCache)(nil)
+	_ Flushable = (*DedupCache)(nil)
+)
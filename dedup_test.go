@@ -0,0 +1,162 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedupCache_SharesIdenticalValues(t *testing.T) {
+	c := NewDedupCache()
+	ctx := context.Background()
+	content := []byte("same file contents")
+
+	for _, key := range []string{"fs:read:/a", "fs:read:/b", "fs:read:/c"} {
+		if err := c.Set(ctx, key, content, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = c.Set(ctx, "fs:read:/d", []byte("other"), time.Minute)
+
+	if keys, values := c.Len(); keys != 4 || values != 2 {
+		t.Errorf("Len() = %d, %d; want 4 keys sharing 2 values", keys, values)
+	}
+	if size := c.Size(); size != len(content)+len("other") {
+		t.Errorf("Size() = %d, want each value counted once", size)
+	}
+	for _, key := range []string{"fs:read:/a", "fs:read:/b", "fs:read:/c"} {
+		if got, ok := c.Get(ctx, key); !ok || string(got) != string(content) {
+			t.Errorf("Get(%q) = %q, %v", key, got, ok)
+		}
+	}
+
+	// Values returned to callers are copies of the shared bytes.
+	got, _ := c.Get(ctx, "fs:read:/a")
+	got[0] = 'X'
+	if again, _ := c.Get(ctx, "fs:read:/b"); string(again) != string(content) {
+		t.Errorf("modifying a returned value changed a shared one: %q", again)
+	}
+}
+
+func TestDedupCache_FreesOnLastRemoval(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	c := NewDedupCache(WithDedupClock(clock))
+	ctx := context.Background()
+	shared := []byte("shared")
+
+	_ = c.Set(ctx, "deleted", shared, time.Hour)
+	_ = c.Set(ctx, "overwritten", shared, time.Hour)
+	_ = c.Set(ctx, "expiring", shared, time.Minute)
+	_ = c.Set(ctx, "last", shared, time.Hour)
+
+	_ = c.Delete(ctx, "deleted")
+	_ = c.Set(ctx, "overwritten", []byte("new"), time.Hour)
+	clock.Advance(2 * time.Minute)
+	if n := c.RemoveExpired(); n != 1 {
+		t.Errorf("RemoveExpired() = %d, want 1", n)
+	}
+	if _, values := c.Len(); values != 2 {
+		t.Fatalf("values = %d; the shared value must survive while referenced", values)
+	}
+	if got, ok := c.Get(ctx, "last"); !ok || string(got) != "shared" {
+		t.Fatalf("Get(last) = %q, %v", got, ok)
+	}
+
+	_ = c.Delete(ctx, "last")
+	if keys, values := c.Len(); keys != 1 || values != 1 {
+		t.Errorf("Len() = %d, %d; want only the overwritten key's value left", keys, values)
+	}
+	if size := c.Size(); size != len("new") {
+		t.Errorf("Size() = %d after last reference removed", size)
+	}
+}
+
+func TestDedupCache_ExpiredGetReleases(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	c := NewDedupCache(WithDedupClock(clock))
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "k", []byte("v"), time.Minute)
+	clock.Advance(time.Hour)
+	if _, ok := c.Get(ctx, "k"); ok {
+		t.Error("expired key should miss")
+	}
+	if keys, values := c.Len(); keys != 0 || values != 0 {
+		t.Errorf("Len() = %d, %d after expiry", keys, values)
+	}
+}
+
+func TestDedupCache_OverwriteWithSameValue(t *testing.T) {
+	c := NewDedupCache()
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "k", []byte("v"), time.Minute)
+	_ = c.Set(ctx, "k", []byte("v"), time.Minute)
+	if got, ok := c.Get(ctx, "k"); !ok || string(got) != "v" {
+		t.Fatalf("Get() = %q, %v", got, ok)
+	}
+	_ = c.Delete(ctx, "k")
+	if keys, values := c.Len(); keys != 0 || values != 0 {
+		t.Errorf("Len() = %d, %d; references leaked", keys, values)
+	}
+}
+
+func TestDedupCache_Flush(t *testing.T) {
+	c := NewDedupCache()
+	ctx := context.Background()
+	_ = c.Set(ctx, "a", []byte("v"), time.Minute)
+	_ = c.Set(ctx, "b", []byte("v"), time.Minute)
+
+	if err := c.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if keys, values := c.Len(); keys != 0 || values != 0 {
+		t.Errorf("Len() = %d, %d after Flush", keys, values)
+	}
+	_ = c.Set(ctx, "a", []byte("v"), time.Minute)
+	_ = c.Delete(ctx, "a")
+	if _, values := c.Len(); values != 0 {
+		t.Errorf("values = %d; Flush left stale reference counts", values)
+	}
+}
+
+func TestDedupCache_InvalidKey(t *testing.T) {
+	c := NewDedupCache()
+	if err := c.Set(context.Background(), "", []byte("v"), time.Minute); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Set(\"\") = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestDedupCache_Concurrent(t *testing.T) {
+	c := NewDedupCache()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				key := fmt.Sprintf("g%d-%d", g, i%10)
+				_ = c.Set(ctx, key, []byte(fmt.Sprintf("v%d", i%3)), time.Minute)
+				_, _ = c.Get(ctx, key)
+				if i%4 == 0 {
+					_ = c.Delete(ctx, key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for g := range 8 {
+		for i := range 10 {
+			_ = c.Delete(ctx, fmt.Sprintf("g%d-%d", g, i))
+		}
+	}
+	if keys, values := c.Len(); keys != 0 || values != 0 {
+		t.Errorf("Len() = %d, %d after deleting every key", keys, values)
+	}
+}
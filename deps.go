@@ -0,0 +1,101 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"time"
+)
+
+// SetWithDeps stores value under key like Set and records that it depends
+// on deps: deleting any of them with Delete or DeleteTree also deletes key,
+// and in turn anything depending on key. Dependencies need not exist yet.
+// Cycles are allowed; each entry is removed at most once per cascade.
+//
+// Overwriting key replaces its dependencies. Expiry and eviction of a
+// dependency do not cascade; only explicit deletion does.
+func (c *MemoryCache) SetWithDeps(_ context.Context, key string, value []byte, ttl time.Duration, deps []string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	deps = slices.Clone(deps)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.storeLocked(key, &cacheEntry{
+		value:     bytes.Clone(value),
+		expiresAt: c.clock.Now().Add(ttl),
+		deps:      deps,
+	})
+	if len(deps) == 0 {
+		return nil
+	}
+	if c.dependents == nil {
+		c.dependents = make(map[string]map[string]struct{})
+	}
+	for _, dep := range deps {
+		set, ok := c.dependents[dep]
+		if !ok {
+			set = make(map[string]struct{})
+			c.dependents[dep] = set
+		}
+		set[key] = struct{}{}
+	}
+	return nil
+}
+
+// unlinkLocked removes the dependency edges recorded for key.
+// Callers must hold c.mu for writing.
+func (c *MemoryCache) unlinkLocked(key string, deps []string) {
+	for _, dep := range deps {
+		if set, ok := c.dependents[dep]; ok {
+			delete(set, key)
+			if len(set) == 0 {
+				delete(c.dependents, dep)
+			}
+		}
+	}
+}
+
+// cascadeLocked deletes everything that transitively depends on the given
+// roots, which the caller has already removed, and returns how many
+// entries it deleted. Callers must hold c.mu for writing.
+func (c *MemoryCache) cascadeLocked(roots ...string) int {
+	if len(c.dependents) == 0 {
+		return 0
+	}
+
+	removed := 0
+	visited := make(map[string]bool, len(roots))
+	queue := slices.Clone(roots)
+	for _, root := range roots {
+		visited[root] = true
+	}
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+
+		for key := range c.dependents[dep] {
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			entry, ok := c.entries[key]
+			// Skip stale edges from entries since overwritten without dep.
+			if !ok || !slices.Contains(entry.deps, dep) {
+				continue
+			}
+			c.removeLocked(key, entry)
+			c.emitEvict(key, EvictReasonDeleted)
+			c.releaseLocked(entry)
+			removed++
+			queue = append(queue, key)
+		}
+		delete(c.dependents, dep)
+	}
+	return removed
+}
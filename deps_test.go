@@ -0,0 +1,187 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func present(c *MemoryCache, keys ...string) map[string]bool {
+	out := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		_, out[key] = c.Get(context.Background(), key)
+	}
+	return out
+}
+
+func TestSetWithDeps_Cascade(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	// report depends on summary, which depends on users and orders.
+	_ = cache.Set(ctx, "users", []byte("u"), time.Hour)
+	_ = cache.Set(ctx, "orders", []byte("o"), time.Hour)
+	_ = cache.SetWithDeps(ctx, "summary", []byte("s"), time.Hour, []string{"users", "orders"})
+	_ = cache.SetWithDeps(ctx, "report", []byte("r"), time.Hour, []string{"summary"})
+	_ = cache.SetWithDeps(ctx, "unrelated", []byte("x"), time.Hour, []string{"products"})
+
+	_ = cache.Delete(ctx, "orders")
+	got := present(cache, "users", "orders", "summary", "report", "unrelated")
+	want := map[string]bool{"users": true, "unrelated": true}
+	for key, ok := range got {
+		if ok != want[key] {
+			t.Errorf("%s present = %v, want %v", key, ok, want[key])
+		}
+	}
+}
+
+func TestSetWithDeps_CycleSafe(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	_ = cache.SetWithDeps(ctx, "a", []byte("1"), time.Hour, []string{"c"})
+	_ = cache.SetWithDeps(ctx, "b", []byte("2"), time.Hour, []string{"a"})
+	_ = cache.SetWithDeps(ctx, "c", []byte("3"), time.Hour, []string{"b"})
+	_ = cache.SetWithDeps(ctx, "self", []byte("4"), time.Hour, []string{"self"})
+
+	done := make(chan struct{})
+	go func() {
+		_ = cache.Delete(ctx, "a")
+		_ = cache.Delete(ctx, "self")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cascade did not terminate on a cycle")
+	}
+	for key, ok := range present(cache, "a", "b", "c", "self") {
+		if ok {
+			t.Errorf("%s should have been removed", key)
+		}
+	}
+	if len(cache.dependents) != 0 {
+		t.Errorf("dependency edges leaked: %v", cache.dependents)
+	}
+}
+
+func TestSetWithDeps_OverwriteDropsDeps(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	_ = cache.SetWithDeps(ctx, "view", []byte("v1"), time.Hour, []string{"old"})
+	_ = cache.SetWithDeps(ctx, "view", []byte("v2"), time.Hour, []string{"new"})
+	_ = cache.Delete(ctx, "old")
+	if !present(cache, "view")["view"] {
+		t.Error("view no longer depends on old")
+	}
+
+	_ = cache.Set(ctx, "view", []byte("v3"), time.Hour)
+	_ = cache.Delete(ctx, "new")
+	if !present(cache, "view")["view"] {
+		t.Error("a plain Set should clear dependencies")
+	}
+}
+
+func TestSetWithDeps_DeleteTreeAndEvents(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithEvictionEvents(8))
+	ctx := context.Background()
+	parent := "toolcache:doc:1"
+	_ = cache.Set(ctx, ChildKey(parent, "toc"), []byte("t"), time.Hour)
+	_ = cache.SetWithDeps(ctx, "index", []byte("i"), time.Hour, []string{ChildKey(parent, "toc")})
+
+	n, _ := cache.DeleteTree(ctx, parent)
+	if n != 2 || present(cache, "index")["index"] {
+		t.Errorf("DeleteTree removed %d, want the child and its dependent", n)
+	}
+	var keys []string
+	for len(cache.EvictionEvents()) > 0 {
+		keys = append(keys, (<-cache.EvictionEvents()).Key)
+	}
+	if len(keys) != 2 || keys[1] != "index" {
+		t.Errorf("eviction events = %v", keys)
+	}
+}
+
+func TestOverwrite_DropsDependencyEdges(t *testing.T) {
+	ctx := context.Background()
+	var snap bytes.Buffer
+	src := NewMemoryCache(DefaultPolicy())
+	_ = src.Set(ctx, "view", []byte("restored"), time.Hour)
+	if err := src.WriteSnapshot(ctx, &snap); err != nil {
+		t.Fatal(err)
+	}
+
+	overwrites := map[string]func(c *MemoryCache) error{
+		"Set":          func(c *MemoryCache) error { return c.Set(ctx, "view", []byte("v"), time.Hour) },
+		"SetImmutable": func(c *MemoryCache) error { return c.SetImmutable(ctx, "view", []byte("v"), time.Hour) },
+		"SetThunk": func(c *MemoryCache) error {
+			return c.SetThunk(ctx, "view", func(context.Context) ([]byte, error) { return []byte("v"), nil }, time.Hour, time.Hour)
+		},
+		"ReadSnapshot": func(c *MemoryCache) error {
+			_, err := c.ReadSnapshot(ctx, bytes.NewReader(snap.Bytes()))
+			return err
+		},
+	}
+	for name, overwrite := range overwrites {
+		t.Run(name, func(t *testing.T) {
+			cache := NewMemoryCache(DefaultPolicy())
+			_ = cache.SetWithDeps(ctx, "view", []byte("v1"), time.Hour, []string{"src"})
+			if err := overwrite(cache); err != nil {
+				t.Fatal(err)
+			}
+			if len(cache.dependents) != 0 {
+				t.Errorf("dependency edges leaked: %v", cache.dependents)
+			}
+		})
+	}
+}
+
+func TestOverwrite_ReleasesPooledEntry(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithEntryPooling())
+	_ = cache.Set(ctx, "k", []byte("pooled"), time.Hour)
+	old := cache.entries["k"]
+
+	_ = cache.SetImmutable(ctx, "k", []byte("v"), time.Hour)
+	if old.buf != nil || old.value != nil {
+		t.Error("the overwritten pooled entry should be returned to the pool")
+	}
+}
+
+func TestRemoval_DropsDependencyEdges(t *testing.T) {
+	ctx := context.Background()
+	removals := map[string]struct {
+		opts   []MemoryCacheOption
+		remove func(c *MemoryCache, clock *ManualClock)
+	}{
+		"expiry": {nil, func(c *MemoryCache, clock *ManualClock) {
+			clock.Advance(2 * time.Hour)
+			_, _ = c.Get(ctx, "view")
+		}},
+		"pooled expiry": {[]MemoryCacheOption{WithEntryPooling()}, func(c *MemoryCache, clock *ManualClock) {
+			clock.Advance(2 * time.Hour)
+			_, _ = c.Get(ctx, "view")
+		}},
+		"capacity": {[]MemoryCacheOption{WithMaxEntries(1)}, func(c *MemoryCache, _ *ManualClock) {
+			_ = c.Set(ctx, "other", []byte("v"), time.Hour)
+		}},
+		"pressure": {nil, func(c *MemoryCache, _ *ManualClock) {
+			c.EvictFraction(1)
+		}},
+	}
+	for name, tc := range removals {
+		t.Run(name, func(t *testing.T) {
+			clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			cache := NewMemoryCacheWithOptions(DefaultPolicy(), append(tc.opts, WithClock(clock))...)
+			_ = cache.SetWithDeps(ctx, "view", []byte("v"), time.Hour, []string{"src"})
+
+			tc.remove(cache, clock)
+			if _, ok := cache.entries["view"]; ok {
+				t.Fatal("view should have been removed")
+			}
+			if len(cache.dependents) != 0 {
+				t.Errorf("dependency edges leaked: %v", cache.dependents)
+			}
+		})
+	}
+}
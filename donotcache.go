@@ -0,0 +1,17 @@
+package toolcache
+
+import "errors"
+
+// ErrDoNotCache lets an executor return a result without it being cached,
+// e.g. when the response itself indicates it is volatile. An executor that
+// returns a value together with ErrDoNotCache (or an error wrapping it)
+// gets the value passed through to the caller with a nil error; the result
+// is neither stored nor negatively cached, and it does not count as a
+// failure for the executor breaker. Calls already waiting on it through
+// WithCoalesceWindow share the value, but it is not kept for the window.
+var ErrDoNotCache = errors.New("toolcache: do not cache result")
+
+// isDoNotCache reports whether err is an executor's do-not-cache signal.
+func isDoNotCache(err error) bool {
+	return errors.Is(err, ErrDoNotCache)
+}
@@ -0,0 +1,145 @@
+package toolcache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// volatileExecutor returns its value together with ErrDoNotCache.
+type volatileExecutor struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (e *volatileExecutor) execute(context.Context, string, any) ([]byte, error) {
+	e.calls.Add(1)
+	if e.err != nil {
+		return []byte("volatile"), e.err
+	}
+	return []byte("volatile"), ErrDoNotCache
+}
+
+func TestDoNotCache_ReturnsValueWithoutStoring(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.NegativeTTL = time.Minute
+	mw := NewCacheMiddleware(NewMemoryCache(policy), NewDefaultKeyer(), policy, nil)
+	executor := &volatileExecutor{}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		got, err := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+		if err != nil || string(got) != "volatile" {
+			t.Fatalf("call %d = %q, %v; want the value and no error", i, got, err)
+		}
+	}
+	if got := executor.calls.Load(); got != 2 {
+		t.Errorf("executor calls = %d, want 2 (nothing cached, positively or negatively)", got)
+	}
+	if got := counters(mw.Stats().ToolStats); got != (ToolStats{Misses: 2}) {
+		t.Errorf("Stats() = %+v, want two misses and no errors", got)
+	}
+}
+
+func TestDoNotCache_Wrapped(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &volatileExecutor{err: fmt.Errorf("rate limit headers present: %w", ErrDoNotCache)}
+
+	got, err := mw.Execute(context.Background(), "tool", 1, nil, executor.execute)
+	if err != nil || string(got) != "volatile" {
+		t.Fatalf("Execute = %q, %v; want the value and no error", got, err)
+	}
+	_, _ = mw.Execute(context.Background(), "tool", 1, nil, executor.execute)
+	if got := executor.calls.Load(); got != 2 {
+		t.Errorf("executor calls = %d, want 2", got)
+	}
+}
+
+func TestDoNotCache_SkippedTool(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &volatileExecutor{}
+
+	got, err := mw.Execute(context.Background(), "tool", 1, []string{"write"}, executor.execute)
+	if err != nil || string(got) != "volatile" {
+		t.Fatalf("Execute = %q, %v; want the value and no error", got, err)
+	}
+	if got := mw.Stats().Errors; got != 0 {
+		t.Errorf("Errors = %d, want 0", got)
+	}
+}
+
+func TestDoNotCache_NotABreakerFailure(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithExecutorBreaker(1, time.Minute))
+	executor := &volatileExecutor{}
+
+	for i := 0; i < 3; i++ {
+		if _, err := mw.Execute(context.Background(), "tool", 1, nil, executor.execute); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if got := executor.calls.Load(); got != 3 {
+		t.Errorf("executor calls = %d, want 3 with the breaker closed", got)
+	}
+}
+
+func TestDoNotCache_CoalescedWaitersShareValue(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithCoalesceWindow(time.Minute))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var calls atomic.Int32
+	executor := func(context.Context, string, any) ([]byte, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return []byte("volatile"), ErrDoNotCache
+	}
+	ctx := context.Background()
+
+	leader := make(chan error, 1)
+	go func() {
+		_, err := mw.Execute(ctx, "tool", 1, nil, executor)
+		leader <- err
+	}()
+	<-started
+	follower := make(chan []byte, 1)
+	go func() {
+		got, err := mw.Execute(ctx, "tool", 1, nil, executor)
+		if err != nil {
+			t.Errorf("follower error: %v", err)
+		}
+		follower <- got
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if err := <-leader; err != nil {
+		t.Fatalf("leader error: %v", err)
+	}
+	if got := <-follower; string(got) != "volatile" {
+		t.Errorf("follower got %q, want the shared value", got)
+	}
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor)
+	if got := calls.Load(); got < 2 {
+		t.Errorf("executor calls = %d; a do-not-cache result must not be kept for the window", got)
+	}
+}
+
+func TestDoNotCache_WarmAllSkips(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &volatileExecutor{}
+
+	results := mw.WarmAll(context.Background(), []WarmRequest{{ToolID: "tool", Input: 1}}, executor.execute)
+	if results[0].Status != WarmSkipped || results[0].Err != nil {
+		t.Errorf("result = %+v, want skipped without error", results[0])
+	}
+	if keys, _ := cache.KeysWithPrefix(context.Background(), ""); len(keys) != 0 {
+		t.Errorf("cache holds %v, want nothing stored", keys)
+	}
+}
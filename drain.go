@@ -0,0 +1,23 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Drain prepares the cache for shutdown. If snapshot is non-nil, the live
+// entries are first written to it as by WriteSnapshot; the cache is then
+// closed, which ends the eviction event stream. If ctx is already done the
+// snapshot is skipped, but the cache is still closed and ctx.Err() is
+// returned. A snapshot that has started is written in full, so bound slow
+// writers with their own deadline.
+func (c *MemoryCache) Drain(ctx context.Context, snapshot io.Writer) error {
+	var snapErr error
+	if snapshot != nil {
+		snapErr = c.WriteSnapshot(ctx, snapshot)
+	} else {
+		snapErr = ctx.Err()
+	}
+	return errors.Join(snapErr, c.Close())
+}
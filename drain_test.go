@@ -0,0 +1,58 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrain_WritesSnapshotAndCloses(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithEvictionEvents(1))
+	ctx := context.Background()
+	_ = cache.Set(ctx, "a", []byte("1"), time.Hour)
+	_ = cache.Set(ctx, "b", []byte("2"), time.Hour)
+
+	var buf bytes.Buffer
+	if err := cache.Drain(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, open := <-cache.EvictionEvents(); open {
+		t.Error("Drain should close the eviction event stream")
+	}
+
+	restored := NewMemoryCache(DefaultPolicy())
+	if n, err := restored.ReadSnapshot(ctx, &buf); err != nil || n != 2 {
+		t.Errorf("ReadSnapshot() = %d, %v; want both entries", n, err)
+	}
+}
+
+func TestDrain_WithoutSnapshot(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	if err := cache.Drain(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Drain(context.Background(), nil); err != nil {
+		t.Errorf("Drain should be idempotent, got %v", err)
+	}
+}
+
+func TestDrain_ExpiredContext(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithEvictionEvents(1))
+	_ = cache.Set(context.Background(), "a", []byte("1"), time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	var buf bytes.Buffer
+	if err := cache.Drain(ctx, &buf); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain() = %v, want DeadlineExceeded", err)
+	}
+	if buf.Len() != 0 {
+		t.Error("no snapshot should be written after the deadline")
+	}
+	if _, open := <-cache.EvictionEvents(); open {
+		t.Error("the cache should still be closed")
+	}
+}
@@ -0,0 +1,98 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// etagKeySuffix is appended to a result key to form the key under which a
+// revalidatable copy of the result is kept past its TTL.
+const etagKeySuffix = ":etag"
+
+// ErrNotModified is returned by an executor, in the manner of HTTP 304,
+// when the ETag passed to it via IfNoneMatch still matches the current
+// result. The middleware then serves the copy it holds and refreshes its
+// TTL instead of storing a new result.
+var ErrNotModified = errors.New("toolcache: not modified")
+
+type ifNoneMatchKey struct{}
+
+// IfNoneMatch returns the ETag of the cached copy being revalidated, if
+// any. Executors that find the result unchanged should return
+// ErrNotModified without producing it again.
+func IfNoneMatch(ctx context.Context) (etag string, ok bool) {
+	etag, ok = ctx.Value(ifNoneMatchKey{}).(string)
+	return etag, ok
+}
+
+// WithETagRevalidation enables conditional revalidation for results whose
+// EntryMeta carries an ETag, as reported by a MetaExecutor through
+// ExecuteWithInfo. Such results are also kept for grace past their TTL.
+// When the entry expires, the next call runs the executor with the stored
+// ETag available through IfNoneMatch; if it returns ErrNotModified, the
+// kept copy is served and stored again for a fresh TTL, otherwise the new
+// result replaces it as usual.
+//
+// The kept copy doubles the storage used by such results, and it requires
+// a cache implementing MetaCache. A grace of 0 (the default) disables it.
+func WithETagRevalidation(grace time.Duration) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.etagGrace = max(grace, 0)
+	}
+}
+
+// staleForRevalidation returns the kept copy of key's result, if
+// conditional revalidation is enabled and one exists.
+func (m *CacheMiddleware) staleForRevalidation(ctx context.Context, key string) ([]byte, EntryMeta, bool) {
+	if m.etagGrace <= 0 {
+		return nil, EntryMeta{}, false
+	}
+	mc, ok := m.cache.(MetaCache)
+	if !ok {
+		return nil, EntryMeta{}, false
+	}
+	etagKey, ok := m.suffixKey(key, etagKeySuffix)
+	if !ok {
+		return nil, EntryMeta{}, false
+	}
+	value, meta, ok := mc.GetWithMeta(ctx, etagKey)
+	if !ok || meta.ETag == "" {
+		return nil, EntryMeta{}, false
+	}
+	return value, meta, true
+}
+
+// keepForRevalidation stores a copy of a result with an ETag for ttl plus
+// the grace period.
+func (m *CacheMiddleware) keepForRevalidation(ctx context.Context, key string, value []byte, meta EntryMeta, ttl time.Duration) {
+	if m.etagGrace <= 0 || meta.ETag == "" {
+		return
+	}
+	mc, ok := m.cache.(MetaCache)
+	if !ok {
+		return
+	}
+	if etagKey, ok := m.suffixKey(key, etagKeySuffix); ok {
+		_ = mc.SetWithMeta(ctx, etagKey, value, meta, ttl+m.etagGrace)
+	}
+}
+
+// refreshNotModified serves the kept copy after the executor reported
+// ErrNotModified, storing it again for a fresh TTL.
+func (m *CacheMiddleware) refreshNotModified(ctx context.Context, toolID, key string, stale []byte, meta EntryMeta, info *ExecInfo, latency time.Duration) []byte {
+	if info == nil {
+		info = &ExecInfo{}
+	}
+	info.Meta, info.Cached = meta, true
+
+	delta := ToolStats{Misses: 1}
+	ttl := m.resultTTL(toolID, stale)
+	if ttl > 0 && !m.deadlineTooShort(ctx) && m.store(ctx, toolID, key, stale, ttl, info) == nil {
+		delta.BytesStored = uint64(len(stale))
+		m.logOp(ctx, OpSet, toolID, key)
+		m.keepForRevalidation(ctx, key, stale, meta, ttl)
+	}
+	m.stats.recordMiss(toolID, delta, latency)
+	return stale
+}
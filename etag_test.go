@@ -0,0 +1,134 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// versionedTool is a MetaExecutor serving body under etag and honoring
+// IfNoneMatch.
+type versionedTool struct {
+	body, etag string
+	calls      int
+	sawETag    []string
+}
+
+func (v *versionedTool) execute(ctx context.Context, _ string, _ any) ([]byte, EntryMeta, error) {
+	v.calls++
+	etag, _ := IfNoneMatch(ctx)
+	v.sawETag = append(v.sawETag, etag)
+	if etag == v.etag {
+		return nil, EntryMeta{}, ErrNotModified
+	}
+	return []byte(v.body), EntryMeta{ContentType: "text/plain", ETag: v.etag}, nil
+}
+
+func newETagTestMiddleware(clock Clock) (*CacheMiddleware, *MemoryCache) {
+	policy := Policy{DefaultTTL: time.Minute}
+	cache := NewMemoryCacheWithOptions(policy, WithClock(clock))
+	return NewCacheMiddleware(cache, NewDefaultKeyer(), policy, nil, WithETagRevalidation(time.Hour)), cache
+}
+
+func TestETagRevalidation_NotModifiedRefreshesTTL(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mw, cache := newETagTestMiddleware(clock)
+	tool := &versionedTool{body: "v1", etag: `"1"`}
+	ctx := context.Background()
+
+	if _, _, err := mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Minute)
+
+	got, info, err := mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute)
+	if err != nil || string(got) != "v1" {
+		t.Fatalf("revalidated call = %q, %v; want the kept copy", got, err)
+	}
+	if !info.Cached || info.Meta.ETag != `"1"` || info.Meta.ContentType != "text/plain" {
+		t.Errorf("info = %+v, want the kept copy's metadata", info)
+	}
+	if tool.calls != 2 || tool.sawETag[1] != `"1"` {
+		t.Errorf("executor calls = %d, etags seen = %q", tool.calls, tool.sawETag)
+	}
+
+	// The refreshed entry is served without the executor for a new TTL.
+	clock.Advance(30 * time.Second)
+	if _, _, err := mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute); err != nil {
+		t.Fatal(err)
+	}
+	if tool.calls != 2 {
+		t.Errorf("refreshed entry should be a hit, executor calls = %d", tool.calls)
+	}
+
+	key, _ := NewDefaultKeyer().Key("doc", "a")
+	if _, meta, ok := cache.GetWithMeta(ctx, key); !ok || meta.ETag != `"1"` {
+		t.Errorf("primary entry = %v, %+v; want it stored with its ETag", ok, meta)
+	}
+}
+
+func TestETagRevalidation_ModifiedReplaces(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mw, _ := newETagTestMiddleware(clock)
+	tool := &versionedTool{body: "v1", etag: `"1"`}
+	ctx := context.Background()
+
+	_, _, _ = mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute)
+	clock.Advance(2 * time.Minute)
+	tool.body, tool.etag = "v2", `"2"`
+
+	got, info, err := mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute)
+	if err != nil || string(got) != "v2" || info.Cached || info.Meta.ETag != `"2"` {
+		t.Fatalf("modified call = %q, %+v, %v; want the new result", got, info, err)
+	}
+	if tool.sawETag[1] != `"1"` {
+		t.Errorf("executor should see the old ETag, saw %q", tool.sawETag[1])
+	}
+
+	// The new version is what gets revalidated next time.
+	clock.Advance(2 * time.Minute)
+	got, _, _ = mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute)
+	if string(got) != "v2" || tool.sawETag[2] != `"2"` {
+		t.Errorf("second revalidation = %q with ETag %q", got, tool.sawETag[2])
+	}
+}
+
+func TestETagRevalidation_GraceExpires(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mw, _ := newETagTestMiddleware(clock)
+	tool := &versionedTool{body: "v1", etag: `"1"`}
+	ctx := context.Background()
+
+	_, _, _ = mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute)
+	clock.Advance(2 * time.Hour)
+	_, _, _ = mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute)
+	if tool.sawETag[1] != "" {
+		t.Errorf("no ETag should be offered after the grace period, got %q", tool.sawETag[1])
+	}
+}
+
+func TestETagRevalidation_DisabledByDefault(t *testing.T) {
+	policy := Policy{DefaultTTL: time.Minute}
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mw := NewCacheMiddleware(NewMemoryCacheWithOptions(policy, WithClock(clock)), NewDefaultKeyer(), policy, nil)
+	tool := &versionedTool{body: "v1", etag: `"1"`}
+	ctx := context.Background()
+
+	_, _, _ = mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute)
+	clock.Advance(2 * time.Minute)
+	_, _, _ = mw.ExecuteWithInfo(ctx, "doc", "a", nil, tool.execute)
+	if tool.sawETag[1] != "" {
+		t.Errorf("IfNoneMatch should be unset without WithETagRevalidation, got %q", tool.sawETag[1])
+	}
+}
+
+func TestETagRevalidation_NotModifiedWithoutCopyIsError(t *testing.T) {
+	mw, _ := newETagTestMiddleware(NewManualClock(time.Now()))
+	executor := func(context.Context, string, any) ([]byte, EntryMeta, error) {
+		return nil, EntryMeta{}, ErrNotModified
+	}
+	if _, _, err := mw.ExecuteWithInfo(context.Background(), "doc", "a", nil, executor); !errors.Is(err, ErrNotModified) {
+		t.Errorf("err = %v, want ErrNotModified when nothing is kept", err)
+	}
+}
@@ -0,0 +1,87 @@
+package toolcache
+
+import "time"
+
+// EvictReason describes why an entry left the cache.
+type EvictReason int
+
+const (
+	// EvictReasonExpired means the entry's TTL elapsed.
+	EvictReasonExpired EvictReason = iota
+
+	// EvictReasonDeleted means the entry was removed by an explicit Delete.
+	EvictReasonDeleted
+
+	// EvictReasonPressure means the entry was shed by EvictFraction, for
+	// example under memory pressure.
+	EvictReasonPressure
+
+	// EvictReasonCapacity means the entry was the least recently used when
+	// a Set exceeded WithMaxEntries.
+	EvictReasonCapacity
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictReasonExpired:
+		return "expired"
+	case EvictReasonDeleted:
+		return "deleted"
+	case EvictReasonPressure:
+		return "pressure"
+	case EvictReasonCapacity:
+		return "capacity"
+	default:
+		return "unknown"
+	}
+}
+
+// EvictEvent reports an entry leaving a MemoryCache.
+type EvictEvent struct {
+	Key    string
+	Reason EvictReason
+	At     time.Time
+}
+
+// WithEvictionEvents enables the eviction event stream with a channel of
+// the given buffer size. Events are dropped rather than blocking cache
+// operations when the buffer is full; see EvictionEventsDropped.
+func WithEvictionEvents(buffer int) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		if buffer < 0 {
+			buffer = 0
+		}
+		c.events = make(chan EvictEvent, buffer)
+	}
+}
+
+// EvictionEvents returns the eviction event stream, or nil if it was not
+// enabled with WithEvictionEvents. The channel is closed by Close.
+func (c *MemoryCache) EvictionEvents() <-chan EvictEvent {
+	return c.events
+}
+
+// EvictionEventsDropped reports how many events were dropped because the
+// consumer did not keep up.
+func (c *MemoryCache) EvictionEventsDropped() uint64 {
+	return c.eventsDropped.Load()
+}
+
+// ResetStats zeroes the cache's cumulative counters, currently
+// EvictionEventsDropped. Cached entries are unaffected.
+func (c *MemoryCache) ResetStats() {
+	c.eventsDropped.Store(0)
+}
+
+// emitEvict publishes an eviction event without blocking.
+// Callers must hold c.mu for writing.
+func (c *MemoryCache) emitEvict(key string, reason EvictReason) {
+	if c.events == nil || c.closed {
+		return
+	}
+	select {
+	case c.events <- EvictEvent{Key: key, Reason: reason, At: c.clock.Now()}:
+	default:
+		c.eventsDropped.Add(1)
+	}
+}
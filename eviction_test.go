@@ -0,0 +1,126 @@
+package toolcache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEvictionEvents_DisabledByDefault(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	if cache.EvictionEvents() != nil {
+		t.Error("EvictionEvents should be nil when not enabled")
+	}
+	_ = cache.Set(context.Background(), "k", []byte("v"), time.Minute)
+	_ = cache.Delete(context.Background(), "k")
+	if err := cache.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestEvictionEvents_DeleteAndExpiry(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithEvictionEvents(8))
+	ctx := context.Background()
+	events := cache.EvictionEvents()
+
+	_ = cache.Set(ctx, "deleted", []byte("v"), time.Minute)
+	_ = cache.Set(ctx, "expiring", []byte("v"), 20*time.Millisecond)
+
+	_ = cache.Delete(ctx, "deleted")
+	_ = cache.Delete(ctx, "never-set") // no event for absent keys
+
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := cache.Get(ctx, "expiring"); ok {
+		t.Fatal("expected expired entry to miss")
+	}
+
+	want := []EvictEvent{
+		{Key: "deleted", Reason: EvictReasonDeleted},
+		{Key: "expiring", Reason: EvictReasonExpired},
+	}
+	for _, w := range want {
+		select {
+		case ev := <-events:
+			if ev.Key != w.Key || ev.Reason != w.Reason {
+				t.Errorf("got event %s/%s, want %s/%s", ev.Key, ev.Reason, w.Key, w.Reason)
+			}
+			if ev.At.IsZero() {
+				t.Error("event timestamp should be set")
+			}
+		default:
+			t.Fatalf("missing event for %s", w.Key)
+		}
+	}
+
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected extra event: %+v", ev)
+	default:
+	}
+}
+
+func TestEvictionEvents_FullBufferDoesNotBlock(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithEvictionEvents(2))
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			_ = cache.Set(ctx, key, []byte("v"), time.Minute)
+			_ = cache.Delete(ctx, key)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cache operations blocked on a full event buffer")
+	}
+
+	if got := cache.EvictionEventsDropped(); got != 8 {
+		t.Errorf("EvictionEventsDropped() = %d, want 8", got)
+	}
+	if got := len(cache.EvictionEvents()); got != 2 {
+		t.Errorf("buffered events = %d, want 2", got)
+	}
+
+	_ = cache.Set(ctx, "live", []byte("v"), time.Minute)
+	cache.ResetStats()
+	if got := cache.EvictionEventsDropped(); got != 0 {
+		t.Errorf("EvictionEventsDropped() after ResetStats = %d, want 0", got)
+	}
+	if _, ok := cache.Get(ctx, "live"); !ok {
+		t.Error("ResetStats must not drop cached entries")
+	}
+}
+
+func TestEvictionEvents_CloseClosesChannel(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithEvictionEvents(4))
+	ctx := context.Background()
+	events := cache.EvictionEvents()
+
+	_ = cache.Set(ctx, "k", []byte("v"), time.Minute)
+	_ = cache.Delete(ctx, "k")
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatalf("second Close failed: %v", err)
+	}
+
+	// Buffered events are still delivered, then the channel reports closed.
+	if ev, ok := <-events; !ok || ev.Key != "k" {
+		t.Errorf("expected buffered event for k, got %+v ok=%v", ev, ok)
+	}
+	if _, ok := <-events; ok {
+		t.Error("expected channel to be closed")
+	}
+
+	// Operations after Close must not panic on the closed channel.
+	_ = cache.Set(ctx, "k2", []byte("v"), time.Minute)
+	_ = cache.Delete(ctx, "k2")
+}
@@ -276,3 +276,27 @@ func ExampleValidateKey() {
 	// toolcache: key is invalid
 	// toolcache: key is invalid
 }
+
+// ExampleCacheFuncs demonstrates stubbing a cache inline from closures.
+func ExampleCacheFuncs() {
+	store := map[string][]byte{}
+	cache := toolcache.CacheFuncs{
+		GetFunc: func(_ context.Context, key string) ([]byte, bool) {
+			v, ok := store[key]
+			return v, ok
+		},
+		SetFunc: func(_ context.Context, key string, value []byte, _ time.Duration) error {
+			store[key] = value
+			return nil
+		},
+	}
+
+	mw := toolcache.NewCacheMiddleware(cache, toolcache.NewDefaultKeyer(), toolcache.DefaultPolicy(), nil)
+	executor := func(ctx context.Context, toolID string, input any) ([]byte, error) {
+		return []byte("result"), nil
+	}
+
+	_, _ = mw.Execute(context.Background(), "ns:tool", nil, nil, executor)
+	fmt.Println(len(store))
+	// Output: 1
+}
@@ -0,0 +1,47 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+)
+
+// Flushable is implemented by caches that can remove every entry at once.
+type Flushable interface {
+	Flush(ctx context.Context) error
+}
+
+// Flush removes every entry. Removals are reported as EvictReasonDeleted.
+func (c *MemoryCache) Flush(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	for key, entry := range c.entries {
+		c.removeLocked(key, entry)
+		c.emitEvict(key, EvictReasonDeleted)
+		c.releaseLocked(entry)
+	}
+	c.dependents = nil
+	c.mu.Unlock()
+
+	return nil
+}
+
+// FlushAll flushes each cache in order, for example every tier of a layered
+// setup during test teardown or emergency invalidation. A failing cache
+// does not stop the others from being flushed; all errors are joined.
+// Caches that do not implement Flushable are skipped.
+func FlushAll(ctx context.Context, caches ...Cache) error {
+	var errs []error
+	for _, c := range caches {
+		if f, ok := c.(Flushable); ok {
+			if err := f.Flush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var _ Flushable = (*MemoryCache)(nil)
@@ -0,0 +1,72 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingFlusher struct {
+	Cache
+	err     error
+	flushed bool
+}
+
+func (f *failingFlusher) Flush(context.Context) error {
+	f.flushed = true
+	return f.err
+}
+
+func TestMemoryCache_Flush(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithEvictionEvents(4))
+	ctx := context.Background()
+	_ = cache.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = cache.Set(ctx, "b", []byte("2"), time.Minute)
+
+	if err := cache.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, ok := cache.Get(ctx, key); ok {
+			t.Errorf("%s should be flushed", key)
+		}
+	}
+	for range 2 {
+		if ev := <-cache.EvictionEvents(); ev.Reason != EvictReasonDeleted {
+			t.Errorf("Reason = %v, want deleted", ev.Reason)
+		}
+	}
+}
+
+func TestFlushAll(t *testing.T) {
+	ctx := context.Background()
+	first := NewMemoryCache(DefaultPolicy())
+	last := NewMemoryCache(DefaultPolicy())
+	_ = first.Set(ctx, "k", []byte("v"), time.Minute)
+	_ = last.Set(ctx, "k", []byte("v"), time.Minute)
+
+	errTier := errors.New("tier unavailable")
+	broken := &failingFlusher{err: errTier}
+	plain := CacheFuncs{}
+
+	err := FlushAll(ctx, first, broken, plain, last)
+	if !errors.Is(err, errTier) {
+		t.Errorf("FlushAll() = %v, want it to include the tier error", err)
+	}
+	if !broken.flushed {
+		t.Error("failing tier should still be asked to flush")
+	}
+	if _, ok := first.Get(ctx, "k"); ok {
+		t.Error("first tier should be flushed")
+	}
+	if _, ok := last.Get(ctx, "k"); ok {
+		t.Error("tiers after a failure should still be flushed")
+	}
+}
+
+func TestFlushAll_NoErrors(t *testing.T) {
+	if err := FlushAll(context.Background(), NewMemoryCache(DefaultPolicy())); err != nil {
+		t.Errorf("FlushAll() = %v, want nil", err)
+	}
+}
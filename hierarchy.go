@@ -0,0 +1,77 @@
+package toolcache
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ChildKeySeparator joins a parent key and a child suffix in ChildKey.
+const ChildKeySeparator = "/"
+
+// ChildKey derives the key for a sub-result stored under parent. Composite
+// tools can cache reusable components under their own result key and have
+// them removed together by DeleteTree. Suffixes may themselves contain
+// ChildKeySeparator to express deeper nesting.
+func ChildKey(parent, suffix string) string {
+	return parent + ChildKeySeparator + suffix
+}
+
+// DeleteTree removes parent and every entry nested under it via ChildKey,
+// along with their dependents (see SetWithDeps), returning the number of
+// entries removed. It scans all entries, so its cost is proportional to
+// the cache size.
+func (c *MemoryCache) DeleteTree(ctx context.Context, parent string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	prefix := parent + ChildKeySeparator
+	removed := 0
+
+	var roots []string
+	c.mu.Lock()
+	for key, entry := range c.entries {
+		if key == parent || strings.HasPrefix(key, prefix) {
+			c.removeLocked(key, entry)
+			c.emitEvict(key, EvictReasonDeleted)
+			c.releaseLocked(entry)
+			roots = append(roots, key)
+		}
+	}
+	removed += len(roots) + c.cascadeLocked(roots...)
+	c.mu.Unlock()
+
+	return removed, nil
+}
+
+// KeysWithPrefix returns the non-expired keys starting with prefix, sorted.
+// Use ToolKeyPrefix to list one tool's entries. It is a single pass over
+// all entries under a read lock, so its cost is proportional to the cache
+// size rather than the number of matches; avoid calling it on hot paths.
+func (c *MemoryCache) KeysWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := c.clock.Now()
+	var keys []string
+
+	c.mu.RLock()
+	for key, entry := range c.entries {
+		if strings.HasPrefix(key, prefix) && !c.staleLocked(key, entry, now) {
+			keys = append(keys, key)
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// ToolKeyPrefix returns the prefix shared by every key DefaultKeyer derives
+// for toolID. Tool IDs may contain ':', so the prefix for "ns" also matches
+// keys of "ns:search"; that is useful for listing a whole namespace.
+func ToolKeyPrefix(toolID string) string {
+	return "toolcache:" + toolID + ":"
+}
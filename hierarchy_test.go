@@ -0,0 +1,126 @@
+package toolcache
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestChildKey(t *testing.T) {
+	parent := "toolcache:pipeline:abc"
+	child := ChildKey(parent, "stage1")
+	if child != "toolcache:pipeline:abc/stage1" {
+		t.Errorf("ChildKey = %q", child)
+	}
+	if err := ValidateKey(child); err != nil {
+		t.Errorf("child key should be valid: %v", err)
+	}
+	if grandchild := ChildKey(child, "part"); grandchild != "toolcache:pipeline:abc/stage1/part" {
+		t.Errorf("nested ChildKey = %q", grandchild)
+	}
+}
+
+func TestDeleteTree_CascadesToChildren(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	parent := "toolcache:pipeline:abc"
+	stage1 := ChildKey(parent, "stage1")
+	stage1part := ChildKey(stage1, "part")
+	stage2 := ChildKey(parent, "stage2")
+	sibling := "toolcache:pipeline:abcd" // shares a string prefix but is not a child
+	other := ChildKey("toolcache:pipeline:xyz", "stage1")
+
+	for _, key := range []string{parent, stage1, stage1part, stage2, sibling, other} {
+		_ = cache.Set(ctx, key, []byte(key), time.Minute)
+	}
+
+	// Children are individually retrievable.
+	if v, ok := cache.Get(ctx, stage1); !ok || string(v) != stage1 {
+		t.Errorf("child Get = %q ok=%v", v, ok)
+	}
+
+	removed, err := cache.DeleteTree(ctx, parent)
+	if err != nil {
+		t.Fatalf("DeleteTree failed: %v", err)
+	}
+	if removed != 4 {
+		t.Errorf("removed %d entries, want 4", removed)
+	}
+	for _, key := range []string{parent, stage1, stage1part, stage2} {
+		if _, ok := cache.Get(ctx, key); ok {
+			t.Errorf("%q should be invalidated", key)
+		}
+	}
+	for _, key := range []string{sibling, other} {
+		if _, ok := cache.Get(ctx, key); !ok {
+			t.Errorf("%q should survive", key)
+		}
+	}
+}
+
+func TestDeleteTree_SubtreeOnly(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	parent := "p"
+	child := ChildKey(parent, "c")
+	grandchild := ChildKey(child, "g")
+	for _, key := range []string{parent, child, grandchild} {
+		_ = cache.Set(ctx, key, []byte("v"), time.Minute)
+	}
+
+	if removed, _ := cache.DeleteTree(ctx, child); removed != 2 {
+		t.Errorf("removed %d, want 2", removed)
+	}
+	if _, ok := cache.Get(ctx, parent); !ok {
+		t.Error("parent should survive invalidating a child subtree")
+	}
+}
+
+func TestKeysWithPrefix(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	keyer := NewDefaultKeyer()
+	ctx := context.Background()
+
+	var searchKeys []string
+	for i := range 3 {
+		key, _ := keyer.Key("ns:search", i)
+		searchKeys = append(searchKeys, key)
+		_ = cache.Set(ctx, key, []byte("v"), time.Minute)
+	}
+	fetchKey, _ := keyer.Key("ns:fetch", 0)
+	_ = cache.Set(ctx, fetchKey, []byte("v"), time.Minute)
+	expiredKey, _ := keyer.Key("ns:search", "old")
+	_ = cache.Set(ctx, expiredKey, []byte("v"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	got, err := cache.KeysWithPrefix(ctx, ToolKeyPrefix("ns:search"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(searchKeys)
+	if !slices.Equal(got, searchKeys) {
+		t.Errorf("KeysWithPrefix() = %v, want %v", got, searchKeys)
+	}
+
+	all, _ := cache.KeysWithPrefix(ctx, ToolKeyPrefix("ns"))
+	if len(all) != 4 {
+		t.Errorf("namespace prefix matched %d keys, want 4", len(all))
+	}
+
+	none, _ := cache.KeysWithPrefix(ctx, ToolKeyPrefix("other"))
+	if len(none) != 0 {
+		t.Errorf("unknown tool matched %v", none)
+	}
+}
+
+func TestKeysWithPrefix_CanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewMemoryCache(DefaultPolicy()).KeysWithPrefix(ctx, ""); err == nil {
+		t.Error("expected context error")
+	}
+}
@@ -0,0 +1,121 @@
+package toolcache
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+)
+
+// KeyCount is an approximate access count for a cache key.
+type KeyCount struct {
+	Key   string
+	Count uint64
+}
+
+// WithHotKeyTracking tracks approximately how often each key is accessed,
+// for HotKeys. It uses the Space-Saving algorithm over capacity counters,
+// so memory stays bounded no matter how many distinct keys are seen, and
+// any key accessed more than 1/capacity of the time is guaranteed to be
+// tracked. Counts may be overestimated for keys that entered the tracker
+// late. Values below 1 disable tracking, the default.
+func WithHotKeyTracking(capacity int) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		if capacity < 1 {
+			m.hotKeys = nil
+			return
+		}
+		m.hotKeys = &hotKeyTracker{capacity: capacity, index: make(map[string]*hotKey, capacity)}
+	}
+}
+
+// HotKeys returns up to n of the most frequently accessed keys, most
+// accessed first, or nil if tracking is disabled. Hits, misses and
+// negatively cached errors all count as accesses.
+func (m *CacheMiddleware) HotKeys(n int) []KeyCount {
+	if m.hotKeys == nil || n <= 0 {
+		return nil
+	}
+	return m.hotKeys.top(n)
+}
+
+type hotKey struct {
+	key   string
+	count uint64
+	index int
+}
+
+// hotKeyTracker implements Space-Saving with a min-heap of counters.
+type hotKeyTracker struct {
+	mu       sync.Mutex
+	capacity int
+	index    map[string]*hotKey
+	heap     hotKeyHeap
+}
+
+func (t *hotKeyTracker) observe(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if hk, ok := t.index[key]; ok {
+		hk.count++
+		heap.Fix(&t.heap, hk.index)
+		return
+	}
+	if len(t.heap) < t.capacity {
+		hk := &hotKey{key: key, count: 1}
+		t.index[key] = hk
+		heap.Push(&t.heap, hk)
+		return
+	}
+	// Replace the least counted key, inheriting its count as the error
+	// bound for the newcomer.
+	victim := t.heap[0]
+	delete(t.index, victim.key)
+	victim.key = key
+	victim.count++
+	t.index[key] = victim
+	heap.Fix(&t.heap, 0)
+}
+
+func (t *hotKeyTracker) top(n int) []KeyCount {
+	t.mu.Lock()
+	counts := make([]KeyCount, len(t.heap))
+	for i, hk := range t.heap {
+		counts[i] = KeyCount{Key: hk.key, Count: hk.count}
+	}
+	t.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+type hotKeyHeap []*hotKey
+
+func (h hotKeyHeap) Len() int           { return len(h) }
+func (h hotKeyHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h hotKeyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hotKeyHeap) Push(x any) {
+	hk := x.(*hotKey)
+	hk.index = len(*h)
+	*h = append(*h, hk)
+}
+
+func (h *hotKeyHeap) Pop() any {
+	old := *h
+	hk := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return hk
+}
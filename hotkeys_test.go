@@ -0,0 +1,79 @@
+package toolcache
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestHotKeys_SkewedAccess(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithHotKeyTracking(16))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+
+	// Three hot inputs take ~60% of traffic; the rest is a long tail of
+	// 1000 distinct inputs.
+	hot := []string{"alpha", "beta", "gamma"}
+	for i := 0; i < 5000; i++ {
+		input := fmt.Sprintf("tail-%d", rng.Intn(1000))
+		if r := rng.Intn(10); r < 6 {
+			input = hot[r%3]
+		}
+		_, _ = mw.Execute(ctx, "tool", input, nil, executor.execute)
+	}
+
+	keyer := NewDefaultKeyer()
+	want := make(map[string]bool)
+	for _, in := range hot {
+		k, _ := keyer.Key("tool", in)
+		want[k] = true
+	}
+	got := mw.HotKeys(3)
+	if len(got) != 3 {
+		t.Fatalf("HotKeys(3) returned %d keys", len(got))
+	}
+	for _, kc := range got {
+		if !want[kc.Key] {
+			t.Errorf("unexpected hot key %+v", kc)
+		}
+		if kc.Count < 800 {
+			t.Errorf("hot key count %d is too low", kc.Count)
+		}
+	}
+	if got[0].Count < got[1].Count || got[1].Count < got[2].Count {
+		t.Errorf("HotKeys not sorted: %+v", got)
+	}
+	if all := mw.HotKeys(100); len(all) != 16 {
+		t.Errorf("tracker should stay bounded at 16 keys, got %d", len(all))
+	}
+}
+
+func TestHotKeys_Disabled(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	_, _ = mw.Execute(context.Background(), "tool", 1, nil, (&mockExecutor{}).execute)
+	if got := mw.HotKeys(5); got != nil {
+		t.Errorf("HotKeys() = %v, want nil when disabled", got)
+	}
+}
+
+func TestHotKeyTracker_ExactUnderCapacity(t *testing.T) {
+	tr := &hotKeyTracker{capacity: 4, index: make(map[string]*hotKey)}
+	for key, n := range map[string]int{"a": 5, "b": 3, "c": 1} {
+		for i := 0; i < n; i++ {
+			tr.observe(key)
+		}
+	}
+	got := tr.top(10)
+	want := []KeyCount{{"a", 5}, {"b", 3}, {"c", 1}}
+	if len(got) != len(want) {
+		t.Fatalf("top = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("top[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrImmutable is returned by ImmutableCache.Set and Delete.
+var ErrImmutable = errors.New("toolcache: cache is immutable")
+
+// ImmutableCache serves a fixed set of entries, such as reference data
+// loaded once at startup, without any locking: Get reads an atomically
+// published, never-modified map. Entries do not expire. Set and Delete
+// fail with ErrImmutable; use Load to replace the whole contents at once.
+// The zero value is an empty cache.
+type ImmutableCache struct {
+	entries atomic.Pointer[map[string][]byte]
+}
+
+// ImmutableCacheBuilder collects entries for an ImmutableCache.
+type ImmutableCacheBuilder struct {
+	entries map[string][]byte
+	err     error
+}
+
+// NewImmutableCacheBuilder returns an empty builder.
+func NewImmutableCacheBuilder() *ImmutableCacheBuilder {
+	return &ImmutableCacheBuilder{entries: make(map[string][]byte)}
+}
+
+// Add records value under key, replacing any earlier value. The value is
+// copied. An invalid key is reported by Build.
+func (b *ImmutableCacheBuilder) Add(key string, value []byte) *ImmutableCacheBuilder {
+	if err := ValidateKey(key); err != nil {
+		if b.err == nil {
+			b.err = fmt.Errorf("toolcache: immutable entry %q: %w", key, err)
+		}
+		return b
+	}
+	b.entries[key] = bytes.Clone(value)
+	return b
+}
+
+// Build returns a cache holding the entries added so far, or the first
+// error from Add. The builder may keep being used; later additions do not
+// affect caches already built.
+func (b *ImmutableCacheBuilder) Build() (*ImmutableCache, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	c := &ImmutableCache{}
+	c.entries.Store(b.snapshot())
+	return c, nil
+}
+
+func (b *ImmutableCacheBuilder) snapshot() *map[string][]byte {
+	entries := make(map[string][]byte, len(b.entries))
+	for key, value := range b.entries {
+		entries[key] = value
+	}
+	return &entries
+}
+
+// Load atomically replaces the cache contents with the builder's entries.
+// Concurrent Gets see either the old or the new contents, never a mix.
+func (c *ImmutableCache) Load(b *ImmutableCacheBuilder) error {
+	if b.err != nil {
+		return b.err
+	}
+	c.entries.Store(b.snapshot())
+	return nil
+}
+
+// load returns the current entries; a zero ImmutableCache is empty.
+func (c *ImmutableCache) load() map[string][]byte {
+	if entries := c.entries.Load(); entries != nil {
+		return *entries
+	}
+	return nil
+}
+
+// Len returns the number of entries.
+func (c *ImmutableCache) Len() int {
+	return len(c.load())
+}
+
+// Get returns a copy of the value stored under key.
+func (c *ImmutableCache) Get(_ context.Context, key string) ([]byte, bool) {
+	value, ok := c.load()[key]
+	if !ok {
+		return nil, false
+	}
+	return bytes.Clone(value), true
+}
+
+// Set always fails with ErrImmutable.
+func (c *ImmutableCache) Set(context.Context, string, []byte, time.Duration) error {
+	return ErrImmutable
+}
+
+// Delete always fails with ErrImmutable.
+func (c *ImmutableCache) Delete(context.Context, string) error {
+	return ErrImmutable
+}
+
+var _ Cache = (*ImmutableCache)(nil)
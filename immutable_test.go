@@ -0,0 +1,125 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestImmutableCache(t *testing.T) {
+	builder := NewImmutableCacheBuilder().Add("a", []byte("1")).Add("b", []byte("2"))
+	cache, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	got, ok := cache.Get(ctx, "a")
+	if !ok || string(got) != "1" {
+		t.Fatalf("Get(a) = %q, %v", got, ok)
+	}
+	got[0] = 'x'
+	if again, _ := cache.Get(ctx, "a"); string(again) != "1" {
+		t.Error("Get must return a copy")
+	}
+	if _, ok := cache.Get(ctx, "missing"); ok {
+		t.Error("missing key should miss")
+	}
+
+	if err := cache.Set(ctx, "c", []byte("3"), time.Minute); !errors.Is(err, ErrImmutable) {
+		t.Errorf("Set() = %v, want ErrImmutable", err)
+	}
+	if err := cache.Delete(ctx, "a"); !errors.Is(err, ErrImmutable) {
+		t.Errorf("Delete() = %v, want ErrImmutable", err)
+	}
+
+	builder.Add("c", []byte("3"))
+	if cache.Len() != 2 {
+		t.Error("later additions must not affect a built cache")
+	}
+	if err := cache.Load(builder); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := cache.Get(ctx, "c"); !ok || string(got) != "3" || cache.Len() != 3 {
+		t.Errorf("after Load: Get(c) = %q, %v; Len = %d", got, ok, cache.Len())
+	}
+}
+
+func TestImmutableCacheBuilder_InvalidKey(t *testing.T) {
+	_, err := NewImmutableCacheBuilder().Add("", []byte("v")).Add("ok", nil).Build()
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Build() = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestImmutableCache_ZeroValue(t *testing.T) {
+	var c ImmutableCache
+	if _, ok := c.Get(context.Background(), "a"); ok || c.Len() != 0 {
+		t.Error("the zero ImmutableCache should be empty")
+	}
+	if err := c.Load(NewImmutableCacheBuilder().Add("a", []byte("1"))); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.Get(context.Background(), "a"); !ok || string(got) != "1" {
+		t.Errorf("Get after Load = %q, %v", got, ok)
+	}
+}
+
+func TestImmutableCache_ConcurrentLoad(t *testing.T) {
+	v1, _ := NewImmutableCacheBuilder().Add("k", []byte("1")).Build()
+	next := NewImmutableCacheBuilder().Add("k", []byte("2"))
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			if got, ok := v1.Get(ctx, "k"); !ok || (string(got) != "1" && string(got) != "2") {
+				t.Errorf("Get(k) = %q, %v", got, ok)
+				return
+			}
+		}
+	}()
+	_ = v1.Load(next)
+	<-done
+}
+
+func benchmarkParallelGet(b *testing.B, cache Cache) {
+	ctx := context.Background()
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("toolcache:ref:%d", i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, ok := cache.Get(ctx, keys[i%len(keys)]); !ok {
+				b.Error("miss")
+				return
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkImmutableCache_ParallelGet(b *testing.B) {
+	builder := NewImmutableCacheBuilder()
+	for i := 0; i < 1024; i++ {
+		builder.Add(fmt.Sprintf("toolcache:ref:%d", i), []byte("value"))
+	}
+	cache, _ := builder.Build()
+	benchmarkParallelGet(b, cache)
+}
+
+func BenchmarkMemoryCache_ParallelGet(b *testing.B) {
+	cache := NewMemoryCache(DefaultPolicy())
+	for i := 0; i < 1024; i++ {
+		_ = cache.Set(context.Background(), fmt.Sprintf("toolcache:ref:%d", i), []byte("value"), time.Hour)
+	}
+	benchmarkParallelGet(b, cache)
+}
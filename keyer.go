@@ -1,11 +1,22 @@
 package toolcache
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"hash"
+	"io"
+	"math"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Keyer derives cache keys from tool input.
@@ -18,33 +29,364 @@ type Keyer interface {
 	Key(toolID string, input any) (string, error)
 }
 
-type DefaultKeyer struct{}
+// KeyHashBytes is the number of SHA-256 bytes DefaultKeyer keeps in a key.
+const KeyHashBytes = 8
+
+// KeyerFunc adapts an ordinary function to the Keyer interface.
+type KeyerFunc func(toolID string, input any) (string, error)
+
+// Key calls f(toolID, input).
+func (f KeyerFunc) Key(toolID string, input any) (string, error) {
+	return f(toolID, input)
+}
+
+// KeyStats describes the canonical serialization produced for a single key.
+type KeyStats struct {
+	// Bytes is the length of the canonical serialization that was hashed.
+	Bytes int
+
+	// Depth is the maximum nesting depth of maps and arrays in the input.
+	// Scalars have depth 0; {"a":1} has depth 1; {"a":[1]} has depth 2.
+	Depth int
+}
+
+// DefaultMaxDepth is the input nesting depth DefaultKeyer accepts unless
+// overridden with MaxDepth.
+const DefaultMaxDepth = 100
+
+// DefaultKeyer derives keys by hashing the canonical JSON form of the input.
+// The zero value uses strict canonicalization; the exported fields opt in to
+// normalizations that trade exactness for higher hit rates.
+type DefaultKeyer struct {
+	// NormalizeNumbers coerces numerically equal values to a single form, so
+	// int(1000000), int64(1000000) and float64(1e6) hash identically. Integral
+	// floats within the int64 range are written as integers, and -0 as 0.
+	NormalizeNumbers bool
+
+	// InputIndependentTools lists tools whose results do not depend on their
+	// input (e.g. "get_capabilities"). Their input is ignored entirely, so
+	// every call maps to one stable key. The map must not be modified after
+	// the keyer is in use.
+	InputIndependentTools map[string]bool
+
+	// MaxMapFields caps the number of keys in any single map of the input.
+	// Wider maps fail with ErrTooManyFields before their keys are sorted,
+	// which guards against pathological inputs making keying a bottleneck;
+	// the middleware then runs the call uncached. Zero means no limit.
+	MaxMapFields int
+
+	// DropNullFields omits map fields whose value is nil, so an optional
+	// field sent as null hashes the same as one left out. Nulls inside
+	// arrays are kept. Enable it only when tools treat null and absent
+	// fields alike.
+	DropNullFields bool
+
+	// StringNormalizers rewrites string values at specific input paths
+	// before hashing, e.g. strings.ToLower for case-insensitive hostnames.
+	// Paths are map keys joined by "." from the input root, with "*"
+	// standing for any array element: "host", "query.path", "files.*.name";
+	// "" is a string input itself. Strings elsewhere are untouched, and map
+	// keys are never normalized. The map must not be modified after the
+	// keyer is in use.
+	StringNormalizers map[string]func(string) string
+
+	// FloatPrecision rounds float values at specific input paths to the
+	// given number of decimal places before hashing, so values that differ
+	// only in the last few bits (0.1+0.2 and 0.3) hash identically. Paths
+	// use the StringNormalizers syntax; a negative precision rounds to tens,
+	// hundreds and so on. Values on either side of a rounding boundary
+	// still hash differently, and distinct values within the precision are
+	// merged, so mark only fields where that is acceptable. The map must
+	// not be modified after the keyer is in use.
+	FloatPrecision map[string]int
+
+	// IgnoreFields lists map fields, by path, whose values do not affect
+	// the result (request IDs, trace headers) and are left out of the key.
+	// Paths use the StringNormalizers syntax. The map must not be modified
+	// after the keyer is in use.
+	IgnoreFields map[string]bool
+
+	// SetArrays lists arrays, by path, whose element order and duplicates
+	// do not matter, so ["b","a","a"] hashes the same as ["a","b"]. Paths
+	// use the StringNormalizers syntax. The map must not be modified after
+	// the keyer is in use.
+	SetArrays map[string]bool
+
+	// BoolFields lists fields, by path, holding booleans that callers may
+	// send as true, "true" or 1, so all spellings of a value hash alike.
+	// Strings accepted by strconv.ParseBool and the numbers 0 and 1 are
+	// coerced to false and true; other values at those paths are left
+	// as-is. Paths use the StringNormalizers syntax. The map must not be
+	// modified after the keyer is in use.
+	BoolFields map[string]bool
+
+	// ReplaceUnsupported encodes values of unsupported types (structs,
+	// channels, functions and so on) as a placeholder naming their Go type
+	// instead of failing the whole key. Keys become lossy: inputs that
+	// differ only in such values collide and share a cache entry, so
+	// enable it only when those values cannot affect the result. By
+	// default keying fails and the middleware runs the call uncached.
+	ReplaceUnsupported bool
+
+	// FallbackKeyFunc, if set, renders values of unsupported types as a
+	// string that is hashed in their place, as a last resort for exotic
+	// inputs; GoSyntaxFallback is a ready-made choice. The rendering is
+	// not canonical: it must be deterministic for equal values (pointer
+	// addresses, for one, are not), and distinct values that render alike
+	// share a cache entry. An error from it fails the key. It takes
+	// precedence over ReplaceUnsupported. By default keying fails.
+	FallbackKeyFunc func(v any) (string, error)
+
+	// MaxDepth caps the nesting depth of maps and arrays in the input,
+	// counted as in KeyStats.Depth. Deeper inputs, including cyclic ones,
+	// fail with ErrTooDeep and the middleware runs the call uncached.
+	// Values below 1 mean DefaultMaxDepth; the limit cannot be disabled.
+	MaxDepth int
+
+	epoch      atomic.Uint64
+	collisions atomic.Pointer[collisionTracker]
+
+	// base, if set, is the keyer this one was derived from; its epoch and
+	// collision tracking are shared. See root.
+	base *DefaultKeyer
+}
 
 func NewDefaultKeyer() *DefaultKeyer {
 	return &DefaultKeyer{}
 }
 
 func (k *DefaultKeyer) Key(toolID string, input any) (string, error) {
-	canonical, err := canonicalJSON(input)
-	if err != nil {
-		return "", fmt.Errorf("toolcache: failed to canonicalize input: %w", err)
+	key, _, err := k.KeyWithStats(toolID, input)
+	return key, err
+}
+
+// KeyWithStats behaves like Key and additionally reports the size and depth
+// of the canonical input, which helps diagnose slow keying and oversized inputs.
+func (k *DefaultKeyer) KeyWithStats(toolID string, input any) (string, KeyStats, error) {
+	if k.InputIndependentTools[toolID] {
+		input = nil
+	}
+
+	hasher := k.newHasher()
+	counter := &countingWriter{w: hasher}
+
+	enc := getCanonicalEncoder(counter, k)
+	defer putCanonicalEncoder(enc)
+
+	if err := enc.encodeAll(input); err != nil {
+		return "", KeyStats{}, fmt.Errorf("toolcache: failed to canonicalize input: %w", err)
 	}
 
-	hash := sha256.Sum256(canonical)
-	hashHex := hex.EncodeToString(hash[:8])
+	stats := KeyStats{Bytes: counter.n, Depth: enc.maxDepth}
+	return k.finishKey(toolID, hasher), stats, nil
+}
 
-	return fmt.Sprintf("toolcache:%s:%s", toolID, hashHex), nil
+// GoSyntaxFallback renders v with fmt's %#v verb, for use as
+// DefaultKeyer.FallbackKeyFunc. It suits plain structs of value fields;
+// values containing pointers, channels or functions render their
+// addresses and so never match across calls.
+func GoSyntaxFallback(v any) (string, error) {
+	return fmt.Sprintf("%#v", v), nil
+}
+
+// KeyRaw derives the key for input the caller has already serialized,
+// hashing canonical as-is instead of canonicalizing a value. It yields the
+// same key as Key for an input whose canonical JSON form is canonical, so
+// the two can share cache entries. The caller is responsible for keeping
+// the serialization stable: sorted map keys, consistent number formatting
+// and no insignificant whitespace. Normalizations configured on the keyer
+// (NormalizeNumbers, StringNormalizers, etc.) are not applied.
+func (k *DefaultKeyer) KeyRaw(toolID string, canonical []byte) (string, error) {
+	if k.InputIndependentTools[toolID] {
+		canonical = []byte("null")
+	}
+	hasher := k.newHasher()
+	hasher.Write(canonical)
+	return k.finishKey(toolID, hasher), nil
+}
+
+// root returns the keyer holding k's epoch and collision tracking.
+func (k *DefaultKeyer) root() *DefaultKeyer {
+	if k.base != nil {
+		return k.base
+	}
+	return k
+}
+
+// newHasher returns a SHA-256 hasher seeded with the key epoch, if any.
+func (k *DefaultKeyer) newHasher() hash.Hash {
+	hasher := sha256.New()
+	if epoch := k.root().epoch.Load(); epoch > 0 {
+		var prefix [8]byte
+		binary.BigEndian.PutUint64(prefix[:], epoch)
+		hasher.Write(prefix[:])
+	}
+	return hasher
+}
+
+// finishKey builds the key for toolID from the hashed input.
+func (k *DefaultKeyer) finishKey(toolID string, hasher hash.Hash) string {
+	var sum [sha256.Size]byte
+	hasher.Sum(sum[:0])
+	key := ToolKeyPrefix(toolID) + hex.EncodeToString(sum[:KeyHashBytes])
+
+	if t := k.root().collisions.Load(); t != nil {
+		t.observe(key, sum)
+	}
+	return key
+}
+
+// CollisionProbability estimates the probability that at least two of
+// distinctKeys inputs share a hash truncated to hashBytes bytes, using the
+// birthday bound 1 - exp(-n(n-1) / 2^(8*hashBytes+1)).
+//
+// Use it with KeyHashBytes to judge collision risk for a given cardinality.
+func CollisionProbability(distinctKeys int, hashBytes int) float64 {
+	if distinctKeys < 2 {
+		return 0
+	}
+	if hashBytes <= 0 {
+		return 1
+	}
+	n := float64(distinctKeys)
+	space := math.Exp2(float64(8 * hashBytes))
+	return -math.Expm1(-n * (n - 1) / (2 * space))
+}
+
+// RoutingKeyer dispatches to a per-namespace Keyer based on the toolID
+// namespace, i.e. the portion before the first ':' ("fs:read" -> "fs").
+// Tool IDs without a registered namespace use the fallback keyer.
+type RoutingKeyer struct {
+	routes   map[string]Keyer
+	fallback Keyer
+}
+
+// NewRoutingKeyer creates a RoutingKeyer. The routes map is copied; a nil
+// fallback defaults to a DefaultKeyer.
+func NewRoutingKeyer(fallback Keyer, routes map[string]Keyer) *RoutingKeyer {
+	if fallback == nil {
+		fallback = NewDefaultKeyer()
+	}
+	copied := make(map[string]Keyer, len(routes))
+	for ns, keyer := range routes {
+		copied[ns] = keyer
+	}
+	return &RoutingKeyer{routes: copied, fallback: fallback}
+}
+
+func (k *RoutingKeyer) Key(toolID string, input any) (string, error) {
+	return k.route(toolID).Key(toolID, input)
+}
+
+// KeyRaw routes like Key to a keyer implementing RawKeyer. It fails with
+// ErrRawKeyUnsupported if the selected keyer does not implement it.
+func (k *RoutingKeyer) KeyRaw(toolID string, canonical []byte) (string, error) {
+	raw, ok := k.route(toolID).(RawKeyer)
+	if !ok {
+		return "", fmt.Errorf("toolcache: keyer for tool %q: %w", toolID, ErrRawKeyUnsupported)
+	}
+	return raw.KeyRaw(toolID, canonical)
+}
+
+// route returns the keyer for toolID's namespace.
+func (k *RoutingKeyer) route(toolID string) Keyer {
+	if ns, _, ok := strings.Cut(toolID, ":"); ok {
+		if keyer, found := k.routes[ns]; found && keyer != nil {
+			return keyer
+		}
+	}
+	return k.fallback
+}
+
+// SetEpoch sets the key epoch mixed into every generated key. Bumping it
+// changes all keys at once, lazily invalidating the whole logical cache
+// (e.g. on a schema change) without touching the backend; entries written
+// under the old epoch simply expire. Epoch 0, the default, leaves keys
+// unchanged from keyers that never set an epoch.
+func (k *DefaultKeyer) SetEpoch(epoch uint64) {
+	k.root().epoch.Store(epoch)
+}
+
+// Epoch returns the current key epoch.
+func (k *DefaultKeyer) Epoch() uint64 {
+	return k.root().epoch.Load()
 }
 
 func canonicalJSON(v any) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := writeCanonical(&buf, v); err != nil {
+	enc := getCanonicalEncoder(&buf, &DefaultKeyer{})
+	defer putCanonicalEncoder(enc)
+
+	if err := enc.encodeAll(v); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-func writeCanonical(buf *bytes.Buffer, v any) error {
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// canonicalEncoder streams the canonical JSON form of a value into an
+// io.Writer and tracks the deepest nesting level it encountered. Output is
+// buffered in fixed-size chunks, so large inputs are never held in memory
+// in full.
+//
+// Map keys are sorted at every level, including maps nested in arrays and
+// in other maps, so the output never depends on map iteration order.
+type canonicalEncoder struct {
+	w        *bufio.Writer
+	opts     *DefaultKeyer
+	maxDepth int
+	scratch  [64]byte
+
+	// path holds the segments leading to the current value; it is only
+	// maintained when StringNormalizers or FloatPrecision is set.
+	path []string
+}
+
+var encoderPool = sync.Pool{
+	New: func() any {
+		return &canonicalEncoder{w: bufio.NewWriterSize(nil, 512)}
+	},
+}
+
+func getCanonicalEncoder(w io.Writer, opts *DefaultKeyer) *canonicalEncoder {
+	enc := encoderPool.Get().(*canonicalEncoder)
+	enc.w.Reset(w)
+	enc.opts = opts
+	enc.maxDepth = 0
+	return enc
+}
+
+func putCanonicalEncoder(enc *canonicalEncoder) {
+	enc.w.Reset(nil)
+	enc.opts = nil
+	enc.path = enc.path[:0]
+	encoderPool.Put(enc)
+}
+
+// encodeAll encodes v and flushes any buffered output.
+func (e *canonicalEncoder) encodeAll(v any) error {
+	if err := e.encode(v, 0); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *canonicalEncoder) encode(v any, depth int) error {
+	if e.boolField() {
+		v = coerceBool(v)
+	}
+	buf := e.w
 	switch val := v.(type) {
 	case nil:
 		buf.WriteString("null")
@@ -55,27 +397,54 @@ func writeCanonical(buf *bytes.Buffer, v any) error {
 			buf.WriteString("false")
 		}
 	case float64:
-		buf.WriteString(fmt.Sprintf("%v", val))
+		if places, ok := e.floatPrecision(); ok {
+			val = roundFloat(val, places)
+		}
+		e.writeFloat(val)
 	case int:
-		buf.WriteString(fmt.Sprintf("%d", val))
+		_, _ = buf.Write(strconv.AppendInt(e.scratch[:0], int64(val), 10))
 	case int64:
-		buf.WriteString(fmt.Sprintf("%d", val))
+		_, _ = buf.Write(strconv.AppendInt(e.scratch[:0], val, 10))
 	case string:
+		if normalize := e.normalizer(); normalize != nil {
+			val = normalize(val)
+		}
 		writeJSONString(buf, val)
+	case time.Time:
+		// Equal instants hash identically regardless of zone or monotonic
+		// clock reading.
+		writeJSONString(buf, val.UTC().Format(time.RFC3339Nano))
 	case []any:
+		if err := e.enter(depth); err != nil {
+			return err
+		}
+		if e.setArray() {
+			return e.encodeSet(val, depth)
+		}
 		buf.WriteByte('[')
 		for i, elem := range val {
 			if i > 0 {
 				buf.WriteByte(',')
 			}
-			if err := writeCanonical(buf, elem); err != nil {
+			e.push("*")
+			if err := e.encode(elem, depth+1); err != nil {
 				return err
 			}
+			e.pop()
 		}
 		buf.WriteByte(']')
 	case map[string]any:
+		if limit := e.opts.MaxMapFields; limit > 0 && len(val) > limit {
+			return fmt.Errorf("%w: map has %d fields, limit is %d", ErrTooManyFields, len(val), limit)
+		}
+		if err := e.enter(depth); err != nil {
+			return err
+		}
 		keys := make([]string, 0, len(val))
-		for k := range val {
+		for k, elem := range val {
+			if (elem == nil && e.opts.DropNullFields) || e.ignoredField(k) {
+				continue
+			}
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
@@ -87,18 +456,185 @@ func writeCanonical(buf *bytes.Buffer, v any) error {
 			}
 			writeJSONString(buf, k)
 			buf.WriteByte(':')
-			if err := writeCanonical(buf, val[k]); err != nil {
+			e.push(k)
+			if err := e.encode(val[k], depth+1); err != nil {
 				return err
 			}
+			e.pop()
 		}
 		buf.WriteByte('}')
 	default:
-		return fmt.Errorf("unsupported type: %T", v)
+		switch {
+		case e.opts.FallbackKeyFunc != nil:
+			rendered, err := e.opts.FallbackKeyFunc(v)
+			if err != nil {
+				return fmt.Errorf("fallback for type %T: %w", v, err)
+			}
+			writeJSONString(buf, fmt.Sprintf("<fallback %T %s>", v, rendered))
+		case e.opts.ReplaceUnsupported:
+			writeJSONString(buf, fmt.Sprintf("<unsupported %T>", v))
+		default:
+			return fmt.Errorf("unsupported type: %T", v)
+		}
+	}
+	return nil
+}
+
+func (e *canonicalEncoder) writeFloat(f float64) {
+	if e.opts.NormalizeNumbers && f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		_, _ = e.w.Write(strconv.AppendInt(e.scratch[:0], int64(f), 10))
+		return
+	}
+	_, _ = e.w.Write(strconv.AppendFloat(e.scratch[:0], f, 'g', -1, 64))
+}
+
+func (e *canonicalEncoder) push(segment string) {
+	if e.tracksPath() {
+		e.path = append(e.path, segment)
+	}
+}
+
+func (e *canonicalEncoder) pop() {
+	if e.tracksPath() {
+		e.path = e.path[:len(e.path)-1]
+	}
+}
+
+func (e *canonicalEncoder) tracksPath() bool {
+	return e.opts.StringNormalizers != nil || e.opts.FloatPrecision != nil ||
+		e.opts.IgnoreFields != nil || e.opts.SetArrays != nil || e.opts.BoolFields != nil
+}
+
+// ignoredField reports whether field k of the map at the current path is
+// listed in IgnoreFields.
+func (e *canonicalEncoder) ignoredField(k string) bool {
+	if e.opts.IgnoreFields == nil {
+		return false
+	}
+	if len(e.path) > 0 {
+		k = strings.Join(e.path, ".") + "." + k
+	}
+	return e.opts.IgnoreFields[k]
+}
+
+// setArray reports whether the array at the current path is listed in
+// SetArrays.
+func (e *canonicalEncoder) setArray() bool {
+	return e.opts.SetArrays != nil && e.opts.SetArrays[strings.Join(e.path, ".")]
+}
+
+// boolField reports whether the value at the current path is listed in
+// BoolFields.
+func (e *canonicalEncoder) boolField() bool {
+	return e.opts.BoolFields != nil && e.opts.BoolFields[strings.Join(e.path, ".")]
+}
+
+// coerceBool converts the boolean spellings accepted by BoolFields to a
+// bool, returning other values unchanged.
+func coerceBool(v any) any {
+	switch val := v.(type) {
+	case string:
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	case int:
+		if val == 0 || val == 1 {
+			return val == 1
+		}
+	case int64:
+		if val == 0 || val == 1 {
+			return val == 1
+		}
+	case float64:
+		if val == 0 || val == 1 {
+			return val == 1
+		}
+	}
+	return v
+}
+
+// encodeSet writes val as an array of its distinct elements in canonical
+// order. Each element is encoded on its own so the encodings can be
+// sorted.
+func (e *canonicalEncoder) encodeSet(val []any, depth int) error {
+	elems := make([][]byte, 0, len(val))
+	for _, elem := range val {
+		var buf bytes.Buffer
+		sub := getCanonicalEncoder(&buf, e.opts)
+		sub.path = append(append(sub.path, e.path...), "*")
+		err := sub.encode(elem, depth+1)
+		if err == nil {
+			err = sub.w.Flush()
+		}
+		e.maxDepth = max(e.maxDepth, sub.maxDepth)
+		putCanonicalEncoder(sub)
+		if err != nil {
+			return err
+		}
+		elems = append(elems, buf.Bytes())
+	}
+	slices.SortFunc(elems, bytes.Compare)
+	elems = slices.CompactFunc(elems, bytes.Equal)
+
+	e.w.WriteByte('[')
+	for i, elem := range elems {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		_, _ = e.w.Write(elem)
 	}
+	e.w.WriteByte(']')
 	return nil
 }
 
-func writeJSONString(buf *bytes.Buffer, s string) {
+// normalizer returns the string normalizer for the current path, if any.
+func (e *canonicalEncoder) normalizer() func(string) string {
+	if e.opts.StringNormalizers == nil {
+		return nil
+	}
+	return e.opts.StringNormalizers[strings.Join(e.path, ".")]
+}
+
+// floatPrecision returns the rounding precision for the current path, if any.
+func (e *canonicalEncoder) floatPrecision() (int, bool) {
+	if e.opts.FloatPrecision == nil {
+		return 0, false
+	}
+	places, ok := e.opts.FloatPrecision[strings.Join(e.path, ".")]
+	return places, ok
+}
+
+// roundFloat rounds f to places decimal places. Infinities and NaN are
+// returned unchanged.
+func roundFloat(f float64, places int) float64 {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return f
+	}
+	scale := math.Pow10(places)
+	rounded := math.Round(f*scale) / scale
+	if math.IsInf(rounded, 0) || math.IsNaN(rounded) {
+		return f
+	}
+	return rounded
+}
+
+// enter records that a container was opened at the given depth, failing
+// if that exceeds the keyer's depth limit.
+func (e *canonicalEncoder) enter(depth int) error {
+	limit := e.opts.MaxDepth
+	if limit < 1 {
+		limit = DefaultMaxDepth
+	}
+	if depth+1 > limit {
+		return fmt.Errorf("%w: input is nested more than %d levels deep", ErrTooDeep, limit)
+	}
+	if depth+1 > e.maxDepth {
+		e.maxDepth = depth + 1
+	}
+	return nil
+}
+
+func writeJSONString(buf *bufio.Writer, s string) {
 	buf.WriteByte('"')
 	for _, r := range s {
 		switch r {
@@ -122,3 +658,9 @@ func writeJSONString(buf *bytes.Buffer, s string) {
 	}
 	buf.WriteByte('"')
 }
+
+var (
+	_ Keyer = (*DefaultKeyer)(nil)
+	_ Keyer = (*RoutingKeyer)(nil)
+	_ Keyer = KeyerFunc(nil)
+)
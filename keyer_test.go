@@ -1,8 +1,14 @@
 package toolcache
 
 import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestKeyer_DeterministicForMaps(t *testing.T) {
@@ -213,3 +219,906 @@ func TestKeyer_EmptyInput(t *testing.T) {
 		t.Errorf("Keys should differ for nil vs empty map:\n  keyNil=%s\n  keyEmpty=%s", keyNil, keyEmpty)
 	}
 }
+
+func TestKeyer_KeyWithStats(t *testing.T) {
+	keyer := NewDefaultKeyer()
+
+	// canonical documents the encoding each case is sized from.
+	testCases := []struct {
+		name      string
+		input     any
+		canonical string
+		wantBytes int
+		wantDepth int
+	}{
+		{"nil", nil, `null`, 4, 0},
+		{"scalar", "hello", `"hello"`, 7, 0},
+		{"flat map", map[string]any{"a": 1, "b": "two"}, `{"a":1,"b":"two"}`, 17, 1},
+		{"empty map", map[string]any{}, `{}`, 2, 1},
+		{"nested", map[string]any{"a": map[string]any{"b": []any{1, 2}}}, `{"a":{"b":[1,2]}}`, 17, 3},
+		{"array of maps", []any{map[string]any{"x": 1}, 2}, `[{"x":1},2]`, 11, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, stats, err := keyer.KeyWithStats("test-tool", tc.input)
+			if err != nil {
+				t.Fatalf("KeyWithStats() error = %v", err)
+			}
+
+			if stats.Bytes != tc.wantBytes {
+				t.Errorf("Bytes = %d, want %d (canonical %s)", stats.Bytes, tc.wantBytes, tc.canonical)
+			}
+			if stats.Depth != tc.wantDepth {
+				t.Errorf("Depth = %d, want %d", stats.Depth, tc.wantDepth)
+			}
+
+			plain, err := keyer.Key("test-tool", tc.input)
+			if err != nil {
+				t.Fatalf("Key() error = %v", err)
+			}
+			if key != plain {
+				t.Errorf("KeyWithStats key %q differs from Key %q", key, plain)
+			}
+		})
+	}
+}
+
+func TestKeyer_KeyWithStatsUnsupported(t *testing.T) {
+	keyer := NewDefaultKeyer()
+
+	_, stats, err := keyer.KeyWithStats("test-tool", struct{}{})
+	if err == nil {
+		t.Fatal("expected error for unsupported input")
+	}
+	if stats != (KeyStats{}) {
+		t.Errorf("expected zero stats on error, got %+v", stats)
+	}
+}
+
+func TestRoutingKeyer_DispatchesByNamespace(t *testing.T) {
+	// fs: ignores the volatile "cwd" field
+	fsKeyer := KeyerFunc(func(toolID string, input any) (string, error) {
+		m, _ := input.(map[string]any)
+		trimmed := make(map[string]any, len(m))
+		for k, v := range m {
+			if k != "cwd" {
+				trimmed[k] = v
+			}
+		}
+		return NewDefaultKeyer().Key(toolID, trimmed)
+	})
+	// http: lowercases the URL
+	httpKeyer := KeyerFunc(func(toolID string, input any) (string, error) {
+		m, _ := input.(map[string]any)
+		url, _ := m["url"].(string)
+		return NewDefaultKeyer().Key(toolID, map[string]any{"url": strings.ToLower(url)})
+	})
+
+	keyer := NewRoutingKeyer(nil, map[string]Keyer{
+		"fs":   fsKeyer,
+		"http": httpKeyer,
+	})
+
+	// fs: keys ignore cwd
+	k1, err := keyer.Key("fs:read", map[string]any{"path": "/a", "cwd": "/x"})
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+	k2, err := keyer.Key("fs:read", map[string]any{"path": "/a", "cwd": "/y"})
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+	if k1 != k2 {
+		t.Errorf("fs keys should ignore cwd: %s != %s", k1, k2)
+	}
+
+	// http: keys normalize URL case
+	k3, err := keyer.Key("http:get", map[string]any{"url": "HTTP://Example.com"})
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+	k4, err := keyer.Key("http:get", map[string]any{"url": "http://example.com"})
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+	if k3 != k4 {
+		t.Errorf("http keys should normalize URL: %s != %s", k3, k4)
+	}
+
+	// Same input routed to different keyers yields different keys
+	input := map[string]any{"path": "/a", "cwd": "/x", "url": "HTTP://Example.com"}
+	fsKey, _ := keyer.Key("fs:tool", input)
+	httpKey, _ := keyer.Key("http:tool", input)
+	defaultKey, _ := NewDefaultKeyer().Key("fs:tool", input)
+	if fsKey == defaultKey {
+		t.Error("fs namespace should not use the default keying")
+	}
+	if fsKey == httpKey {
+		t.Error("different namespaces should produce different keys")
+	}
+}
+
+func TestRoutingKeyer_Fallback(t *testing.T) {
+	fallback := KeyerFunc(func(toolID string, _ any) (string, error) {
+		return "fallback:" + toolID, nil
+	})
+	keyer := NewRoutingKeyer(fallback, map[string]Keyer{"fs": NewDefaultKeyer()})
+
+	for _, toolID := range []string{"other:tool", "no-namespace", ""} {
+		key, err := keyer.Key(toolID, nil)
+		if err != nil {
+			t.Fatalf("Key(%q) error = %v", toolID, err)
+		}
+		if key != "fallback:"+toolID {
+			t.Errorf("Key(%q) = %q, want fallback key", toolID, key)
+		}
+	}
+}
+
+// largeNestedInput builds a wide, nested input of roughly 1MB canonical size.
+func largeNestedInput() map[string]any {
+	items := make([]any, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		items = append(items, map[string]any{
+			"id":    i,
+			"name":  strings.Repeat("n", 64),
+			"score": float64(i) / 3,
+			"tags":  []any{"alpha", "beta", "gamma"},
+			"meta":  map[string]any{"owner": "team", "active": i%2 == 0},
+		})
+	}
+	return map[string]any{"items": items, "query": strings.Repeat("q", 1024)}
+}
+
+// BenchmarkKeyer_LargeInputBuffered measures the previous approach of
+// serializing the whole input into memory before hashing.
+func BenchmarkKeyer_LargeInputBuffered(b *testing.B) {
+	input := largeNestedInput()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		canonical, err := canonicalJSON(input)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = sha256.Sum256(canonical)
+	}
+}
+
+// BenchmarkKeyer_LargeInputStreaming measures DefaultKeyer, which streams
+// canonical bytes directly into the hash.
+func BenchmarkKeyer_LargeInputStreaming(b *testing.B) {
+	keyer := NewDefaultKeyer()
+	input := largeNestedInput()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := keyer.Key("bench-tool", input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCollisionProbability(t *testing.T) {
+	testCases := []struct {
+		name      string
+		keys      int
+		hashBytes int
+		want      float64
+		tolerance float64
+	}{
+		{"no keys", 0, 8, 0, 0},
+		{"single key", 1, 8, 0, 0},
+		{"no hash bits", 2, 0, 1, 0},
+		// Two keys in a 16-bit space collide with probability 2^-16.
+		{"two keys 16-bit", 2, 2, 1.0 / 65536, 1e-9},
+		// 300 keys in a 16-bit space: well-known ~49.6%.
+		{"300 keys 16-bit", 300, 2, 0.4961, 0.001},
+		// Birthday-attack table for 64-bit hashes: 5.1e9 keys -> 50%.
+		{"5.1e9 keys 64-bit", 5_100_000_000, 8, 0.5, 0.01},
+		// 1.9e8 keys -> 0.1%.
+		{"1.9e8 keys 64-bit", 190_000_000, 8, 0.001, 0.0001},
+		// 6.1e6 keys -> 1e-6.
+		{"6.1e6 keys 64-bit", 6_100_000, 8, 1e-6, 1e-7},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CollisionProbability(tc.keys, tc.hashBytes)
+			if math.Abs(got-tc.want) > tc.tolerance {
+				t.Errorf("CollisionProbability(%d, %d) = %g, want %g ± %g",
+					tc.keys, tc.hashBytes, got, tc.want, tc.tolerance)
+			}
+		})
+	}
+}
+
+func TestCollisionProbability_MonotonicInHashLength(t *testing.T) {
+	for bytes := 1; bytes < 16; bytes++ {
+		shorter := CollisionProbability(100_000, bytes)
+		longer := CollisionProbability(100_000, bytes+1)
+		if longer > shorter {
+			t.Errorf("longer hash (%d bytes) should not increase risk: %g > %g", bytes+1, longer, shorter)
+		}
+	}
+}
+
+func TestKeyer_NormalizeNumbers(t *testing.T) {
+	strict := NewDefaultKeyer()
+	normalized := &DefaultKeyer{NormalizeNumbers: true}
+
+	equalGroups := [][]any{
+		{1, 1.0, int64(1)},
+		{1000000, float64(1e6), int64(1000000)},
+		{0, -0.0, int64(0), math.Copysign(0, -1)},
+		{int64(1) << 60, float64(int64(1) << 60)},
+	}
+	for _, group := range equalGroups {
+		var want string
+		for i, n := range group {
+			key, err := normalized.Key("tool", map[string]any{"n": n})
+			if err != nil {
+				t.Fatalf("Key(%v) error = %v", n, err)
+			}
+			if i == 0 {
+				want = key
+				continue
+			}
+			if key != want {
+				t.Errorf("normalized keys differ for %v (%T) and %v (%T)", group[0], group[0], n, n)
+			}
+		}
+	}
+
+	// Without the option, float formatting diverges for large integral values.
+	k1, _ := strict.Key("tool", map[string]any{"n": 1000000})
+	k2, _ := strict.Key("tool", map[string]any{"n": float64(1e6)})
+	if k1 == k2 {
+		t.Error("strict keyer should distinguish int 1000000 from float 1e6")
+	}
+
+	// Distinct values remain distinct under normalization.
+	k3, _ := normalized.Key("tool", map[string]any{"n": 1.5})
+	k4, _ := normalized.Key("tool", map[string]any{"n": 1})
+	if k3 == k4 {
+		t.Error("1.5 and 1 must not hash identically")
+	}
+}
+
+func TestKeyer_NormalizeNumbersSpecialFloats(t *testing.T) {
+	keyer := &DefaultKeyer{NormalizeNumbers: true}
+	for _, f := range []float64{math.Inf(1), math.Inf(-1), 1e300, -1e300} {
+		if _, err := keyer.Key("tool", f); err != nil {
+			t.Errorf("Key(%v) error = %v", f, err)
+		}
+	}
+}
+
+func TestKeyer_InputIndependentTools(t *testing.T) {
+	keyer := &DefaultKeyer{InputIndependentTools: map[string]bool{"get_capabilities": true}}
+
+	inputs := []any{
+		nil,
+		map[string]any{"verbose": true},
+		map[string]any{"a": 1, "b": []any{2, 3}},
+		struct{}{}, // not canonicalizable, but ignored
+	}
+	var want string
+	for i, input := range inputs {
+		key, err := keyer.Key("get_capabilities", input)
+		if err != nil {
+			t.Fatalf("Key(%v) error = %v", input, err)
+		}
+		if i == 0 {
+			want = key
+			continue
+		}
+		if key != want {
+			t.Errorf("input %v produced %s, want single key %s", input, key, want)
+		}
+	}
+
+	// Other tools still key on input.
+	k1, _ := keyer.Key("search", map[string]any{"q": "a"})
+	k2, _ := keyer.Key("search", map[string]any{"q": "b"})
+	if k1 == k2 {
+		t.Error("regular tools must still depend on input")
+	}
+
+	// Distinct input-independent tools get distinct keys.
+	other, _ := (&DefaultKeyer{InputIndependentTools: map[string]bool{"version": true}}).Key("version", nil)
+	if other == want {
+		t.Error("different tools should not share a key")
+	}
+}
+
+func TestKeyer_DeeplyNestedOrderIndependent(t *testing.T) {
+	// Three levels of maps, with maps nested both in maps and in arrays,
+	// built in scrambled insertion order.
+	build := func(order []string) map[string]any {
+		level3 := map[string]any{}
+		level2 := map[string]any{}
+		root := map[string]any{}
+		for _, k := range order {
+			level3[k] = k + "3"
+		}
+		for _, k := range order {
+			level2[k] = []any{map[string]any{"y": k, "x": level3}, k}
+		}
+		for _, k := range order {
+			root[k] = level2
+		}
+		return root
+	}
+
+	orders := [][]string{
+		{"a", "b", "c", "d"},
+		{"d", "c", "b", "a"},
+		{"c", "a", "d", "b"},
+		{"b", "d", "a", "c"},
+	}
+
+	const wantLevel3 = `{"a":"a3","b":"b3","c":"c3","d":"d3"}`
+	wantLevel2 := "{"
+	for i, k := range orders[0] {
+		if i > 0 {
+			wantLevel2 += ","
+		}
+		wantLevel2 += `"` + k + `":[{"x":` + wantLevel3 + `,"y":"` + k + `"},"` + k + `"]`
+	}
+	wantLevel2 += "}"
+	want := `{"a":` + wantLevel2 + `,"b":` + wantLevel2 + `,"c":` + wantLevel2 + `,"d":` + wantLevel2 + `}`
+
+	keyer := NewDefaultKeyer()
+	var firstKey string
+	// Repeat to exercise Go's randomized map iteration order.
+	for iter := 0; iter < 20; iter++ {
+		for _, order := range orders {
+			input := build(order)
+
+			canonical, err := canonicalJSON(input)
+			if err != nil {
+				t.Fatalf("canonicalJSON error = %v", err)
+			}
+			if string(canonical) != want {
+				t.Fatalf("canonical form not fully sorted:\n got: %s\nwant: %s", canonical, want)
+			}
+
+			key, err := keyer.Key("test-tool", input)
+			if err != nil {
+				t.Fatalf("Key() error = %v", err)
+			}
+			if firstKey == "" {
+				firstKey = key
+			} else if key != firstKey {
+				t.Fatalf("key for order %v = %s, want %s", order, key, firstKey)
+			}
+		}
+	}
+}
+
+func TestKeyer_TimeNormalizedToUTC(t *testing.T) {
+	keyer := NewDefaultKeyer()
+
+	utc := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	plus2 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+	minus5 := time.Date(2026, 3, 1, 3, 0, 0, 0, time.FixedZone("UTC-5", -5*60*60))
+	parsed, err := time.Parse(time.RFC3339, "2026-03-01T10:00:00+02:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want string
+	for i, ts := range []time.Time{utc, plus2, minus5, parsed} {
+		key, err := keyer.Key("schedule", map[string]any{"at": ts})
+		if err != nil {
+			t.Fatalf("Key(%v) error = %v", ts, err)
+		}
+		if i == 0 {
+			want = key
+		} else if key != want {
+			t.Errorf("%v should hash like %v", ts, utc)
+		}
+	}
+
+	canonical, err := canonicalJSON(plus2)
+	if err != nil {
+		t.Fatalf("canonicalJSON error = %v", err)
+	}
+	if string(canonical) != `"2026-03-01T08:00:00Z"` {
+		t.Errorf("canonical time = %s", canonical)
+	}
+
+	// Sub-second precision is preserved and distinguishes instants.
+	later, _ := keyer.Key("schedule", map[string]any{"at": utc.Add(time.Nanosecond)})
+	if later == want {
+		t.Error("different instants must hash differently")
+	}
+
+	// Monotonic clock readings do not affect the key.
+	now := time.Now()
+	k1, _ := keyer.Key("schedule", now)
+	k2, _ := keyer.Key("schedule", now.Round(0))
+	if k1 != k2 {
+		t.Error("monotonic reading should not affect the key")
+	}
+}
+
+func TestKeyer_Epoch(t *testing.T) {
+	keyer := NewDefaultKeyer()
+	input := map[string]any{"q": "x"}
+
+	if keyer.Epoch() != 0 {
+		t.Errorf("default epoch = %d, want 0", keyer.Epoch())
+	}
+	base, _ := keyer.Key("tool", input)
+
+	// Setting epoch 0 explicitly keeps keys unchanged.
+	keyer.SetEpoch(0)
+	if k, _ := keyer.Key("tool", input); k != base {
+		t.Error("epoch 0 should not change keys")
+	}
+
+	keyer.SetEpoch(1)
+	e1a, _ := keyer.Key("tool", input)
+	e1b, _ := keyer.Key("tool", input)
+	if e1a == base {
+		t.Error("bumping the epoch should change keys")
+	}
+	if e1a != e1b {
+		t.Error("keys must be stable within an epoch")
+	}
+	if !strings.HasPrefix(e1a, "toolcache:tool:") {
+		t.Errorf("epoch should not change key format: %s", e1a)
+	}
+
+	keyer.SetEpoch(2)
+	e2, _ := keyer.Key("tool", input)
+	if e2 == e1a || e2 == base {
+		t.Error("each epoch should produce distinct keys")
+	}
+
+	// Returning to a previous epoch reproduces its keys.
+	keyer.SetEpoch(1)
+	if k, _ := keyer.Key("tool", input); k != e1a {
+		t.Error("keys should be deterministic per epoch")
+	}
+}
+
+func TestKeyer_EpochInvalidatesMiddlewareCache(t *testing.T) {
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), keyer, DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	keyer.SetEpoch(7)
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("epoch bump should force a miss, got %d calls", executor.calls)
+	}
+}
+
+func TestKeyer_MaxMapFields(t *testing.T) {
+	wide := make(map[string]any, 11)
+	for i := range 11 {
+		wide[strconv.Itoa(i)] = i
+	}
+	keyer := &DefaultKeyer{MaxMapFields: 10}
+
+	// The limit applies to nested maps too.
+	_, err := keyer.Key("tool", map[string]any{"outer": []any{wide}})
+	if !errors.Is(err, ErrTooManyFields) {
+		t.Fatalf("expected ErrTooManyFields, got %v", err)
+	}
+
+	delete(wide, "0")
+	if _, err := keyer.Key("tool", wide); err != nil {
+		t.Errorf("map at the limit should key, got %v", err)
+	}
+
+	wide["0"] = 0
+	if _, err := NewDefaultKeyer().Key("tool", wide); err != nil {
+		t.Errorf("limit should be disabled by default, got %v", err)
+	}
+}
+
+func TestKeyer_MaxMapFieldsFallsBackToUncached(t *testing.T) {
+	keyer := &DefaultKeyer{MaxMapFields: 1}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), keyer, DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("ok")}
+	input := map[string]any{"a": 1, "b": 2}
+
+	for range 2 {
+		if _, err := mw.Execute(context.Background(), "tool", input, nil, executor.execute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if executor.calls != 2 {
+		t.Errorf("over-wide input should run uncached, got %d calls", executor.calls)
+	}
+}
+
+// nestedInput returns an input of the given depth, alternating maps and
+// arrays: depth 1 is {"v":0}, depth 2 is {"v":[0]}, and so on.
+func nestedInput(depth int) any {
+	var v any = 0
+	for i := depth; i > 0; i-- {
+		if i%2 == 1 {
+			v = map[string]any{"v": v}
+		} else {
+			v = []any{v}
+		}
+	}
+	return v
+}
+
+func TestKeyer_MaxDepth(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		keyer *DefaultKeyer
+		limit int
+	}{
+		{"default", NewDefaultKeyer(), DefaultMaxDepth},
+		{"lowered", &DefaultKeyer{MaxDepth: 5}, 5},
+		{"raised", &DefaultKeyer{MaxDepth: 500}, 500},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, depth := range []int{tc.limit - 1, tc.limit} {
+				_, stats, err := tc.keyer.KeyWithStats("tool", nestedInput(depth))
+				if err != nil {
+					t.Errorf("depth %d: %v", depth, err)
+				} else if stats.Depth != depth {
+					t.Errorf("depth %d: KeyStats.Depth = %d", depth, stats.Depth)
+				}
+			}
+
+			_, err := tc.keyer.Key("tool", nestedInput(tc.limit+1))
+			if !errors.Is(err, ErrTooDeep) {
+				t.Fatalf("depth %d: error = %v, want ErrTooDeep", tc.limit+1, err)
+			}
+			if !strings.Contains(err.Error(), strconv.Itoa(tc.limit)) {
+				t.Errorf("error %q should name the limit %d", err, tc.limit)
+			}
+		})
+	}
+}
+
+func TestKeyer_MaxDepthBoundsCycles(t *testing.T) {
+	cyclic := map[string]any{}
+	cyclic["self"] = cyclic
+	list := []any{nil}
+	list[0] = list
+
+	for _, input := range []any{cyclic, list} {
+		if _, err := NewDefaultKeyer().Key("tool", input); !errors.Is(err, ErrTooDeep) {
+			t.Errorf("cyclic %T: error = %v, want ErrTooDeep", input, err)
+		}
+	}
+}
+
+func TestKeyer_DropNullFields(t *testing.T) {
+	withNull := map[string]any{"a": 1, "b": nil, "nested": map[string]any{"c": nil}}
+	absent := map[string]any{"a": 1, "nested": map[string]any{}}
+
+	plain := NewDefaultKeyer()
+	k1, _ := plain.Key("tool", withNull)
+	k2, _ := plain.Key("tool", absent)
+	if k1 == k2 {
+		t.Error("null and absent fields should differ by default")
+	}
+
+	dropping := &DefaultKeyer{DropNullFields: true}
+	k1, _ = dropping.Key("tool", withNull)
+	k2, _ = dropping.Key("tool", absent)
+	if k1 != k2 {
+		t.Errorf("DropNullFields: null and absent fields should match: %s vs %s", k1, k2)
+	}
+
+	// Nulls in arrays are positional and must be kept.
+	k1, _ = dropping.Key("tool", []any{1, nil})
+	k2, _ = dropping.Key("tool", []any{1})
+	if k1 == k2 {
+		t.Error("array nulls should not be dropped")
+	}
+}
+
+func TestKeyer_StringNormalizers(t *testing.T) {
+	keyer := &DefaultKeyer{StringNormalizers: map[string]func(string) string{
+		"host":         strings.ToLower,
+		"files.*.name": strings.ToLower,
+	}}
+
+	a := map[string]any{"host": "Example.COM", "files": []any{map[string]any{"name": "README.md"}}}
+	b := map[string]any{"host": "example.com", "files": []any{map[string]any{"name": "readme.MD"}}}
+	ka, _ := keyer.Key("tool", a)
+	kb, _ := keyer.Key("tool", b)
+	if ka != kb {
+		t.Errorf("case-differing values at normalized paths should match: %s vs %s", ka, kb)
+	}
+
+	// Other paths stay case-sensitive.
+	c := map[string]any{"host": "example.com", "token": "AbC"}
+	d := map[string]any{"host": "example.com", "token": "abc"}
+	kc, _ := keyer.Key("tool", c)
+	kd, _ := keyer.Key("tool", d)
+	if kc == kd {
+		t.Error("strings outside normalized paths must not be normalized")
+	}
+
+	// A nested field with the same name is a different path.
+	e := map[string]any{"nested": map[string]any{"host": "A"}}
+	f := map[string]any{"nested": map[string]any{"host": "a"}}
+	ke, _ := keyer.Key("tool", e)
+	kf, _ := keyer.Key("tool", f)
+	if ke == kf {
+		t.Error("normalization should be scoped to the exact path")
+	}
+}
+
+func TestKeyer_StringNormalizersRoot(t *testing.T) {
+	keyer := &DefaultKeyer{StringNormalizers: map[string]func(string) string{"": strings.TrimSpace}}
+	k1, _ := keyer.Key("tool", "  query ")
+	k2, _ := keyer.Key("tool", "query")
+	if k1 != k2 {
+		t.Error("the empty path should address a scalar root input")
+	}
+}
+
+func TestKeyer_FloatPrecision(t *testing.T) {
+	keyer := &DefaultKeyer{FloatPrecision: map[string]int{
+		"price":      6,
+		"points.*.x": 2,
+	}}
+	sum := 0.1
+	sum += 0.2 // 0.30000000000000004
+
+	ka, _ := keyer.Key("tool", map[string]any{"price": sum, "points": []any{map[string]any{"x": 1.004}}})
+	kb, _ := keyer.Key("tool", map[string]any{"price": 0.3, "points": []any{map[string]any{"x": 1.0}}})
+	if ka != kb {
+		t.Errorf("near-equal floats at marked paths should match: %s vs %s", ka, kb)
+	}
+
+	// Unmarked fields keep full precision.
+	kc, _ := keyer.Key("tool", map[string]any{"other": sum})
+	kd, _ := keyer.Key("tool", map[string]any{"other": 0.3})
+	if kc == kd {
+		t.Error("floats outside marked paths must not be rounded")
+	}
+
+	// Values that differ beyond the precision stay distinct.
+	ke, _ := keyer.Key("tool", map[string]any{"price": 0.300001})
+	kf, _ := keyer.Key("tool", map[string]any{"price": 0.3})
+	if ke == kf {
+		t.Error("values differing within the configured precision must not merge")
+	}
+}
+
+func TestKeyer_FloatPrecisionWithNormalizeNumbers(t *testing.T) {
+	keyer := &DefaultKeyer{NormalizeNumbers: true, FloatPrecision: map[string]int{"": 3}}
+	k1, _ := keyer.Key("tool", 2.0000001)
+	k2, _ := keyer.Key("tool", 2)
+	if k1 != k2 {
+		t.Error("a float rounded to an integer should hash like the integer")
+	}
+}
+
+func TestRoundFloat(t *testing.T) {
+	tests := []struct {
+		in     float64
+		places int
+		want   float64
+	}{
+		{1.2345, 2, 1.23},
+		{1.235, 0, 1},
+		{1234, -2, 1200},
+		{math.Inf(1), 2, math.Inf(1)},
+		{math.MaxFloat64, 10, math.MaxFloat64},
+	}
+	for _, tt := range tests {
+		if got := roundFloat(tt.in, tt.places); got != tt.want {
+			t.Errorf("roundFloat(%v, %d) = %v, want %v", tt.in, tt.places, got, tt.want)
+		}
+	}
+	if got := roundFloat(math.NaN(), 2); !math.IsNaN(got) {
+		t.Errorf("roundFloat(NaN) = %v", got)
+	}
+}
+
+func TestKeyer_IgnoreFields(t *testing.T) {
+	keyer := &DefaultKeyer{IgnoreFields: map[string]bool{"trace_id": true, "items.*.etag": true}}
+
+	k1, _ := keyer.Key("tool", map[string]any{"q": 1.0, "trace_id": "a", "items": []any{map[string]any{"id": 1.0, "etag": "x"}}})
+	k2, _ := keyer.Key("tool", map[string]any{"q": 1.0, "items": []any{map[string]any{"id": 1.0, "etag": "y"}}})
+	if k1 != k2 {
+		t.Errorf("ignored fields should not affect the key: %s vs %s", k1, k2)
+	}
+
+	k3, _ := keyer.Key("tool", map[string]any{"q": 2.0, "items": []any{map[string]any{"id": 1.0}}})
+	if k1 == k3 {
+		t.Error("other fields must still affect the key")
+	}
+}
+
+func TestKeyer_SetArrays(t *testing.T) {
+	keyer := &DefaultKeyer{SetArrays: map[string]bool{"ids": true, "groups.*": true}}
+
+	k1, stats, err := keyer.KeyWithStats("tool", map[string]any{
+		"ids":    []any{3.0, 1.0, 2.0, 1.0},
+		"groups": []any{[]any{"b", "a"}, []any{"c"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Depth != 3 {
+		t.Errorf("Depth = %d, want 3", stats.Depth)
+	}
+	k2, _ := keyer.Key("tool", map[string]any{
+		"ids":    []any{1.0, 2.0, 3.0},
+		"groups": []any{[]any{"a", "b"}, []any{"c"}},
+	})
+	if k1 != k2 {
+		t.Errorf("set arrays should ignore order and duplicates: %s vs %s", k1, k2)
+	}
+
+	// Only the listed arrays are sets: the order of groups itself matters.
+	k3, _ := keyer.Key("tool", map[string]any{
+		"ids":    []any{1.0, 2.0, 3.0},
+		"groups": []any{[]any{"c"}, []any{"a", "b"}},
+	})
+	if k1 == k3 {
+		t.Error("arrays not listed in SetArrays must keep their order")
+	}
+
+	deep := &DefaultKeyer{SetArrays: map[string]bool{"": true}, MaxDepth: 1}
+	if _, err := deep.Key("tool", []any{[]any{1.0}}); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("depth limit should apply inside set arrays, got %v", err)
+	}
+}
+
+func TestKeyer_BoolFields(t *testing.T) {
+	keyer := &DefaultKeyer{BoolFields: map[string]bool{"recursive": true, "files.*.hidden": true}}
+	input := func(recursive, hidden any) map[string]any {
+		return map[string]any{
+			"recursive": recursive,
+			"files":     []any{map[string]any{"name": "a", "hidden": hidden}},
+		}
+	}
+
+	want, err := keyer.Key("tool", input(true, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ recursive, hidden any }{
+		{"true", "false"},
+		{1, 0},
+		{1.0, 0.0},
+		{int64(1), int64(0)},
+		{"TRUE", "f"},
+		{"1", "0"},
+	} {
+		got, err := keyer.Key("tool", input(tc.recursive, tc.hidden))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Key(%#v, %#v) differs from Key(true, false)", tc.recursive, tc.hidden)
+		}
+	}
+
+	// Values that are not boolean spellings keep their own keys.
+	for _, v := range []any{2, "yes", nil} {
+		if got, _ := keyer.Key("tool", input(v, false)); got == want {
+			t.Errorf("Key(%#v) should not be coerced to true", v)
+		}
+	}
+
+	// Unmarked paths are untouched, so "1" stays a string there.
+	plain := NewDefaultKeyer()
+	k1, _ := plain.Key("tool", map[string]any{"count": "1"})
+	k2, _ := plain.Key("tool", map[string]any{"count": 1})
+	k3, _ := keyer.Key("tool", map[string]any{"count": "1", "recursive": true})
+	k4, _ := keyer.Key("tool", map[string]any{"count": true, "recursive": true})
+	if k1 == k2 || k3 == k4 {
+		t.Error("fields outside BoolFields must not be coerced")
+	}
+}
+
+func TestKeyer_ReplaceUnsupported(t *testing.T) {
+	type opaque struct{ ID int }
+	input := func(v any) map[string]any {
+		return map[string]any{"query": "q", "items": []any{1.0, 2.0}, "handle": v}
+	}
+
+	if _, err := NewDefaultKeyer().Key("tool", input(opaque{ID: 1})); err == nil {
+		t.Fatal("strict keying should fail on an unsupported field")
+	}
+
+	lenient := &DefaultKeyer{ReplaceUnsupported: true}
+	k1, err := lenient.Key("tool", input(opaque{ID: 1}))
+	if err != nil {
+		t.Fatalf("lenient keying failed: %v", err)
+	}
+
+	// Lossy: the unsupported value itself is not hashed, only its type.
+	k2, _ := lenient.Key("tool", input(opaque{ID: 2}))
+	if k1 != k2 {
+		t.Error("values of the same unsupported type should collide")
+	}
+	k3, _ := lenient.Key("tool", input(make(chan int)))
+	if k1 == k3 {
+		t.Error("different unsupported types should key differently")
+	}
+	k4, _ := lenient.Key("tool", map[string]any{"query": "other", "items": []any{1.0, 2.0}, "handle": opaque{}})
+	if k1 == k4 {
+		t.Error("supported fields must still affect the key")
+	}
+}
+
+func TestKeyer_ReplaceUnsupportedCaches(t *testing.T) {
+	input := map[string]any{"q": 1.0, "callback": func() {}}
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		keyer     *DefaultKeyer
+		wantCalls int
+	}{
+		{NewDefaultKeyer(), 2},
+		{&DefaultKeyer{ReplaceUnsupported: true}, 1},
+	} {
+		mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), tc.keyer, DefaultPolicy(), nil)
+		executor := &mockExecutor{result: []byte("ok")}
+		for range 2 {
+			if _, err := mw.Execute(ctx, "tool", input, nil, executor.execute); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if executor.calls != tc.wantCalls {
+			t.Errorf("ReplaceUnsupported=%v: executor calls = %d, want %d",
+				tc.keyer.ReplaceUnsupported, executor.calls, tc.wantCalls)
+		}
+	}
+}
+
+func TestKeyer_FallbackKeyFunc(t *testing.T) {
+	type point struct{ X, Y int }
+	input := func(p any) map[string]any { return map[string]any{"op": "distance", "from": p} }
+
+	if _, err := NewDefaultKeyer().Key("tool", input(point{1, 2})); err == nil {
+		t.Fatal("strict keying should fail on an unsupported type")
+	}
+
+	keyer := &DefaultKeyer{FallbackKeyFunc: GoSyntaxFallback}
+	k1, err := keyer.Key("tool", input(point{1, 2}))
+	if err != nil {
+		t.Fatalf("fallback keying failed: %v", err)
+	}
+	k2, _ := keyer.Key("tool", input(point{1, 2}))
+	k3, _ := keyer.Key("tool", input(point{2, 1}))
+	if k1 != k2 {
+		t.Error("equal values should key identically through the fallback")
+	}
+	if k1 == k3 {
+		t.Error("values rendering differently should key differently")
+	}
+
+	// A fallback rendering cannot collide with a plain string input.
+	rendered, _ := GoSyntaxFallback(point{1, 2})
+	if k4, _ := keyer.Key("tool", input(rendered)); k4 == k1 {
+		t.Error("fallback output should be distinguishable from strings")
+	}
+
+	// The fallback wins over ReplaceUnsupported.
+	both := &DefaultKeyer{FallbackKeyFunc: GoSyntaxFallback, ReplaceUnsupported: true}
+	if k5, _ := both.Key("tool", input(point{2, 1})); k5 != k3 {
+		t.Error("FallbackKeyFunc should take precedence over ReplaceUnsupported")
+	}
+
+	errRender := errors.New("cannot render")
+	failing := &DefaultKeyer{FallbackKeyFunc: func(any) (string, error) { return "", errRender }}
+	if _, err := failing.Key("tool", input(point{})); !errors.Is(err, errRender) {
+		t.Errorf("error = %v, want the fallback's error", err)
+	}
+}
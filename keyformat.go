@@ -0,0 +1,53 @@
+package toolcache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// KeyFormatVersion identifies DefaultKeyer's canonicalization. It is bumped
+// whenever a release changes the keys DefaultKeyer derives, so keys
+// persisted by an older release can be recognized as unusable.
+const KeyFormatVersion = 1
+
+// ErrKeyFormatMismatch is returned by ReadSnapshot when a snapshot was
+// written under a different key format than the cache's current one.
+var ErrKeyFormatMismatch = errors.New("toolcache: snapshot key format mismatch")
+
+// KeyFormatter is implemented by keyers that can identify the format of
+// the keys they derive. Two keyers with the same KeyFormat derive the same
+// key for the same call.
+type KeyFormatter interface {
+	KeyFormat() string
+}
+
+// KeyFormat identifies the keys k derives: KeyFormatVersion and the key
+// epoch. Other settings, such as IgnoreFields or NormalizeNumbers, are not
+// included; bump the epoch with SetEpoch when changing them.
+func (k *DefaultKeyer) KeyFormat() string {
+	return fmt.Sprintf("default/v%d/epoch%d", KeyFormatVersion, k.Epoch())
+}
+
+// WithKeyFormat stamps snapshots written by WriteSnapshot with the current
+// format of keyer, the keyer used to derive the cache's keys, and makes
+// ReadSnapshot refuse snapshots stamped with a different format with
+// ErrKeyFormatMismatch. Without it, a snapshot taken before the keyer's
+// canonicalization or epoch changed imports cleanly but never hits.
+// Snapshots without a stamp are imported unchecked.
+func WithKeyFormat(keyer KeyFormatter) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		c.keyFormat = keyer
+	}
+}
+
+// checkKeyFormat returns an error wrapping ErrKeyFormatMismatch if a
+// snapshot stamped with format cannot be imported.
+func (c *MemoryCache) checkKeyFormat(format string) error {
+	if c.keyFormat == nil || format == "" {
+		return nil
+	}
+	if current := c.keyFormat.KeyFormat(); format != current {
+		return fmt.Errorf("%w: snapshot has %q, cache expects %q", ErrKeyFormatMismatch, format, current)
+	}
+	return nil
+}
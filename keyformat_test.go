@@ -0,0 +1,101 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// snapshotWith writes a snapshot holding one entry from a cache built
+// with opts.
+func snapshotWith(t *testing.T, opts ...MemoryCacheOption) *bytes.Buffer {
+	t.Helper()
+	ctx := context.Background()
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), opts...)
+	_ = c.Set(ctx, "k", []byte("v"), time.Hour)
+	var buf bytes.Buffer
+	if err := c.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestKeyFormat_DefaultKeyer(t *testing.T) {
+	k := NewDefaultKeyer()
+	if got := k.KeyFormat(); got != "default/v1/epoch0" {
+		t.Errorf("KeyFormat() = %q", got)
+	}
+	k.SetEpoch(3)
+	if got := k.KeyFormat(); got != "default/v1/epoch3" {
+		t.Errorf("KeyFormat() after SetEpoch = %q", got)
+	}
+}
+
+func TestKeyFormat_SnapshotMatching(t *testing.T) {
+	keyer := NewDefaultKeyer()
+	buf := snapshotWith(t, WithKeyFormat(keyer))
+	if !strings.Contains(buf.String(), `"key_format":"default/v1/epoch0"`) {
+		t.Fatalf("snapshot not stamped: %s", buf.String())
+	}
+
+	dst := NewMemoryCacheWithOptions(DefaultPolicy(), WithKeyFormat(NewDefaultKeyer()))
+	if n, err := dst.ReadSnapshot(context.Background(), buf); err != nil || n != 1 {
+		t.Errorf("ReadSnapshot() = %d, %v; want 1, nil", n, err)
+	}
+}
+
+func TestKeyFormat_SnapshotMismatch(t *testing.T) {
+	old := NewDefaultKeyer()
+	buf := snapshotWith(t, WithKeyFormat(old))
+
+	current := NewDefaultKeyer()
+	current.SetEpoch(2)
+	dst := NewMemoryCacheWithOptions(DefaultPolicy(), WithKeyFormat(current))
+	n, err := dst.ReadSnapshot(context.Background(), buf)
+	if !errors.Is(err, ErrKeyFormatMismatch) || n != 0 {
+		t.Fatalf("ReadSnapshot() = %d, %v; want 0, ErrKeyFormatMismatch", n, err)
+	}
+	if !strings.Contains(err.Error(), "default/v1/epoch0") || !strings.Contains(err.Error(), "default/v1/epoch2") {
+		t.Errorf("error should name both formats: %v", err)
+	}
+	if dst.Len() != 0 {
+		t.Error("a refused snapshot must not import entries")
+	}
+}
+
+func TestKeyFormat_FollowsKeyerChanges(t *testing.T) {
+	keyer := NewDefaultKeyer()
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithKeyFormat(keyer))
+	ctx := context.Background()
+	_ = c.Set(ctx, "k", []byte("v"), time.Hour)
+
+	var buf bytes.Buffer
+	_ = c.WriteSnapshot(ctx, &buf)
+	keyer.SetEpoch(1)
+	if _, err := c.ReadSnapshot(ctx, &buf); !errors.Is(err, ErrKeyFormatMismatch) {
+		t.Errorf("ReadSnapshot() after SetEpoch = %v, want ErrKeyFormatMismatch", err)
+	}
+}
+
+func TestKeyFormat_Unstamped(t *testing.T) {
+	ctx := context.Background()
+
+	// Legacy snapshots carry no format and are imported unchecked.
+	buf := snapshotWith(t)
+	if strings.Contains(buf.String(), "key_format") {
+		t.Errorf("unstamped snapshot = %s", buf.String())
+	}
+	dst := NewMemoryCacheWithOptions(DefaultPolicy(), WithKeyFormat(NewDefaultKeyer()))
+	if n, err := dst.ReadSnapshot(ctx, buf); err != nil || n != 1 {
+		t.Errorf("ReadSnapshot(unstamped) = %d, %v", n, err)
+	}
+
+	// Caches without a format accept stamped snapshots.
+	buf = snapshotWith(t, WithKeyFormat(NewDefaultKeyer()))
+	if n, err := NewMemoryCache(DefaultPolicy()).ReadSnapshot(ctx, buf); err != nil || n != 1 {
+		t.Errorf("ReadSnapshot(stamped) without WithKeyFormat = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,38 @@
+package toolcache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// KeyValidator checks that a key is acceptable to a cache backend. It
+// returns nil for valid keys and an error otherwise, ideally wrapping
+// ErrKeyTooLong for keys that are too long. Errors wrapping neither
+// ErrInvalidKey nor ErrKeyTooLong are wrapped in ErrInvalidKey by the
+// middleware, so callers can always match them with errors.Is.
+type KeyValidator func(key string) error
+
+// WithKeyValidator replaces ValidateKey as the check applied to derived
+// keys and to keys passed to ExecuteWithKey, for backends with stricter
+// constraints (a smaller size limit, forbidden characters). Validators
+// should normally call ValidateKey first and add their own rules. Derived
+// keys that fail validation run uncached, as with ValidateKey; a failure
+// wrapping ErrKeyTooLong is handled by WithOversizedKeyMode. A nil
+// validator restores ValidateKey.
+func WithKeyValidator(v KeyValidator) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.keyValidator = v
+	}
+}
+
+// validateKey checks key with the configured validator.
+func (m *CacheMiddleware) validateKey(key string) error {
+	if m.keyValidator == nil {
+		return ValidateKey(key)
+	}
+	err := m.keyValidator(key)
+	if err == nil || errors.Is(err, ErrInvalidKey) || errors.Is(err, ErrKeyTooLong) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+}
@@ -0,0 +1,143 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// noColonValidator mimics a backend that forbids ':' in keys.
+func noColonValidator(key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if strings.Contains(key, ":") {
+		return fmt.Errorf("%w: contains ':'", ErrInvalidKey)
+	}
+	return nil
+}
+
+func TestKeyValidator_ExecuteWithKey(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), nil, DefaultPolicy(), nil,
+		WithKeyValidator(noColonValidator))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	if _, err := mw.ExecuteWithKey(ctx, "tool", "a:b", nil, nil, executor.execute); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("ExecuteWithKey(a:b) error = %v, want ErrInvalidKey", err)
+	}
+	if executor.calls != 0 {
+		t.Errorf("executor should not run for a rejected key, got %d calls", executor.calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := mw.ExecuteWithKey(ctx, "tool", "a-b", nil, nil, executor.execute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if executor.calls != 1 {
+		t.Errorf("a valid key should be cached, got %d calls", executor.calls)
+	}
+}
+
+func TestKeyValidator_DerivedKeysRunUncached(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithKeyValidator(noColonValidator))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := mw.Execute(ctx, "tool", 1, nil, executor.execute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if executor.calls != 2 || len(cache.entries) != 0 {
+		t.Errorf("keys with ':' should run uncached: calls=%d entries=%d", executor.calls, len(cache.entries))
+	}
+
+	dashed := KeyerFunc(func(toolID string, _ any) (string, error) { return "toolcache-" + toolID, nil })
+	mw = NewCacheMiddleware(cache, dashed, DefaultPolicy(), nil, WithKeyValidator(noColonValidator))
+	executor = &mockExecutor{result: []byte("ok")}
+	for i := 0; i < 2; i++ {
+		_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	}
+	if executor.calls != 1 {
+		t.Errorf("keys accepted by the validator should be cached, got %d calls", executor.calls)
+	}
+}
+
+func TestKeyValidator_StricterLengthIsOversized(t *testing.T) {
+	const limit = 100
+	strict := func(key string) error {
+		if len(key) > limit {
+			return ErrKeyTooLong
+		}
+		return ValidateKey(key)
+	}
+	long := KeyerFunc(func(string, any) (string, error) { return strings.Repeat("k", 200), nil })
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), long, DefaultPolicy(), nil,
+		WithKeyValidator(strict), WithOversizedKeyMode(OversizedKeyReject))
+
+	_, err := mw.Execute(context.Background(), "tool", 1, nil, (&mockExecutor{}).execute)
+	if !errors.Is(err, ErrKeyTooLong) {
+		t.Errorf("error = %v, want ErrKeyTooLong from the custom validator", err)
+	}
+}
+
+func TestKeyValidator_ShortenToStricterLength(t *testing.T) {
+	const limit = 100
+	strict := func(key string) error {
+		if len(key) > limit {
+			return ErrKeyTooLong
+		}
+		return ValidateKey(key)
+	}
+	// The second key fits the limit, but its negative-cache key does not.
+	for _, n := range []int{200, limit - 1} {
+		cache := NewMemoryCache(DefaultPolicy())
+		keyer := KeyerFunc(func(toolID string, _ any) (string, error) { return strings.Repeat(toolID[:1], n), nil })
+		mw := NewCacheMiddleware(cache, keyer, negativePolicy(), nil,
+			WithKeyValidator(strict), WithOversizedKeyMode(OversizedKeyShorten), WithCacheableError(isPermanent))
+		ok := &mockExecutor{result: []byte("ok")}
+		failing := &mockExecutor{err: errValidation}
+		ctx := context.Background()
+
+		for i := 0; i < 2; i++ {
+			_, _ = mw.Execute(ctx, "ok", 1, nil, ok.execute)
+			_, _ = mw.Execute(ctx, "failing", 1, nil, failing.execute)
+		}
+		if ok.calls != 1 || failing.calls != 1 {
+			t.Errorf("key of %d bytes: executors ran %d and %d times, want 1 each", n, ok.calls, failing.calls)
+		}
+		for key := range cache.entries {
+			if err := strict(key); err != nil {
+				t.Errorf("key of %d bytes: stored key %q fails the validator: %v", n, key, err)
+			}
+		}
+	}
+}
+
+func TestKeyValidator_WarmSkipsInvalidKeys(t *testing.T) {
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), keyer, DefaultPolicy(), nil,
+		WithKeyValidator(noColonValidator))
+	executor := &mockExecutor{result: []byte("ok")}
+
+	results := mw.WarmAll(context.Background(), []WarmRequest{{ToolID: "tool", Input: 1}}, executor.execute)
+	if results[0].Status != WarmSkipped || results[0].Key != "" {
+		t.Errorf("WarmAll() = %+v, want skipped", results[0])
+	}
+	if executor.calls != 0 {
+		t.Errorf("executor ran %d times for a key that would not be cached", executor.calls)
+	}
+
+	key, _ := keyer.Key("tool", 1)
+	requests := []WarmRequest{{ToolID: "other", Input: 2}, {ToolID: "tool", Input: 1}}
+	got := mw.PrioritizeWarm(requests, map[string]uint64{key: 10})
+	if got[0].ToolID != "other" {
+		t.Error("PrioritizeWarm should treat uncacheable keys as never accessed")
+	}
+}
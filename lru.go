@@ -0,0 +1,197 @@
+package toolcache
+
+import (
+	"slices"
+	"sync"
+)
+
+// WithMaxEntries bounds the cache to n entries. When a Set pushes the
+// count past n, the least recently used entries of the lowest priority
+// (see EntryMeta.Priority) are evicted and reported as
+// EvictReasonCapacity. A new entry never displaces one of higher priority;
+// it is evicted itself instead. Recency is kept in an intrusive
+// doubly-linked list per priority, so Gets and evictions are O(1) in the
+// number of entries; a hit then also takes a short internal lock to move
+// its entry to the front.
+//
+// Pinned entries count toward n but are never evicted, so a cache whose
+// other entries are all evicted may exceed n. Values of 0 or less, the
+// default, leave the cache unbounded.
+func WithMaxEntries(n int) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		c.maxEntries = max(n, 0)
+	}
+}
+
+// lruIndex orders a MemoryCache's entries for eviction: one recency list
+// per entry priority (see EntryMeta.Priority), with priorities kept in
+// ascending order. Writers modify it under the cache's write lock; readers
+// holding only the read lock move entries to the front under mu, which
+// writers never need.
+type lruIndex struct {
+	mu         sync.Mutex
+	lists      map[int]*lruList
+	priorities []int
+}
+
+// lruList orders entries of one priority by recency, most recent first.
+// Entries are linked through their prev and next fields.
+type lruList struct {
+	head, tail *cacheEntry
+}
+
+func (x *lruIndex) pushFront(e *cacheEntry) {
+	priority := e.meta.Priority
+	l, ok := x.lists[priority]
+	if !ok {
+		if x.lists == nil {
+			x.lists = make(map[int]*lruList)
+		}
+		l = &lruList{}
+		x.lists[priority] = l
+		i, _ := slices.BinarySearch(x.priorities, priority)
+		x.priorities = slices.Insert(x.priorities, i, priority)
+	}
+	l.pushFront(e)
+}
+
+func (x *lruIndex) remove(e *cacheEntry) {
+	priority := e.meta.Priority
+	l := x.lists[priority]
+	l.remove(e)
+	if l.head == nil {
+		delete(x.lists, priority)
+		i, _ := slices.BinarySearch(x.priorities, priority)
+		x.priorities = slices.Delete(x.priorities, i, i+1)
+	}
+}
+
+func (x *lruIndex) moveToFront(e *cacheEntry) {
+	x.lists[e.meta.Priority].moveToFront(e)
+}
+
+func (l *lruList) pushFront(e *cacheEntry) {
+	e.prev, e.next = nil, l.head
+	if l.head != nil {
+		l.head.prev = e
+	} else {
+		l.tail = e
+	}
+	l.head = e
+}
+
+func (l *lruList) remove(e *cacheEntry) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else if l.head == e {
+		l.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else if l.tail == e {
+		l.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+}
+
+func (l *lruList) moveToFront(e *cacheEntry) {
+	if l.head == e {
+		return
+	}
+	l.remove(e)
+	l.pushFront(e)
+}
+
+// putLocked stores entry under key, replacing and returning any previous
+// entry, and evicts entries beyond WithMaxEntries. Callers must hold c.mu
+// for writing.
+func (c *MemoryCache) putLocked(key string, entry *cacheEntry) *cacheEntry {
+	old := c.entries[key]
+	c.entries[key] = entry
+	if c.maxEntries <= 0 {
+		return old
+	}
+	if old != nil {
+		c.lru.remove(old)
+	}
+	entry.key = key
+	c.lru.pushFront(entry)
+	c.evictOverflowLocked(entry)
+	return old
+}
+
+// storeLocked stores entry under key like putLocked, dropping the
+// dependency edges of the entry it replaces and returning that entry to
+// the pool. Callers must hold c.mu for writing.
+func (c *MemoryCache) storeLocked(key string, entry *cacheEntry) {
+	if old := c.putLocked(key, entry); old != nil {
+		c.unlinkLocked(key, old.deps)
+		c.releaseLocked(old)
+	}
+}
+
+// removeLocked deletes key's entry from the cache, along with the
+// dependency edges recorded for it. Callers must hold c.mu for writing and
+// pass the entry currently stored under key.
+func (c *MemoryCache) removeLocked(key string, entry *cacheEntry) {
+	delete(c.entries, key)
+	c.unlinkLocked(key, entry.deps)
+	if c.maxEntries > 0 {
+		c.lru.remove(entry)
+	}
+}
+
+// promoteRLocked marks entry as most recently used. Callers must hold
+// c.mu, for reading or writing, and entry must be stored in the cache.
+func (c *MemoryCache) promoteRLocked(entry *cacheEntry) {
+	if c.maxEntries <= 0 {
+		return
+	}
+	c.lru.mu.Lock()
+	c.lru.moveToFront(entry)
+	c.lru.mu.Unlock()
+}
+
+// evictOverflowLocked evicts entries until the cache fits WithMaxEntries,
+// skipping pinned ones. Like EvictFraction, it does not cascade to
+// dependents. Callers must hold c.mu for writing.
+//
+// stored is the entry just stored, if any. It is kept over entries of the
+// same or lower priority, but evicted itself rather than displacing an
+// entry of higher priority.
+func (c *MemoryCache) evictOverflowLocked(stored *cacheEntry) {
+	if stored != nil && c.pinnedLocked(stored.key) {
+		stored = nil
+	}
+	for len(c.entries) > c.maxEntries {
+		victim := c.lruVictimLocked(stored)
+		if victim == nil {
+			return
+		}
+		key := victim.key
+		c.removeLocked(key, victim)
+		c.emitEvict(key, EvictReasonCapacity)
+		c.releaseLocked(victim)
+		if victim == stored {
+			return
+		}
+	}
+}
+
+// lruVictimLocked returns the least recently used unpinned entry of the
+// lowest priority, with stored treated as described for
+// evictOverflowLocked, or nil if every entry is pinned.
+func (c *MemoryCache) lruVictimLocked(stored *cacheEntry) *cacheEntry {
+	for _, priority := range c.lru.priorities {
+		for e := c.lru.lists[priority].tail; e != nil; e = e.prev {
+			if e == stored || c.pinnedLocked(e.key) {
+				continue
+			}
+			if stored != nil && priority > stored.meta.Priority {
+				return stored
+			}
+			return e
+		}
+	}
+	return nil
+}
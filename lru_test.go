@@ -0,0 +1,227 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lruKeys returns the keys in eviction order reversed: highest priority
+// first, most recent first within a priority. It checks that the lists and
+// the entries map agree.
+func lruKeys(t *testing.T, c *MemoryCache) []string {
+	t.Helper()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !slices.IsSorted(c.lru.priorities) || len(c.lru.priorities) != len(c.lru.lists) {
+		t.Fatalf("priorities %v do not match the lists", c.lru.priorities)
+	}
+	var keys []string
+	for _, priority := range slices.Backward(c.lru.priorities) {
+		l := c.lru.lists[priority]
+		var prev *cacheEntry
+		for e := l.head; e != nil; e = e.next {
+			if e.prev != prev {
+				t.Fatalf("broken back link at %q", e.key)
+			}
+			if c.entries[e.key] != e {
+				t.Fatalf("listed entry %q is not the stored entry", e.key)
+			}
+			if e.meta.Priority != priority {
+				t.Fatalf("entry %q of priority %d is listed under %d", e.key, e.meta.Priority, priority)
+			}
+			keys = append(keys, e.key)
+			prev = e
+		}
+		if prev == nil || l.tail != prev {
+			t.Fatalf("list for priority %d is empty or its tail does not end it", priority)
+		}
+	}
+	if len(keys) != len(c.entries) {
+		t.Fatalf("lists have %d entries, map has %d", len(keys), len(c.entries))
+	}
+	return keys
+}
+
+func TestMaxEntries_EvictsLeastRecentlyUsed(t *testing.T) {
+	for name, opts := range map[string][]MemoryCacheOption{
+		"default": nil,
+		"pooled":  {WithEntryPooling()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewMemoryCacheWithOptions(DefaultPolicy(), append(opts, WithMaxEntries(3), WithEvictionEvents(8))...)
+			ctx := context.Background()
+
+			for _, key := range []string{"a", "b", "c"} {
+				_ = c.Set(ctx, key, []byte(key), time.Minute)
+			}
+			_, _ = c.Get(ctx, "a")
+			_ = c.Set(ctx, "d", []byte("d"), time.Minute)
+
+			if _, ok := c.Get(ctx, "b"); ok {
+				t.Error("least recently used entry b should be evicted")
+			}
+			if got := lruKeys(t, c); !slices.Equal(got, []string{"d", "a", "c"}) {
+				t.Errorf("recency order = %v", got)
+			}
+			select {
+			case ev := <-c.EvictionEvents():
+				if ev.Key != "b" || ev.Reason != EvictReasonCapacity {
+					t.Errorf("event = %s/%s, want b/capacity", ev.Key, ev.Reason)
+				}
+			default:
+				t.Error("no eviction event")
+			}
+
+			// Overwriting an existing key refreshes it without evicting.
+			_ = c.Set(ctx, "c", []byte("c2"), time.Minute)
+			if got := lruKeys(t, c); !slices.Equal(got, []string{"c", "d", "a"}) {
+				t.Errorf("after overwrite, recency order = %v", got)
+			}
+		})
+	}
+}
+
+func TestMaxEntries_Unbounded(t *testing.T) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithMaxEntries(0))
+	ctx := context.Background()
+	for i := range 1000 {
+		_ = c.Set(ctx, fmt.Sprint(i), []byte("v"), time.Minute)
+	}
+	if n := c.Len(); n != 1000 {
+		t.Errorf("Len() = %d, want 1000 with no limit", n)
+	}
+}
+
+func TestMaxEntries_SkipsPinned(t *testing.T) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithMaxEntries(2))
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "config", []byte("v"), time.Minute)
+	_ = c.Pin(ctx, "config")
+	_ = c.Set(ctx, "a", []byte("v"), time.Minute)
+	_ = c.Set(ctx, "b", []byte("v"), time.Minute)
+
+	if _, ok := c.Get(ctx, "config"); !ok {
+		t.Error("pinned entry should never be evicted")
+	}
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Error("oldest unpinned entry should be evicted instead")
+	}
+
+	// With every older entry pinned, the newest is kept over the limit.
+	_ = c.Pin(ctx, "b")
+	_ = c.Set(ctx, "c", []byte("v"), time.Minute)
+	if n := c.Len(); n != 3 {
+		t.Errorf("Len() = %d, want 3 while only pinned entries remain", n)
+	}
+}
+
+func TestMaxEntries_AllWritePaths(t *testing.T) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithMaxEntries(2))
+	ctx := context.Background()
+
+	_ = c.SetImmutable(ctx, "immutable", []byte("v"), time.Minute)
+	_ = c.SetThunk(ctx, "thunk", func(context.Context) ([]byte, error) { return []byte("v"), nil }, time.Minute, time.Minute)
+	_ = c.SetWithDeps(ctx, "deps", []byte("v"), time.Minute, []string{"thunk"})
+	if _, ok := c.Get(ctx, "immutable"); ok {
+		t.Error("SetWithDeps should evict the oldest entry")
+	}
+	if _, ok := c.Get(ctx, "thunk"); !ok {
+		t.Fatal("thunk should materialize")
+	}
+	_ = c.Delete(ctx, "thunk") // cascades to deps
+	if got := lruKeys(t, c); len(got) != 0 {
+		t.Errorf("entries left after cascade: %v", got)
+	}
+
+	_ = c.Set(ctx, "a", []byte("v"), time.Minute)
+	_ = c.Set(ctx, "b", []byte("v"), time.Minute)
+	if _, err := c.DeleteTree(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.EvictFraction(1)
+	_ = c.Flush(ctx)
+	lruKeys(t, c)
+}
+
+func TestMaxEntries_Snapshot(t *testing.T) {
+	src := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	for i := range 10 {
+		_ = src.Set(ctx, fmt.Sprint(i), []byte("v"), time.Minute)
+	}
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewMemoryCacheWithOptions(DefaultPolicy(), WithMaxEntries(4))
+	if _, err := dst.ReadSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(lruKeys(t, dst)); n != 4 {
+		t.Errorf("imported %d entries, want the limit of 4", n)
+	}
+}
+
+func TestMaxEntries_Concurrent(t *testing.T) {
+	const limit = 50
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithMaxEntries(limit))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 2000 {
+				key := fmt.Sprint((g*31 + i) % 200)
+				switch i % 4 {
+				case 0, 1:
+					_, _ = c.Get(ctx, key)
+				case 2:
+					_ = c.Set(ctx, key, []byte(key), time.Minute)
+				case 3:
+					if i%20 == 3 {
+						_ = c.Delete(ctx, key)
+					} else {
+						_ = c.Set(ctx, key, []byte(key), time.Minute)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	keys := lruKeys(t, c)
+	if len(keys) > limit {
+		t.Errorf("cache holds %d entries, limit is %d", len(keys), limit)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if got, ok := c.Get(ctx, key); !ok || string(got) != key {
+			t.Errorf("Get(%q) = %q, %v", key, got, ok)
+		}
+	}
+}
+
+func BenchmarkMemoryCache_SetMaxEntries(b *testing.B) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithMaxEntries(1000))
+	ctx := context.Background()
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprint(i)
+	}
+	value := []byte("v")
+	b.ResetTimer()
+	for i := range b.N {
+		_ = c.Set(ctx, keys[i%len(keys)], value, time.Minute)
+	}
+}
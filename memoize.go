@@ -0,0 +1,94 @@
+package toolcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Memoizer offers the middleware's get-or-compute behavior to callers that
+// are not tools. Values are stored in a Cache as JSON under the string the
+// key function derives from K, and concurrent Gets for the same key share
+// one computation.
+type Memoizer[K comparable, V any] struct {
+	cache Cache
+	key   func(K) string
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	inflight map[K]*memoCall[V]
+}
+
+type memoCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewMemoizer returns a Memoizer that caches values in cache for ttl, using
+// key to derive cache keys. Keys must satisfy ValidateKey; include a
+// namespace prefix if the cache is shared with other users.
+func NewMemoizer[K comparable, V any](cache Cache, key func(K) string, ttl time.Duration) *Memoizer[K, V] {
+	return &Memoizer[K, V]{
+		cache:    cache,
+		key:      key,
+		ttl:      ttl,
+		inflight: make(map[K]*memoCall[V]),
+	}
+}
+
+// Get returns the cached value for k, or calls compute, caches its result
+// and returns it. Errors from compute are returned and not cached. If the
+// cached bytes cannot be decoded, the value is recomputed.
+func (m *Memoizer[K, V]) Get(ctx context.Context, k K, compute func() (V, error)) (V, error) {
+	key := m.key(k)
+	if err := ValidateKey(key); err != nil {
+		var zero V
+		return zero, err
+	}
+
+	if data, ok := m.cache.Get(ctx, key); ok {
+		var v V
+		if err := json.Unmarshal(data, &v); err == nil {
+			return v, nil
+		}
+	}
+
+	m.mu.Lock()
+	if call, ok := m.inflight[k]; ok {
+		m.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+	// Waiters see this error if compute panics.
+	call := &memoCall[V]{done: make(chan struct{}), err: errMemoPanicked}
+	m.inflight[k] = call
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.inflight, k)
+		m.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = compute()
+	if call.err == nil {
+		if data, err := json.Marshal(call.value); err == nil {
+			_ = m.cache.Set(ctx, key, data, m.ttl)
+		} else {
+			call.err = fmt.Errorf("toolcache: memoized value is not JSON-encodable: %w", err)
+		}
+	}
+	return call.value, call.err
+}
+
+var errMemoPanicked = errors.New("toolcache: memoized computation panicked")
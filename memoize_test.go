@@ -0,0 +1,129 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func userKey(id int) string { return fmt.Sprintf("users:%d", id) }
+
+func TestMemoizer_MissThenHit(t *testing.T) {
+	m := NewMemoizer[int, user](NewMemoryCache(DefaultPolicy()), userKey, time.Minute)
+	ctx := context.Background()
+	var calls int
+	compute := func() (user, error) {
+		calls++
+		return user{ID: 7, Name: "ada"}, nil
+	}
+
+	for range 2 {
+		got, err := m.Get(ctx, 7, compute)
+		if err != nil || got != (user{ID: 7, Name: "ada"}) {
+			t.Fatalf("Get() = %+v, %v", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("compute ran %d times, want 1", calls)
+	}
+}
+
+func TestMemoizer_ErrorsNotCached(t *testing.T) {
+	m := NewMemoizer[int, user](NewMemoryCache(DefaultPolicy()), userKey, time.Minute)
+	ctx := context.Background()
+	errLookup := errors.New("lookup failed")
+	var calls int
+	compute := func() (user, error) {
+		calls++
+		return user{}, errLookup
+	}
+
+	for range 2 {
+		if _, err := m.Get(ctx, 1, compute); !errors.Is(err, errLookup) {
+			t.Fatalf("Get() error = %v, want errLookup", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("compute ran %d times, want 2", calls)
+	}
+}
+
+func TestMemoizer_InvalidKey(t *testing.T) {
+	m := NewMemoizer[string, int](NewMemoryCache(DefaultPolicy()), func(s string) string { return s }, time.Minute)
+	_, err := m.Get(context.Background(), "", func() (int, error) { return 1, nil })
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Get() error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestMemoizer_ConcurrentDedup(t *testing.T) {
+	m := NewMemoizer[int, user](NewMemoryCache(DefaultPolicy()), userKey, time.Minute)
+	ctx := context.Background()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	compute := func() (user, error) {
+		calls.Add(1)
+		<-release
+		return user{ID: 1}, nil
+	}
+
+	const callers = 16
+	var wg sync.WaitGroup
+	var started sync.WaitGroup
+	started.Add(callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			if got, err := m.Get(ctx, 1, compute); err != nil || got.ID != 1 {
+				t.Errorf("Get() = %+v, %v", got, err)
+			}
+		}()
+	}
+	started.Wait()
+	time.Sleep(10 * time.Millisecond) // let callers reach the in-flight wait
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("compute ran %d times, want 1", got)
+	}
+}
+
+func TestMemoizer_WaiterHonorsContext(t *testing.T) {
+	m := NewMemoizer[int, int](NewMemoryCache(DefaultPolicy()), func(int) string { return "k" }, time.Minute)
+	release := make(chan struct{})
+	defer close(release)
+
+	go func() {
+		_, _ = m.Get(context.Background(), 1, func() (int, error) {
+			<-release
+			return 1, nil
+		})
+	}()
+	for {
+		m.mu.Lock()
+		n := len(m.inflight)
+		m.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := m.Get(ctx, 1, func() (int, error) { return 2, nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want DeadlineExceeded", err)
+	}
+}
@@ -1,68 +1,382 @@
 package toolcache
 
 import (
+	"bytes"
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 )
 
+// cacheEntry's value, metadata and expiry are never modified once stored
+// in MemoryCache.entries: setters build a new entry and swap the map
+// pointer under the write lock, which is what keeps reads atomic. Only the
+// access counters and recency links change in place, and pooled entries
+// are recycled only after leaving the map.
 type cacheEntry struct {
 	value     []byte
+	meta      EntryMeta
 	expiresAt time.Time
+
+	// immutable entries were stored with SetImmutable and are shared with
+	// readers instead of copied.
+	immutable bool
+
+	// thunk is set for entries stored with SetThunk until they are read.
+	thunk *thunk
+
+	// deps lists the keys this entry was stored as depending on with
+	// SetWithDeps.
+	deps []string
+
+	// buf is set for entries from the pool (see WithEntryPooling) and
+	// holds value's buffer for reuse.
+	buf *[]byte
+
+	// access is maintained under WithAccessTracking.
+	access accessStats
+
+	// key, prev and next link the entry into its priority's recency list
+	// under WithMaxEntries.
+	key        string
+	prev, next *cacheEntry
+}
+
+// LockMode selects the locking strategy MemoryCache uses to guard its entries.
+type LockMode int
+
+const (
+	// LockReadHeavy guards entries with a sync.RWMutex so concurrent Gets
+	// proceed in parallel. Each operation pays slightly more bookkeeping, and
+	// a waiting writer blocks new readers until it has run. This is the
+	// default and suits workloads dominated by cache hits.
+	LockReadHeavy LockMode = iota
+
+	// LockWriteHeavy guards entries with a plain sync.Mutex. Gets no longer
+	// run in parallel, but every operation is cheaper and lock handoff is
+	// simpler, which wins when Sets and Deletes make up a large share of
+	// traffic (e.g. cold caches or high-cardinality inputs).
+	LockWriteHeavy
+)
+
+// rwLocker is the subset of sync.RWMutex used by MemoryCache.
+type rwLocker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+// exclusiveLock implements rwLocker with a plain mutex; read locks are
+// exclusive.
+type exclusiveLock struct {
+	sync.Mutex
+}
+
+func (l *exclusiveLock) RLock()   { l.Lock() }
+func (l *exclusiveLock) RUnlock() { l.Unlock() }
+
+// MemoryCacheOption configures a MemoryCache.
+type MemoryCacheOption func(*MemoryCache)
+
+// WithLockMode selects the locking strategy. See LockMode for the tradeoffs.
+func WithLockMode(mode LockMode) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		if mode == LockWriteHeavy {
+			c.mu = &exclusiveLock{}
+		} else {
+			c.mu = &sync.RWMutex{}
+		}
+	}
+}
+
+// WithInitialCapacity pre-sizes the entries map for about n entries, which
+// avoids repeated rehashing while a large working set is loaded (e.g. during
+// warm-up or snapshot import). It is a hint, not a limit.
+func WithInitialCapacity(n int) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		if n > 0 {
+			c.entries = make(map[string]*cacheEntry, n)
+		}
+	}
+}
+
+// WithZeroCopy makes Get return the cached slice itself instead of a copy,
+// saving an allocation per hit. Callers must then treat returned bytes as
+// read-only: mutating them corrupts the cached entry for every other
+// reader. By default Get returns a defensive copy.
+func WithZeroCopy() MemoryCacheOption {
+	return func(c *MemoryCache) {
+		c.zeroCopy = true
+	}
 }
 
+// MemoryCache is an in-memory Cache, safe for concurrent use.
+//
+// Reads are atomic with respect to writes of the same key: a Get racing a
+// Set that replaces the key returns either the complete old entry or the
+// complete new one, never a mix of the two, and the value, metadata and
+// expiry it sees always belong together. Which one it returns depends on
+// which operation takes the lock first.
 type MemoryCache struct {
-	mu      sync.RWMutex
+	mu      rwLocker
 	entries map[string]*cacheEntry
 	policy  Policy
+
+	zeroCopy    bool
+	pooling     bool
+	trackAccess bool
+	expirySkew  time.Duration
+	clock       Clock
+
+	// dependents maps a key to the keys stored with SetWithDeps as
+	// depending on it.
+	dependents map[string]map[string]struct{}
+
+	// pinned holds the keys exempt from expiry and eviction; see Pin.
+	pinned map[string]struct{}
+
+	// keyFormat stamps and checks snapshots; see WithKeyFormat.
+	keyFormat KeyFormatter
+
+	// maxEntries bounds the cache, evicting from lru; see WithMaxEntries.
+	maxEntries int
+	lru        lruIndex
+
+	events        chan EvictEvent
+	eventsDropped atomic.Uint64
+	closed        bool
+}
+
+// WithExpirySkew tolerates clock disagreement between nodes: an entry is
+// treated as fresh until skew after its expiry time. This avoids spurious
+// misses for entries whose absolute expiry was computed on another node,
+// such as those loaded with ReadSnapshot. Negative values are treated as 0.
+func WithExpirySkew(skew time.Duration) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		c.expirySkew = max(skew, 0)
+	}
+}
+
+// expired reports whether an entry expiring at expiresAt is stale at now.
+func (c *MemoryCache) expired(expiresAt, now time.Time) bool {
+	return now.After(expiresAt.Add(c.expirySkew))
 }
 
 func NewMemoryCache(policy Policy) *MemoryCache {
-	return &MemoryCache{
+	return NewMemoryCacheWithOptions(policy)
+}
+
+// NewMemoryCacheWithOptions creates a MemoryCache configured by opts.
+func NewMemoryCacheWithOptions(policy Policy, opts ...MemoryCacheOption) *MemoryCache {
+	c := &MemoryCache{
+		mu:      &sync.RWMutex{},
 		entries: make(map[string]*cacheEntry),
 		policy:  policy,
+		clock:   systemClock{},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, _, ok := c.GetWithMeta(ctx, key)
+	return value, ok
 }
 
-func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool) {
+// GetWithMeta returns the value and metadata stored under key.
+func (c *MemoryCache) GetWithMeta(ctx context.Context, key string) ([]byte, EntryMeta, bool) {
+	if c.pools() {
+		return c.getPooled(ctx, key)
+	}
+	entry, ok := c.lookup(ctx, key)
+	if !ok {
+		return nil, EntryMeta{}, false
+	}
+
+	if c.zeroCopy || entry.immutable {
+		return entry.value, entry.meta, true
+	}
+	return bytes.Clone(entry.value), entry.meta, true
+}
+
+// GetString returns the value stored under key as a string that shares the
+// cached bytes, so no copy is made. This is safe because the cache never
+// modifies stored bytes in place and callers cannot mutate a string. Under
+// WithZeroCopy or SetImmutable, a caller that breaks the read-only contract
+// on a shared slice would also change strings returned here. With
+// WithEntryPooling, the string is backed by a copy instead, since the
+// cached buffer may be reused.
+func (c *MemoryCache) GetString(ctx context.Context, key string) (string, bool) {
+	if c.pools() {
+		value, _, ok := c.getPooled(ctx, key)
+		if !ok || len(value) == 0 {
+			return "", ok
+		}
+		return unsafe.String(&value[0], len(value)), true
+	}
+	entry, ok := c.lookup(ctx, key)
+	if !ok {
+		return "", false
+	}
+	if len(entry.value) == 0 {
+		return "", true
+	}
+	return unsafe.String(&entry.value[0], len(entry.value)), true
+}
+
+// lookup returns the live entry for key, removing it if it has expired and
+// materializing it if it is a thunk.
+func (c *MemoryCache) lookup(ctx context.Context, key string) (*cacheEntry, bool) {
+	now := c.clock.Now()
+
 	c.mu.RLock()
 	entry, exists := c.entries[key]
+	stale := exists && c.staleLocked(key, entry, now)
+	if exists && !stale {
+		c.promoteRLocked(entry)
+	}
 	c.mu.RUnlock()
 
 	if !exists {
 		return nil, false
 	}
 
-	if time.Now().After(entry.expiresAt) {
+	if stale {
 		c.mu.Lock()
-		delete(c.entries, key)
+		// Only remove the entry we observed; a concurrent Set or Pin may
+		// have replaced or kept it in the meantime.
+		if c.entries[key] == entry && !c.pinnedLocked(key) {
+			c.removeLocked(key, entry)
+			c.emitEvict(key, EvictReasonExpired)
+		}
 		c.mu.Unlock()
 		return nil, false
 	}
 
-	return entry.value, true
+	if entry.thunk != nil {
+		materialized, ok := c.materialize(ctx, key, entry)
+		if ok {
+			c.touch(materialized, now)
+		}
+		return materialized, ok
+	}
+	c.touch(entry, now)
+	return entry, true
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.SetWithMeta(ctx, key, value, EntryMeta{}, ttl)
 }
 
-func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+// SetWithMeta stores value and its metadata under key. Like every
+// MemoryCache setter, it rejects keys failing ValidateKey. A non-zero
+// meta.Version is checked against the live entry; see ErrVersionConflict.
+func (c *MemoryCache) SetWithMeta(_ context.Context, key string, value []byte, meta EntryMeta, ttl time.Duration) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
 	if ttl <= 0 {
 		return nil
 	}
 
+	now := c.clock.Now()
+	if c.pools() {
+		entry := newPooledEntry(value, meta, now.Add(ttl))
+		c.mu.Lock()
+		old := c.entries[key]
+		if err := c.checkVersionLocked(key, old, value, meta.Version, now); err != nil {
+			c.releaseLocked(entry)
+			c.mu.Unlock()
+			return err
+		}
+		c.storeLocked(key, entry)
+		c.mu.Unlock()
+		return nil
+	}
+
+	entry := &cacheEntry{
+		value:     bytes.Clone(value),
+		meta:      meta,
+		expiresAt: now.Add(ttl),
+	}
 	c.mu.Lock()
-	c.entries[key] = &cacheEntry{
-		value:     value,
-		expiresAt: time.Now().Add(ttl),
+	if err := c.checkVersionLocked(key, c.entries[key], value, meta.Version, now); err != nil {
+		c.mu.Unlock()
+		return err
 	}
+	c.storeLocked(key, entry)
 	c.mu.Unlock()
 
 	return nil
 }
 
+// SetImmutable stores value under key without copying it, and Get returns
+// the same slice to every reader. The caller transfers ownership: neither
+// it nor any reader may modify value afterwards. Use it for freshly built
+// buffers to skip both defensive copies without enabling WithZeroCopy for
+// the whole cache.
+func (c *MemoryCache) SetImmutable(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.storeLocked(key, &cacheEntry{
+		value:     value,
+		expiresAt: c.clock.Now().Add(ttl),
+		immutable: true,
+	})
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes key and, through SetWithDeps, every entry depending on it.
 func (c *MemoryCache) Delete(_ context.Context, key string) error {
 	c.mu.Lock()
-	delete(c.entries, key)
+	if entry, exists := c.entries[key]; exists {
+		c.removeLocked(key, entry)
+		c.emitEvict(key, EvictReasonDeleted)
+		c.releaseLocked(entry)
+	}
+	c.cascadeLocked(key)
 	c.mu.Unlock()
 	return nil
 }
 
-var _ Cache = (*MemoryCache)(nil)
+// Len returns the number of entries stored, including expired entries not
+// yet removed. It is cheap enough to call on every metrics scrape.
+func (c *MemoryCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Close releases resources held by the cache and closes the eviction event
+// channel, if enabled. Close is idempotent; the cache remains usable for
+// Get/Set/Delete afterwards but no longer emits events.
+func (c *MemoryCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.events != nil {
+		close(c.events)
+	}
+	return nil
+}
+
+var (
+	_ Cache     = (*MemoryCache)(nil)
+	_ MetaCache = (*MemoryCache)(nil)
+)
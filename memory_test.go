@@ -3,6 +3,7 @@ package toolcache
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -128,6 +129,78 @@ func TestMemoryCache_ConcurrentAccess(t *testing.T) {
 	wg.Wait()
 }
 
+// TestMemoryCache_GetDuringSet hammers one key with Sets of distinct,
+// self-describing values and checks that every racing read sees one
+// complete value with its own metadata.
+func TestMemoryCache_GetDuringSet(t *testing.T) {
+	for name, opts := range map[string][]MemoryCacheOption{
+		"default":     nil,
+		"write-heavy": {WithLockMode(LockWriteHeavy)},
+		"pooled":      {WithEntryPooling()},
+		"zero-copy":   {WithZeroCopy()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache := NewMemoryCacheWithOptions(DefaultPolicy(), opts...)
+			ctx := context.Background()
+			const key = "contended"
+			const writers, readers, ops = 4, 8, 2000
+
+			// Value n is n+1 copies of the byte 'a'+n, tagged with ETag n.
+			valueFor := func(n int) ([]byte, EntryMeta) {
+				return bytes.Repeat([]byte{byte('a' + n)}, 512*(n+1)), EntryMeta{ETag: fmt.Sprint(n)}
+			}
+			check := func(value []byte, meta EntryMeta) error {
+				if len(value) == 0 {
+					return fmt.Errorf("empty value")
+				}
+				n := int(value[0] - 'a')
+				want, wantMeta := valueFor(n)
+				if !bytes.Equal(value, want) || meta != wantMeta {
+					return fmt.Errorf("torn read: %d bytes starting %q with meta %+v", len(value), value[0], meta)
+				}
+				return nil
+			}
+
+			value, meta := valueFor(0)
+			_ = cache.SetWithMeta(ctx, key, value, meta, time.Minute)
+
+			var wg sync.WaitGroup
+			for w := range writers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range ops {
+						value, meta := valueFor((w + i) % 8)
+						_ = cache.SetWithMeta(ctx, key, value, meta, time.Minute)
+					}
+				}()
+			}
+			for range readers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range ops {
+						value, meta, ok := cache.GetWithMeta(ctx, key)
+						if !ok {
+							t.Error("Get missed a key that is always present")
+							return
+						}
+						if err := check(value, meta); err != nil {
+							t.Error(err)
+							return
+						}
+						if s, _ := cache.GetString(ctx, key); check([]byte(s), EntryMeta{ETag: fmt.Sprint(int(s[0] - 'a'))}) != nil {
+							t.Errorf("torn GetString: %d bytes", len(s))
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
 func TestMemoryCache_SetOverwrite(t *testing.T) {
 	cache := NewMemoryCache(DefaultPolicy())
 	ctx := context.Background()
@@ -167,6 +240,29 @@ func TestMemoryCache_SetOverwrite(t *testing.T) {
 	}
 }
 
+func TestMemoryCache_Len(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(clock))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = cache.Set(ctx, "b", []byte("2"), time.Hour)
+	_ = cache.Set(ctx, "a", []byte("3"), time.Minute)
+	if n := cache.Len(); n != 2 {
+		t.Errorf("Len() = %d, want 2", n)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if n := cache.Len(); n != 2 {
+		t.Errorf("Len() = %d, want expired entries counted until removed", n)
+	}
+	_, _ = cache.Get(ctx, "a")
+	_ = cache.Delete(ctx, "b")
+	if n := cache.Len(); n != 0 {
+		t.Errorf("Len() = %d after removal, want 0", n)
+	}
+}
+
 func TestMemoryCache_ZeroTTL(t *testing.T) {
 	cache := NewMemoryCache(DefaultPolicy())
 	ctx := context.Background()
@@ -272,3 +368,338 @@ func TestMemoryCache_LargeValues(t *testing.T) {
 
 // Verify MemoryCache implements Cache interface at compile time
 var _ Cache = (*MemoryCache)(nil)
+
+func TestMemoryCache_LockModes(t *testing.T) {
+	for _, mode := range []LockMode{LockReadHeavy, LockWriteHeavy} {
+		cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithLockMode(mode))
+		ctx := context.Background()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				key := fmt.Sprintf("key-%d", id%5)
+				for j := 0; j < 200; j++ {
+					_ = cache.Set(ctx, key, []byte("value"), time.Minute)
+					_, _ = cache.Get(ctx, key)
+					if j%10 == 0 {
+						_ = cache.Delete(ctx, key)
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		_ = cache.Set(ctx, "final", []byte("ok"), time.Minute)
+		if got, ok := cache.Get(ctx, "final"); !ok || string(got) != "ok" {
+			t.Errorf("mode %d: Get after Set = %q, %v", mode, got, ok)
+		}
+	}
+}
+
+// benchmarkWriteHeavy runs a workload of 90% Sets and 10% Gets.
+func benchmarkWriteHeavy(b *testing.B, mode LockMode) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithLockMode(mode))
+	ctx := context.Background()
+	value := []byte("value")
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%10 == 0 {
+				_, _ = cache.Get(ctx, key)
+			} else {
+				_ = cache.Set(ctx, key, value, time.Minute)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkMemoryCache_WriteHeavy_ReadHeavyLock(b *testing.B) {
+	benchmarkWriteHeavy(b, LockReadHeavy)
+}
+
+func BenchmarkMemoryCache_WriteHeavy_WriteHeavyLock(b *testing.B) {
+	benchmarkWriteHeavy(b, LockWriteHeavy)
+}
+
+func TestMemoryCache_NilDistinctFromMiss(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "nil", nil, 5*time.Minute)
+	_ = cache.Set(ctx, "empty", []byte{}, 5*time.Minute)
+
+	for _, key := range []string{"nil", "empty"} {
+		got, ok := cache.Get(ctx, key)
+		if !ok {
+			t.Errorf("Get(%q) should be a hit", key)
+		}
+		if len(got) != 0 {
+			t.Errorf("Get(%q) = %q, want empty", key, got)
+		}
+	}
+
+	if _, ok := cache.Get(ctx, "missing"); ok {
+		t.Error("Get on a missing key should be a miss")
+	}
+}
+
+func TestMemoryCache_InitialCapacity(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithInitialCapacity(128))
+	ctx := context.Background()
+	for i := 0; i < 256; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("key-%d", i), []byte("v"), time.Minute)
+	}
+	if got := len(cache.entries); got != 256 {
+		t.Errorf("entries = %d, want 256 (capacity is a hint, not a limit)", got)
+	}
+}
+
+func benchmarkBulkImport(b *testing.B, opts ...MemoryCacheOption) {
+	const n = 10000
+	ctx := context.Background()
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("toolcache:tool:%d", i)
+	}
+	value := []byte("value")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache := NewMemoryCacheWithOptions(DefaultPolicy(), opts...)
+		for _, key := range keys {
+			_ = cache.Set(ctx, key, value, time.Minute)
+		}
+	}
+}
+
+func BenchmarkMemoryCache_BulkImport(b *testing.B) {
+	benchmarkBulkImport(b)
+}
+
+func BenchmarkMemoryCache_BulkImportPresized(b *testing.B) {
+	benchmarkBulkImport(b, WithInitialCapacity(10000))
+}
+
+func TestMemoryCache_SetWithMeta(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	meta := EntryMeta{ContentType: "application/json"}
+	if err := cache.SetWithMeta(ctx, "k", []byte(`{}`), meta, time.Minute); err != nil {
+		t.Fatalf("SetWithMeta failed: %v", err)
+	}
+	value, got, ok := cache.GetWithMeta(ctx, "k")
+	if !ok || string(value) != `{}` || got != meta {
+		t.Errorf("GetWithMeta = %q, %+v, %v", value, got, ok)
+	}
+
+	// Plain Set clears metadata.
+	_ = cache.Set(ctx, "k", []byte("x"), time.Minute)
+	if _, got, _ := cache.GetWithMeta(ctx, "k"); got != (EntryMeta{}) {
+		t.Errorf("Set should reset metadata, got %+v", got)
+	}
+}
+
+func TestMemoryCache_CopyOnReadAndWrite(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	original := []byte("value")
+	_ = cache.Set(ctx, "k", original, time.Minute)
+	original[0] = 'X' // mutate after Set
+
+	got, _ := cache.Get(ctx, "k")
+	if string(got) != "value" {
+		t.Errorf("Set should copy the value, got %q", got)
+	}
+
+	got[0] = 'Y' // mutate the returned copy
+	again, _ := cache.Get(ctx, "k")
+	if string(again) != "value" {
+		t.Errorf("Get should return a defensive copy, got %q", again)
+	}
+}
+
+// TestMemoryCache_ZeroCopyCaveat documents that zero-copy reads share the
+// cached buffer: a caller that mutates a returned slice corrupts the entry.
+func TestMemoryCache_ZeroCopyCaveat(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithZeroCopy())
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "k", []byte("value"), time.Minute)
+
+	first, _ := cache.Get(ctx, "k")
+	second, _ := cache.Get(ctx, "k")
+	if &first[0] != &second[0] {
+		t.Fatal("zero-copy reads should share the cached buffer")
+	}
+
+	first[0] = 'X'
+	if got, _ := cache.Get(ctx, "k"); string(got) != "Xalue" {
+		t.Errorf("mutation should be visible to later readers in zero-copy mode, got %q", got)
+	}
+}
+
+func TestMemoryCache_SetImmutable(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	value := []byte("frozen")
+	_ = cache.SetImmutable(ctx, "immutable", value, time.Minute)
+	_ = cache.Set(ctx, "mutable", []byte("copied"), time.Minute)
+
+	first, _ := cache.Get(ctx, "immutable")
+	second, _ := cache.Get(ctx, "immutable")
+	if &first[0] != &value[0] || &second[0] != &value[0] {
+		t.Error("immutable entries should be stored and returned without copying")
+	}
+
+	a, _ := cache.Get(ctx, "mutable")
+	b, _ := cache.Get(ctx, "mutable")
+	if &a[0] == &b[0] {
+		t.Error("mutable entries should still be copied on read")
+	}
+
+	// Overwriting with Set makes the entry mutable again.
+	_ = cache.Set(ctx, "immutable", value, time.Minute)
+	got, _ := cache.Get(ctx, "immutable")
+	if &got[0] == &value[0] {
+		t.Error("Set should replace the immutable entry with a copy")
+	}
+}
+
+func TestMemoryCache_SetImmutableNonPositiveTTL(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	_ = cache.SetImmutable(context.Background(), "k", []byte("v"), 0)
+	if _, ok := cache.Get(context.Background(), "k"); ok {
+		t.Error("non-positive TTL should not store")
+	}
+}
+
+func TestMemoryCache_GetString(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	original := []byte("value")
+	_ = cache.Set(ctx, "k", original, time.Minute)
+	original[0] = 'X'
+
+	got, ok := cache.GetString(ctx, "k")
+	if !ok || got != "value" {
+		t.Errorf("GetString() = %q, %v; want value, true", got, ok)
+	}
+
+	_ = cache.Set(ctx, "empty", nil, time.Minute)
+	if got, ok := cache.GetString(ctx, "empty"); !ok || got != "" {
+		t.Errorf("empty value: GetString() = %q, %v; want \"\", true", got, ok)
+	}
+
+	if _, ok := cache.GetString(ctx, "missing"); ok {
+		t.Error("missing key should miss")
+	}
+
+	_ = cache.Set(ctx, "short", []byte("v"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.GetString(ctx, "short"); ok {
+		t.Error("expired key should miss")
+	}
+}
+
+func TestMemoryCache_GetStringNoAlloc(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	_ = cache.Set(ctx, "k", make([]byte, 4096), time.Minute)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = cache.GetString(ctx, "k")
+	})
+	if allocs != 0 {
+		t.Errorf("GetString allocated %.0f times per call, want 0", allocs)
+	}
+}
+
+func TestMemoryCache_ExpirySkew(t *testing.T) {
+	ctx := context.Background()
+
+	tolerant := NewMemoryCacheWithOptions(DefaultPolicy(), WithExpirySkew(time.Minute))
+	strict := NewMemoryCacheWithOptions(DefaultPolicy(), WithExpirySkew(2*time.Millisecond))
+	for _, c := range []*MemoryCache{tolerant, strict} {
+		_ = c.Set(ctx, "k", []byte("v"), time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := tolerant.Get(ctx, "k"); !ok {
+		t.Error("entry just past expiry should be fresh within the skew")
+	}
+	if infos, _ := tolerant.Snapshot(ctx); len(infos) != 1 {
+		t.Errorf("Snapshot should include entries within the skew, got %d", len(infos))
+	}
+	if _, ok := strict.Get(ctx, "k"); ok {
+		t.Error("entry beyond the skew should be expired")
+	}
+}
+
+func TestMemoryCache_ExpirySkewOnSnapshotImport(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryCache(DefaultPolicy())
+	_ = src.Set(ctx, "k", []byte("v"), 5*time.Millisecond)
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	dst := NewMemoryCacheWithOptions(DefaultPolicy(), WithExpirySkew(time.Minute))
+	if n, err := dst.ReadSnapshot(ctx, &buf); err != nil || n != 1 {
+		t.Errorf("ReadSnapshot() = %d, %v; want entry within skew imported", n, err)
+	}
+}
+
+func benchmarkGet(b *testing.B, opts ...MemoryCacheOption) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), opts...)
+	ctx := context.Background()
+	_ = cache.Set(ctx, "k", make([]byte, 4096), time.Minute)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := cache.Get(ctx, "k"); !ok {
+			b.Fatal("miss")
+		}
+	}
+}
+
+func BenchmarkMemoryCache_GetCopy(b *testing.B) {
+	benchmarkGet(b)
+}
+
+func BenchmarkMemoryCache_GetZeroCopy(b *testing.B) {
+	benchmarkGet(b, WithZeroCopy())
+}
+
+func BenchmarkMemoryCache_GetString(b *testing.B) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	_ = cache.Set(ctx, "k", make([]byte, 4096), time.Minute)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := cache.GetString(ctx, "k"); !ok {
+			b.Fatal("miss")
+		}
+	}
+}
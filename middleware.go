@@ -1,14 +1,47 @@
 package toolcache
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 )
 
+type allowUnsafeKey struct{}
+
+// WithAllowUnsafe returns a context that overrides Policy.AllowUnsafe for
+// calls made with it. Use it to force-cache an unsafe tool for a specific,
+// controlled call without changing the global policy. Each override that
+// causes an otherwise-skipped tool to be cached is reported to the
+// Observer as EventUnsafeOverride.
+func WithAllowUnsafe(ctx context.Context, allow bool) context.Context {
+	return context.WithValue(ctx, allowUnsafeKey{}, allow)
+}
+
+func allowUnsafeFromContext(ctx context.Context) (allow, ok bool) {
+	allow, ok = ctx.Value(allowUnsafeKey{}).(bool)
+	return allow, ok
+}
+
 type SkipRule func(toolID string, tags []string) bool
 
 type ToolExecutor func(ctx context.Context, toolID string, input any) ([]byte, error)
 
+// MetaExecutor is a ToolExecutor that also reports metadata about its
+// result, such as the content type to serve it with.
+type MetaExecutor func(ctx context.Context, toolID string, input any) ([]byte, EntryMeta, error)
+
+// ExecInfo describes how ExecuteWithInfo produced its result.
+type ExecInfo struct {
+	// Meta is the result metadata, from the executor or the cache.
+	Meta EntryMeta
+
+	// Cached reports whether the result was served from the cache.
+	Cached bool
+}
+
 var DefaultUnsafeTags = []string{"write", "danger", "unsafe", "mutation", "delete"}
 
 func DefaultSkipRule(_ string, tags []string) bool {
@@ -24,53 +57,363 @@ func DefaultSkipRule(_ string, tags []string) bool {
 }
 
 type CacheMiddleware struct {
+	name     string
 	cache    Cache
 	keyer    Keyer
 	policy   Policy
 	skipRule SkipRule
+
+	stats    *statsRecorder
+	observer Observer
+	tracer   Tracer
+	breaker  *executorBreaker
+	ops      *opLog
+
+	warmConcurrency      int
+	cacheableError       func(err error) bool
+	errorCodec           ErrorCodec
+	minRemainingDeadline time.Duration
+	oversizedKeys        OversizedKeyMode
+	maxKeyDuration       time.Duration
+	transform            ResultTransform
+	transformMode        TransformMode
+	revalidate           RevalidateFunc
+	partialTTL           time.Duration
+	ttlFromResult        TTLFromResultFunc
+	coalesce             *coalescer
+	setRetries           int
+	setBackoff           time.Duration
+	keyValidator         KeyValidator
+	etagGrace            time.Duration
+	keyTags              map[string]bool
+	hotKeys              *hotKeyTracker
+	maxResultBytes       int
+	negativeCache        *SegmentedCache
 }
 
-func NewCacheMiddleware(cache Cache, keyer Keyer, policy Policy, skipRule SkipRule) *CacheMiddleware {
-	return &CacheMiddleware{
-		cache:    cache,
-		keyer:    keyer,
-		policy:   policy,
-		skipRule: skipRule,
+// MiddlewareOption configures optional CacheMiddleware behavior.
+type MiddlewareOption func(*CacheMiddleware)
+
+// NewCacheMiddleware wraps cache with keying, policy and skip rules. A nil
+// keyer defaults to NewDefaultKeyer and a nil skipRule to DefaultSkipRule.
+// A nil cache is accepted, but every Execute call and WarmAll request then
+// fails with ErrNilCache without running the executor.
+func NewCacheMiddleware(cache Cache, keyer Keyer, policy Policy, skipRule SkipRule, opts ...MiddlewareOption) *CacheMiddleware {
+	if keyer == nil {
+		keyer = NewDefaultKeyer()
 	}
+	m := &CacheMiddleware{
+		cache:           cache,
+		keyer:           keyer,
+		policy:          policy,
+		skipRule:        skipRule,
+		warmConcurrency: DefaultWarmConcurrency,
+		stats:           newStatsRecorder(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 func (m *CacheMiddleware) Execute(ctx context.Context, toolID string, input any, tags []string, executor ToolExecutor) ([]byte, error) {
-	if m.shouldSkip(toolID, tags) {
-		return executor(ctx, toolID, input)
+	return m.execute(ctx, toolID, input, tags, executor, nil)
+}
+
+// ExecuteWithInfo behaves like Execute for executors that report result
+// metadata. The metadata is cached alongside the result when the cache
+// implements MetaCache, so a hit returns the same content type the
+// executor originally reported.
+func (m *CacheMiddleware) ExecuteWithInfo(ctx context.Context, toolID string, input any, tags []string, executor MetaExecutor) ([]byte, ExecInfo, error) {
+	var info ExecInfo
+	wrapped := func(ctx context.Context, toolID string, input any) ([]byte, error) {
+		value, meta, err := executor(ctx, toolID, input)
+		info.Meta = meta
+		return value, err
+	}
+	result, err := m.execute(ctx, toolID, input, tags, wrapped, &info)
+	return result, info, err
+}
+
+func (m *CacheMiddleware) execute(ctx context.Context, toolID string, input any, tags []string, executor ToolExecutor, info *ExecInfo) (result []byte, err error) {
+	ctx, endSpan := m.startSpan(ctx, toolID)
+	defer func() { endSpan(err) }()
+
+	if m.cache == nil {
+		return nil, ErrNilCache
+	}
+	if m.shouldSkip(ctx, toolID, tags) {
+		return m.executeUncached(ctx, toolID, input, executor)
+	}
+
+	start := time.Now()
+	key, err := m.safeKey(toolID, input)
+	elapsed := time.Since(start)
+	m.observe(ctx, Event{Kind: EventKeyed, ToolID: toolID, Key: key, Duration: elapsed, Err: err})
+	if err != nil || (m.maxKeyDuration > 0 && elapsed > m.maxKeyDuration) {
+		m.stats.record(toolID, ToolStats{Phases: PhaseTimings{Keying: elapsed}})
+		return m.executeUncached(ctx, toolID, input, executor)
 	}
 
-	key, err := m.keyer.Key(toolID, input)
+	key, err = m.checkKey(m.tagKey(key, tags))
 	if err != nil {
-		return executor(ctx, toolID, input)
+		return nil, err
+	}
+	if key == "" {
+		return m.executeUncached(ctx, toolID, input, executor)
+	}
+
+	return m.executeKeyed(ctx, toolID, key, input, executor, info, elapsed)
+}
+
+// safeKey calls the keyer, converting a panic into an error wrapping
+// ErrKeyerPanic so a faulty keyer degrades to uncached execution instead
+// of crashing Execute.
+func (m *CacheMiddleware) safeKey(toolID string, input any) (key string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			key, err = "", fmt.Errorf("%w: %v", ErrKeyerPanic, r)
+		}
+	}()
+	return m.keyer.Key(toolID, input)
+}
+
+// ExecuteWithKey behaves like Execute but uses the caller-provided key
+// instead of deriving one from input. This suits callers whose input is
+// not canonicalizable or who already hold a normalized key.
+//
+// The key must pass ValidateKey, or the validator set with
+// WithKeyValidator; otherwise the executor is not run and the
+// validation error is returned.
+func (m *CacheMiddleware) ExecuteWithKey(ctx context.Context, toolID, key string, input any, tags []string, executor ToolExecutor) (result []byte, err error) {
+	ctx, endSpan := m.startSpan(ctx, toolID)
+	defer func() { endSpan(err) }()
+
+	if m.cache == nil {
+		return nil, ErrNilCache
+	}
+	if err := m.validateKey(key); err != nil {
+		return nil, err
+	}
+
+	if m.shouldSkip(ctx, toolID, tags) {
+		return m.executeUncached(ctx, toolID, input, executor)
+	}
+
+	return m.executeKeyed(ctx, toolID, key, input, executor, nil, 0)
+}
+
+// executeKeyed serves key from the cache or runs the executor and stores
+// its result. When info is non-nil, entry metadata is read and written
+// through MetaCache and info.Cached is set on hits. keying is the time
+// spent deriving key, recorded with the other phase timings.
+func (m *CacheMiddleware) executeKeyed(ctx context.Context, toolID, key string, input any, executor ToolExecutor, info *ExecInfo, keying time.Duration) ([]byte, error) {
+	phases := PhaseTimings{Keying: keying}
+	defer func() { m.stats.record(toolID, ToolStats{Phases: phases}) }()
+
+	if m.hotKeys != nil {
+		m.hotKeys.observe(key)
+	}
+	phaseStart := time.Now()
+	cached, ok := m.cacheGet(ctx, key, info)
+	phases.CacheGet += time.Since(phaseStart)
+	if ok {
+		if m.stillValid(ctx, toolID, key, cached) {
+			m.stats.recordHit(toolID, len(cached))
+			m.logOp(ctx, OpHit, toolID, key)
+			return cached, nil
+		}
+		if info != nil {
+			*info = ExecInfo{}
+		}
+	}
+	phaseStart = time.Now()
+	negErr := m.getNegative(ctx, key)
+	phases.CacheGet += time.Since(phaseStart)
+	if negErr != nil {
+		m.stats.recordHit(toolID, 0)
+		m.stats.record(toolID, ToolStats{NegativeHits: 1})
+		m.logOp(ctx, OpHit, toolID, key)
+		if info != nil {
+			info.Cached = true
+		}
+		return nil, negErr
+	}
+
+	phaseStart = time.Now()
+	stale, staleMeta, revalidating := m.staleForRevalidation(ctx, key)
+	phases.CacheGet += time.Since(phaseStart)
+	if revalidating {
+		ctx = context.WithValue(ctx, ifNoneMatchKey{}, staleMeta.ETag)
 	}
 
-	if cached, ok := m.cache.Get(ctx, key); ok {
-		return cached, nil
+	start := time.Now()
+	result, shared, err := m.runCoalesced(ctx, toolID, key, input, executor)
+	latency := time.Since(start)
+	phases.Executor = latency
+	uncacheable := isDoNotCache(err)
+	if uncacheable {
+		err = nil
+	}
+	notModified := revalidating && errors.Is(err, ErrNotModified)
+	if notModified {
+		result, err = bytes.Clone(stale), nil
+		if shared && info != nil {
+			info.Meta = staleMeta
+		}
+	}
+	if shared {
+		m.stats.recordHit(toolID, len(result))
+		m.logOp(ctx, OpHit, toolID, key)
+		if info != nil {
+			info.Cached = true
+		}
+		return result, err
 	}
 
-	result, err := executor(ctx, toolID, input)
+	m.logOp(ctx, OpMiss, toolID, key)
+	phaseStart = time.Now()
+	defer func() { phases.CacheSet = time.Since(phaseStart) }()
+	if notModified {
+		return m.refreshNotModified(ctx, toolID, key, result, staleMeta, info, latency), nil
+	}
 	if err != nil {
+		if stored, returned, ok := m.cachePartial(ctx, toolID, key, result, info); ok {
+			m.stats.record(toolID, ToolStats{Misses: 1, Errors: 1, BytesStored: uint64(len(stored))})
+			m.logOp(ctx, OpSet, toolID, key)
+			return returned, err
+		}
+		m.stats.record(toolID, ToolStats{Misses: 1, Errors: 1})
+		m.setNegative(ctx, toolID, key, err)
 		return nil, err
 	}
+	delta := ToolStats{Misses: 1}
+	ttl := m.resultTTL(toolID, result)
+	stored, result, err := m.transformResult(toolID, result)
+	if err != nil {
+		m.stats.recordMiss(toolID, delta, latency)
+		return result, nil
+	}
+
+	if ttl > 0 && !uncacheable && !m.deadlineTooShort(ctx) && !m.oversizedResult(toolID, stored) {
+		if m.store(ctx, toolID, key, stored, ttl, info) == nil {
+			delta.BytesStored = uint64(len(stored))
+			m.logOp(ctx, OpSet, toolID, key)
+			if info != nil {
+				m.keepForRevalidation(ctx, key, stored, info.Meta, ttl)
+			}
+		}
+	}
+	m.stats.recordMiss(toolID, delta, latency)
+
+	return result, nil
+}
+
+func (m *CacheMiddleware) cacheGet(ctx context.Context, key string, info *ExecInfo) ([]byte, bool) {
+	if info == nil {
+		return m.cache.Get(ctx, key)
+	}
+
+	var (
+		value []byte
+		meta  EntryMeta
+		ok    bool
+	)
+	if mc, isMeta := m.cache.(MetaCache); isMeta {
+		value, meta, ok = mc.GetWithMeta(ctx, key)
+	} else {
+		value, ok = m.cache.Get(ctx, key)
+	}
+	if ok {
+		info.Meta = meta
+		info.Cached = true
+	}
+	return value, ok
+}
+
+func (m *CacheMiddleware) cacheSet(ctx context.Context, key string, value []byte, ttl time.Duration, info *ExecInfo) error {
+	priority, prioritized := priorityFromContext(ctx)
+	if info != nil || prioritized {
+		if mc, isMeta := m.cache.(MetaCache); isMeta {
+			var meta EntryMeta
+			if info != nil {
+				meta = info.Meta
+			}
+			if prioritized {
+				meta.Priority = priority
+			}
+			return mc.SetWithMeta(ctx, key, value, meta, ttl)
+		}
+	}
+	return m.cache.Set(ctx, key, value, ttl)
+}
+
+// WithMaxKeyDuration makes the middleware bypass the cache for calls whose
+// key took longer than d to compute, treating keying as too expensive to be
+// worth caching. Keying durations are reported to the Observer as
+// EventKeyed. A value of 0 (the default) disables the threshold.
+func WithMaxKeyDuration(d time.Duration) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.maxKeyDuration = d
+	}
+}
 
-	ttl := m.policy.EffectiveTTL(0)
-	if ttl > 0 {
-		_ = m.cache.Set(ctx, key, result, ttl)
+// WithMinRemainingDeadline makes the middleware skip cache writes when the
+// context's deadline is nearer than d, since a Set is then unlikely to
+// complete or be worth it. Cache reads are still attempted. A value of 0
+// (the default) disables the check.
+func WithMinRemainingDeadline(d time.Duration) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.minRemainingDeadline = d
 	}
+}
 
+// deadlineTooShort reports whether ctx's remaining deadline is below the
+// configured threshold.
+func (m *CacheMiddleware) deadlineTooShort(ctx context.Context) bool {
+	if m.minRemainingDeadline <= 0 {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) < m.minRemainingDeadline
+}
+
+// executeUncached runs the executor without consulting the cache.
+func (m *CacheMiddleware) executeUncached(ctx context.Context, toolID string, input any, executor ToolExecutor) ([]byte, error) {
+	m.logOp(ctx, OpSkip, toolID, "")
+	start := time.Now()
+	result, err := m.runExecutor(ctx, toolID, input, executor)
+	delta := ToolStats{Skips: 1, Phases: PhaseTimings{Executor: time.Since(start)}}
+	if isDoNotCache(err) {
+		err = nil
+	}
+	if err != nil {
+		delta.Errors = 1
+		m.stats.record(toolID, delta)
+		return result, err
+	}
+	m.stats.record(toolID, delta)
 	return result, nil
 }
 
-func (m *CacheMiddleware) shouldSkip(toolID string, tags []string) bool {
-	if m.policy.AllowUnsafe {
+func (m *CacheMiddleware) shouldSkip(ctx context.Context, toolID string, tags []string) bool {
+	allowUnsafe := m.policy.AllowUnsafe
+	override, overridden := allowUnsafeFromContext(ctx)
+	if overridden {
+		allowUnsafe = override
+	}
+
+	if allowUnsafe {
+		if overridden && !m.policy.AllowUnsafe && m.skipByRule(toolID, tags) {
+			m.observe(ctx, Event{Kind: EventUnsafeOverride, ToolID: toolID})
+		}
 		return false
 	}
 
+	return m.skipByRule(toolID, tags)
+}
+
+func (m *CacheMiddleware) skipByRule(toolID string, tags []string) bool {
 	if m.skipRule != nil {
 		return m.skipRule(toolID, tags)
 	}
@@ -415,3 +415,371 @@ func TestDefaultSkipRule(t *testing.T) {
 		})
 	}
 }
+
+func TestMiddleware_ExecuteWithKey(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("explicit")}
+	ctx := context.Background()
+
+	// Input is not canonicalizable, so only an explicit key can cache it.
+	input := struct{ Name string }{"opaque"}
+
+	result, err := mw.ExecuteWithKey(ctx, "test-tool", "upstream:key:1", input, []string{"read"}, executor.execute)
+	if err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	if string(result) != "explicit" {
+		t.Errorf("unexpected result: %s", result)
+	}
+
+	// Hit: same key, executor not called again
+	result, err = mw.ExecuteWithKey(ctx, "test-tool", "upstream:key:1", input, []string{"read"}, executor.execute)
+	if err != nil {
+		t.Fatalf("second call failed: %v", err)
+	}
+	if executor.calls != 1 {
+		t.Errorf("expected 1 call (cache hit), got %d", executor.calls)
+	}
+	if string(result) != "explicit" {
+		t.Errorf("unexpected cached result: %s", result)
+	}
+	if cached, ok := cache.Get(ctx, "upstream:key:1"); !ok || string(cached) != "explicit" {
+		t.Errorf("expected value stored under explicit key, got %q ok=%v", cached, ok)
+	}
+
+	// Miss: different key executes
+	_, err = mw.ExecuteWithKey(ctx, "test-tool", "upstream:key:2", input, []string{"read"}, executor.execute)
+	if err != nil {
+		t.Fatalf("third call failed: %v", err)
+	}
+	if executor.calls != 2 {
+		t.Errorf("expected 2 calls (cache miss), got %d", executor.calls)
+	}
+}
+
+func TestMiddleware_ExecuteWithKeyInvalid(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("x")}
+	ctx := context.Background()
+
+	testCases := []struct {
+		key     string
+		wantErr error
+	}{
+		{"", ErrInvalidKey},
+		{"   ", ErrInvalidKey},
+		{"bad\nkey", ErrInvalidKey},
+		{strings.Repeat("k", MaxKeyLength+1), ErrKeyTooLong},
+	}
+	for _, tc := range testCases {
+		_, err := mw.ExecuteWithKey(ctx, "test-tool", tc.key, nil, nil, executor.execute)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("ExecuteWithKey(%q) error = %v, want %v", tc.key, err, tc.wantErr)
+		}
+	}
+	if executor.calls != 0 {
+		t.Errorf("executor should not run for invalid keys, got %d calls", executor.calls)
+	}
+}
+
+func TestMiddleware_ExecuteWithKeySkipsUnsafe(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("x")}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := mw.ExecuteWithKey(ctx, "test-tool", "explicit-key", nil, []string{"write"}, executor.execute); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	if executor.calls != 2 {
+		t.Errorf("unsafe tools should not be cached, got %d calls", executor.calls)
+	}
+}
+
+func TestMiddleware_EmptyResultCached(t *testing.T) {
+	for name, empty := range map[string][]byte{"empty": {}, "nil": nil} {
+		t.Run(name, func(t *testing.T) {
+			mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+			executor := &mockExecutor{result: empty}
+			ctx := context.Background()
+			input := map[string]any{"x": 1}
+
+			for i := 0; i < 3; i++ {
+				result, err := mw.Execute(ctx, "empty-tool", input, nil, executor.execute)
+				if err != nil {
+					t.Fatalf("call %d failed: %v", i, err)
+				}
+				if len(result) != 0 {
+					t.Errorf("call %d: expected empty result, got %q", i, result)
+				}
+			}
+			if executor.calls != 1 {
+				t.Errorf("expected empty result to be cached (1 call), got %d", executor.calls)
+			}
+			if hits := mw.PerToolStats()["empty-tool"].Hits; hits != 2 {
+				t.Errorf("Hits = %d, want 2", hits)
+			}
+		})
+	}
+}
+
+func TestMiddleware_AllowUnsafeContextOverride(t *testing.T) {
+	obs := &recordingObserver{}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithObserver(obs))
+	executor := &mockExecutor{result: []byte("batch")}
+	input := map[string]any{"id": 1}
+	tags := []string{"write"}
+
+	// Without override, unsafe tool is skipped.
+	ctx := context.Background()
+	_, _ = mw.Execute(ctx, "batch:write", input, tags, executor.execute)
+	_, _ = mw.Execute(ctx, "batch:write", input, tags, executor.execute)
+	if executor.calls != 2 {
+		t.Fatalf("expected unsafe tool to be skipped, got %d calls", executor.calls)
+	}
+
+	// With override, the call is cached.
+	trusted := WithAllowUnsafe(ctx, true)
+	_, _ = mw.Execute(trusted, "batch:write", input, tags, executor.execute)
+	_, _ = mw.Execute(trusted, "batch:write", input, tags, executor.execute)
+	if executor.calls != 3 {
+		t.Errorf("expected override to cache unsafe tool, got %d calls", executor.calls)
+	}
+
+	events := obs.byKind(EventUnsafeOverride)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 audit events, got %d", len(events))
+	}
+	if events[0].ToolID != "batch:write" {
+		t.Errorf("audit event tool = %q", events[0].ToolID)
+	}
+
+	// Safe tools under an override are not audited.
+	_, _ = mw.Execute(trusted, "read", input, []string{"read"}, executor.execute)
+	if got := len(obs.byKind(EventUnsafeOverride)); got != 2 {
+		t.Errorf("safe tool should not emit audit event, got %d events", got)
+	}
+}
+
+func TestMiddleware_AllowUnsafeContextDisables(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.AllowUnsafe = true
+	mw := NewCacheMiddleware(NewMemoryCache(policy), NewDefaultKeyer(), policy, nil)
+	executor := &mockExecutor{result: []byte("x")}
+
+	ctx := WithAllowUnsafe(context.Background(), false)
+	_, _ = mw.Execute(ctx, "t", nil, []string{"delete"}, executor.execute)
+	_, _ = mw.Execute(ctx, "t", nil, []string{"delete"}, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("override=false should skip unsafe tools for this call, got %d calls", executor.calls)
+	}
+}
+
+func TestMiddleware_MinRemainingDeadline(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(cache, keyer, DefaultPolicy(), nil,
+		WithMinRemainingDeadline(time.Second))
+	executor := &mockExecutor{result: []byte("fresh")}
+	input := map[string]any{"q": "x"}
+	key, _ := keyer.Key("tool", input)
+
+	// Near deadline: result returned, Set skipped.
+	nearCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err := mw.Execute(nearCtx, "tool", input, nil, executor.execute)
+	if err != nil || string(result) != "fresh" {
+		t.Fatalf("result = %q, err = %v", result, err)
+	}
+	if _, ok := cache.Get(context.Background(), key); ok {
+		t.Error("Set should be skipped when the deadline is near")
+	}
+
+	// Get is still attempted near the deadline.
+	_ = cache.Set(context.Background(), key, []byte("cached"), time.Minute)
+	result, _ = mw.Execute(nearCtx, "tool", input, nil, executor.execute)
+	if string(result) != "cached" || executor.calls != 1 {
+		t.Errorf("expected cache hit near deadline, got %q after %d calls", result, executor.calls)
+	}
+	_ = cache.Delete(context.Background(), key)
+
+	// Ample deadline and no deadline both cache.
+	farCtx, cancelFar := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelFar()
+	for _, ctx := range []context.Context{farCtx, context.Background()} {
+		_ = cache.Delete(ctx, key)
+		_, _ = mw.Execute(ctx, "tool", input, nil, executor.execute)
+		if _, ok := cache.Get(ctx, key); !ok {
+			t.Error("Set should happen with ample or no deadline")
+		}
+	}
+}
+
+func TestMiddleware_ExecuteWithInfoContentType(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	ctx := context.Background()
+	calls := 0
+	executor := func(_ context.Context, _ string, _ any) ([]byte, EntryMeta, error) {
+		calls++
+		return []byte("<p>hi</p>"), EntryMeta{ContentType: "text/html"}, nil
+	}
+	input := map[string]any{"page": "home"}
+
+	result, info, err := mw.ExecuteWithInfo(ctx, "render", input, nil, executor)
+	if err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	if info.Cached || info.Meta.ContentType != "text/html" || string(result) != "<p>hi</p>" {
+		t.Errorf("miss: result=%q info=%+v", result, info)
+	}
+
+	result, info, err = mw.ExecuteWithInfo(ctx, "render", input, nil, executor)
+	if err != nil {
+		t.Fatalf("second call failed: %v", err)
+	}
+	if !info.Cached || info.Meta.ContentType != "text/html" || string(result) != "<p>hi</p>" {
+		t.Errorf("hit: result=%q info=%+v", result, info)
+	}
+	if calls != 1 {
+		t.Errorf("expected cache hit, got %d calls", calls)
+	}
+
+	// Plain Execute shares the same entry.
+	if plain, _ := mw.Execute(ctx, "render", input, nil, (&mockExecutor{}).execute); string(plain) != "<p>hi</p>" {
+		t.Errorf("Execute should hit the same entry, got %q", plain)
+	}
+}
+
+func TestMiddleware_ExecuteWithInfoPlainCache(t *testing.T) {
+	// A Cache without MetaCache support still caches, but loses metadata.
+	backing := NewMemoryCache(DefaultPolicy())
+	plain := struct{ Cache }{backing}
+	mw := NewCacheMiddleware(plain, NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := func(_ context.Context, _ string, _ any) ([]byte, EntryMeta, error) {
+		return []byte("{}"), EntryMeta{ContentType: "application/json"}, nil
+	}
+	ctx := context.Background()
+
+	_, _, _ = mw.ExecuteWithInfo(ctx, "tool", nil, nil, executor)
+	_, info, _ := mw.ExecuteWithInfo(ctx, "tool", nil, nil, executor)
+	if !info.Cached {
+		t.Error("expected cache hit")
+	}
+	if info.Meta.ContentType != "" {
+		t.Errorf("plain cache cannot return metadata, got %q", info.Meta.ContentType)
+	}
+}
+
+func TestMiddleware_MaxKeyDuration(t *testing.T) {
+	slowKeyer := KeyerFunc(func(toolID string, input any) (string, error) {
+		time.Sleep(20 * time.Millisecond)
+		return NewDefaultKeyer().Key(toolID, input)
+	})
+	obs := &recordingObserver{}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), slowKeyer, DefaultPolicy(), nil,
+		WithMaxKeyDuration(5*time.Millisecond), WithObserver(obs))
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("slow keying should bypass caching, got %d calls", executor.calls)
+	}
+	if got := mw.Stats().Skips; got != 2 {
+		t.Errorf("Skips = %d, want 2", got)
+	}
+
+	events := obs.byKind(EventKeyed)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 keyed events, got %d", len(events))
+	}
+	for _, ev := range events {
+		if ev.Duration < 20*time.Millisecond {
+			t.Errorf("keyed duration = %v, want >= 20ms", ev.Duration)
+		}
+		if ev.ToolID != "tool" || ev.Key == "" {
+			t.Errorf("keyed event = %+v", ev)
+		}
+	}
+}
+
+func TestMiddleware_MaxKeyDurationFastKeyer(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithMaxKeyDuration(time.Second))
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	if executor.calls != 1 {
+		t.Errorf("fast keying should cache normally, got %d calls", executor.calls)
+	}
+}
+
+func TestMiddleware_KeyerPanicFallsBackToUncached(t *testing.T) {
+	panicky := KeyerFunc(func(string, any) (string, error) {
+		panic("boom")
+	})
+	obs := &recordingObserver{}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), panicky, DefaultPolicy(), nil, WithObserver(obs))
+	executor := &mockExecutor{result: []byte("v")}
+
+	result, err := mw.Execute(context.Background(), "tool", nil, nil, executor.execute)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if string(result) != "v" || executor.calls != 1 {
+		t.Errorf("executor should still run, got %q after %d calls", result, executor.calls)
+	}
+	if got := mw.Stats().Skips; got != 1 {
+		t.Errorf("Skips = %d, want 1", got)
+	}
+
+	events := obs.byKind(EventKeyed)
+	if len(events) != 1 || !errors.Is(events[0].Err, ErrKeyerPanic) {
+		t.Fatalf("expected keyed event with ErrKeyerPanic, got %+v", events)
+	}
+	if !strings.Contains(events[0].Err.Error(), "boom") {
+		t.Errorf("panic value missing from error: %v", events[0].Err)
+	}
+}
+
+func TestMiddleware_NilCache(t *testing.T) {
+	mw := NewCacheMiddleware(nil, nil, DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	if _, err := mw.Execute(ctx, "tool", nil, nil, executor.execute); !errors.Is(err, ErrNilCache) {
+		t.Errorf("Execute() error = %v, want ErrNilCache", err)
+	}
+	if _, err := mw.ExecuteWithKey(ctx, "tool", "toolcache:tool:k", nil, nil, executor.execute); !errors.Is(err, ErrNilCache) {
+		t.Errorf("ExecuteWithKey() error = %v, want ErrNilCache", err)
+	}
+	infoExec := func(context.Context, string, any) ([]byte, EntryMeta, error) { return nil, EntryMeta{}, nil }
+	if _, _, err := mw.ExecuteWithInfo(ctx, "tool", nil, nil, infoExec); !errors.Is(err, ErrNilCache) {
+		t.Errorf("ExecuteWithInfo() error = %v, want ErrNilCache", err)
+	}
+	results := mw.WarmAll(ctx, []WarmRequest{{ToolID: "tool"}}, executor.execute)
+	if results[0].Status != WarmFailed || !errors.Is(results[0].Err, ErrNilCache) {
+		t.Errorf("WarmAll() result = %+v, want ErrNilCache failure", results[0])
+	}
+	if executor.calls != 0 {
+		t.Errorf("executor should not run without a cache, got %d calls", executor.calls)
+	}
+}
+
+func TestMiddleware_NilKeyerUsesDefault(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), nil, DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", map[string]any{"a": 1}, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", map[string]any{"a": 1}, nil, executor.execute)
+	if executor.calls != 1 {
+		t.Errorf("nil keyer should fall back to DefaultKeyer and cache, got %d calls", executor.calls)
+	}
+}
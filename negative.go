@@ -0,0 +1,165 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+// negativeKeySuffix is appended to a result key to form the key under
+// which a cached error for the same invocation is stored.
+const negativeKeySuffix = ":error"
+
+// ErrCachedError is matched (via errors.Is) by every error served from the
+// negative cache.
+var ErrCachedError = errors.New("toolcache: cached error")
+
+// CachedError is returned in place of an executor error served from the
+// negative cache. Only the original message survives serialization, so the
+// reconstructed error will not match the original error's type or sentinel
+// via errors.Is/errors.As; match ErrCachedError or *CachedError instead.
+type CachedError struct {
+	Message string
+}
+
+func (e *CachedError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns ErrCachedError.
+func (e *CachedError) Unwrap() error {
+	return ErrCachedError
+}
+
+// ErrorCodec converts executor errors to and from the bytes stored by the
+// negative cache.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Decoding: DecodeError returns nil for unrecognized data, which is treated as a miss.
+type ErrorCodec interface {
+	EncodeError(err error) ([]byte, error)
+	DecodeError(data []byte) error
+}
+
+// errorMarker prefixes values written by the default ErrorCodec.
+var errorMarker = []byte("toolcache-error/v1\n")
+
+// DefaultErrorCodec stores the error message behind a version marker and
+// restores it as a *CachedError.
+type DefaultErrorCodec struct{}
+
+func (DefaultErrorCodec) EncodeError(err error) ([]byte, error) {
+	msg := err.Error()
+	data := make([]byte, 0, len(errorMarker)+len(msg))
+	data = append(data, errorMarker...)
+	return append(data, msg...), nil
+}
+
+func (DefaultErrorCodec) DecodeError(data []byte) error {
+	msg, ok := bytes.CutPrefix(data, errorMarker)
+	if !ok {
+		return nil
+	}
+	return &CachedError{Message: string(msg)}
+}
+
+// WithErrorCodec sets how errors are serialized for negative caching.
+// The default is DefaultErrorCodec.
+func WithErrorCodec(codec ErrorCodec) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.errorCodec = codec
+	}
+}
+
+func (m *CacheMiddleware) codec() ErrorCodec {
+	if m.errorCodec != nil {
+		return m.errorCodec
+	}
+	return DefaultErrorCodec{}
+}
+
+// WithCacheableError sets the predicate consulted before an executor error
+// is negatively cached (see Policy.NegativeTTL). Only errors for which it
+// returns true are cached; use it to admit deterministic failures such as
+// validation errors and reject transient ones such as timeouts or 5xx.
+//
+// Context cancellation and deadline errors, and ErrExecutorCircuitOpen, are
+// never cached, regardless of the predicate. When unset, every other error
+// is cacheable.
+func WithCacheableError(fn func(err error) bool) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.cacheableError = fn
+	}
+}
+
+// isCacheableError reports whether err may be negatively cached.
+func (m *CacheMiddleware) isCacheableError(err error) bool {
+	if isContextError(err) {
+		return false
+	}
+	if errors.Is(err, ErrExecutorCircuitOpen) {
+		return false
+	}
+	if m.cacheableError != nil {
+		return m.cacheableError(err)
+	}
+	return true
+}
+
+// WithNegativeCacheSize stores cached errors in a separate in-memory LRU
+// holding at most n entries, instead of alongside results in the main
+// cache. A storm of failing calls then evicts only other cached errors,
+// never useful results, and the main cache's budget is left to results.
+// Entries still expire after Policy.NegativeTTL; Stats reports how many are
+// held as NegativeEntries. Values below 1 are treated as 1.
+func WithNegativeCacheSize(n int) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.negativeCache = NewSegmentedCache(WithProbationSize(n), WithProtectedSize(0))
+	}
+}
+
+// negativeStore returns the cache holding cached errors.
+func (m *CacheMiddleware) negativeStore() Cache {
+	if m.negativeCache != nil {
+		return m.negativeCache
+	}
+	return m.cache
+}
+
+// getNegative returns the cached error for key, or nil if negative caching
+// is disabled or no error is cached.
+func (m *CacheMiddleware) getNegative(ctx context.Context, key string) error {
+	if m.policy.EffectiveNegativeTTL() <= 0 {
+		return nil
+	}
+	errKey, ok := m.suffixKey(key, negativeKeySuffix)
+	if !ok {
+		return nil
+	}
+	data, ok := m.negativeStore().Get(ctx, errKey)
+	if !ok {
+		return nil
+	}
+	return m.codec().DecodeError(data)
+}
+
+// setNegative caches err for key when negative caching is enabled and the
+// error is classified as cacheable.
+func (m *CacheMiddleware) setNegative(ctx context.Context, toolID, key string, err error) {
+	ttl := m.policy.EffectiveNegativeTTL()
+	if ttl <= 0 || !m.isCacheableError(err) || m.deadlineTooShort(ctx) {
+		return
+	}
+	errKey, ok := m.suffixKey(key, negativeKeySuffix)
+	if !ok {
+		return
+	}
+	data, encErr := m.codec().EncodeError(err)
+	if encErr != nil {
+		return
+	}
+	if m.negativeStore().Set(ctx, errKey, data, ttl) == nil {
+		m.stats.record(toolID, ToolStats{NegativeStores: 1})
+	}
+}
@@ -0,0 +1,298 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var (
+	errValidation = errors.New("invalid argument")
+	errUpstream   = errors.New("upstream 503")
+)
+
+func negativePolicy() Policy {
+	p := DefaultPolicy()
+	p.NegativeTTL = time.Minute
+	return p
+}
+
+func isPermanent(err error) bool {
+	return errors.Is(err, errValidation)
+}
+
+func TestNegativeCache_PermanentErrorCached(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), negativePolicy(), nil,
+		WithCacheableError(isPermanent))
+	executor := &mockExecutor{err: fmt.Errorf("bad path: %w", errValidation)}
+	ctx := context.Background()
+	input := map[string]any{"path": ""}
+
+	for i := 0; i < 3; i++ {
+		_, err := mw.Execute(ctx, "fs:read", input, nil, executor.execute)
+		if err == nil {
+			t.Fatalf("call %d: expected error", i)
+		}
+		if err.Error() != "bad path: invalid argument" {
+			t.Errorf("call %d: error = %q", i, err)
+		}
+	}
+	if executor.calls != 1 {
+		t.Errorf("permanent error should be cached, got %d calls", executor.calls)
+	}
+}
+
+func TestNegativeCache_TransientErrorNotCached(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), negativePolicy(), nil,
+		WithCacheableError(isPermanent))
+	executor := &mockExecutor{err: errUpstream}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := mw.Execute(ctx, "http:get", map[string]any{"u": "x"}, nil, executor.execute); !errors.Is(err, errUpstream) {
+			t.Fatalf("call %d: error = %v, want %v", i, err, errUpstream)
+		}
+	}
+	if executor.calls != 3 {
+		t.Errorf("transient error should not be cached, got %d calls", executor.calls)
+	}
+}
+
+func TestNegativeCache_ContextErrorsNeverCached(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), negativePolicy(), nil,
+		WithCacheableError(func(error) bool { return true }))
+	ctx := context.Background()
+
+	for _, ctxErr := range []error{context.Canceled, context.DeadlineExceeded} {
+		executor := &mockExecutor{err: fmt.Errorf("wrapped: %w", ctxErr)}
+		for i := 0; i < 2; i++ {
+			_, _ = mw.Execute(ctx, "tool", map[string]any{"e": ctxErr.Error()}, nil, executor.execute)
+		}
+		if executor.calls != 2 {
+			t.Errorf("%v should never be cached, got %d calls", ctxErr, executor.calls)
+		}
+	}
+}
+
+func TestNegativeCache_DisabledByDefault(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithCacheableError(func(error) bool { return true }))
+	executor := &mockExecutor{err: errValidation}
+
+	for i := 0; i < 2; i++ {
+		_, _ = mw.Execute(context.Background(), "tool", nil, nil, executor.execute)
+	}
+	if executor.calls != 2 {
+		t.Errorf("errors should not be cached without NegativeTTL, got %d calls", executor.calls)
+	}
+}
+
+func TestNegativeCache_Expires(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.NegativeTTL = 30 * time.Millisecond
+	mw := NewCacheMiddleware(NewMemoryCache(policy), NewDefaultKeyer(), policy, nil)
+	executor := &mockExecutor{err: errValidation}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	if executor.calls != 1 {
+		t.Fatalf("expected cached error, got %d calls", executor.calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	executor.err = nil
+	executor.result = []byte("recovered")
+
+	result, err := mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	if err != nil {
+		t.Fatalf("expected recovery after NegativeTTL, got %v", err)
+	}
+	if string(result) != "recovered" || executor.calls != 2 {
+		t.Errorf("result = %q after %d calls", result, executor.calls)
+	}
+}
+
+func TestNegativeCache_CachedErrorRoundTrip(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), negativePolicy(), nil)
+	executor := &mockExecutor{err: fmt.Errorf("lookup failed: %w", errValidation)}
+	ctx := context.Background()
+
+	first, _ := mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	_, err := mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	if first != nil {
+		t.Errorf("expected nil result, got %q", first)
+	}
+
+	var cached *CachedError
+	if !errors.As(err, &cached) {
+		t.Fatalf("expected *CachedError, got %T: %v", err, err)
+	}
+	if cached.Message != "lookup failed: invalid argument" {
+		t.Errorf("Message = %q", cached.Message)
+	}
+	if !errors.Is(err, ErrCachedError) {
+		t.Error("cached error should match ErrCachedError")
+	}
+	// The original error chain does not survive serialization.
+	if errors.Is(err, errValidation) {
+		t.Error("reconstructed error should not match the original sentinel")
+	}
+}
+
+func TestDefaultErrorCodec(t *testing.T) {
+	codec := DefaultErrorCodec{}
+
+	data, err := codec.EncodeError(errors.New("line1\nline2"))
+	if err != nil {
+		t.Fatalf("EncodeError failed: %v", err)
+	}
+	decoded := codec.DecodeError(data)
+	if decoded == nil || decoded.Error() != "line1\nline2" {
+		t.Errorf("DecodeError = %v", decoded)
+	}
+
+	// Data without the marker is not recognized.
+	if got := codec.DecodeError([]byte("plain result")); got != nil {
+		t.Errorf("DecodeError on unmarked data = %v, want nil", got)
+	}
+}
+
+type upperCodec struct{}
+
+func (upperCodec) EncodeError(err error) ([]byte, error) {
+	return []byte("E:" + err.Error()), nil
+}
+
+func (upperCodec) DecodeError(data []byte) error {
+	if len(data) < 2 || string(data[:2]) != "E:" {
+		return nil
+	}
+	return errors.New("restored " + string(data[2:]))
+}
+
+func TestNegativeCache_CustomCodec(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), negativePolicy(), nil, WithErrorCodec(upperCodec{}))
+	executor := &mockExecutor{err: errors.New("boom")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	_, err := mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	if err == nil || err.Error() != "restored boom" {
+		t.Errorf("error = %v, want restored boom", err)
+	}
+	if executor.calls != 1 {
+		t.Errorf("expected cached error, got %d calls", executor.calls)
+	}
+}
+
+func TestNegativeCache_UnrecognizedEntryIsMiss(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(cache, keyer, negativePolicy(), nil)
+	ctx := context.Background()
+
+	key, _ := keyer.Key("tool", nil)
+	_ = cache.Set(ctx, key+negativeKeySuffix, []byte("garbage"), time.Minute)
+
+	executor := &mockExecutor{result: []byte("fresh")}
+	result, err := mw.Execute(ctx, "tool", nil, nil, executor.execute)
+	if err != nil || string(result) != "fresh" || executor.calls != 1 {
+		t.Errorf("unrecognized negative entry should miss: result=%q err=%v calls=%d", result, err, executor.calls)
+	}
+}
+
+func TestNegativeCacheSize_ErrorsDoNotEvictResults(t *testing.T) {
+	for _, separate := range []bool{false, true} {
+		t.Run(fmt.Sprintf("separate=%v", separate), func(t *testing.T) {
+			// A main cache with room for five entries.
+			cache := NewSegmentedCache(WithProbationSize(5), WithProtectedSize(0))
+			var opts []MiddlewareOption
+			if separate {
+				opts = append(opts, WithNegativeCacheSize(10))
+			}
+			mw := NewCacheMiddleware(cache, NewDefaultKeyer(), negativePolicy(), nil, opts...)
+			ok := &mockExecutor{result: []byte("v")}
+			failing := &mockExecutor{err: errValidation}
+			ctx := context.Background()
+
+			for i := range 3 {
+				_, _ = mw.Execute(ctx, "good", i, nil, ok.execute)
+			}
+			for i := range 100 {
+				_, _ = mw.Execute(ctx, "bad", i, nil, failing.execute)
+			}
+			for i := range 3 {
+				_, _ = mw.Execute(ctx, "good", i, nil, ok.execute)
+			}
+
+			if separate && ok.calls != 3 {
+				t.Errorf("error storm evicted results: %d executor calls, want 3", ok.calls)
+			}
+			if !separate && ok.calls != 6 {
+				t.Errorf("shared cache: %d executor calls, want errors to evict all 3 results", ok.calls)
+			}
+		})
+	}
+}
+
+func TestNegativeCacheSize_OwnLimitAndStats(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), negativePolicy(), nil,
+		WithNegativeCacheSize(10))
+	failing := &mockExecutor{err: errValidation}
+	ok := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	for i := range 20 {
+		_, _ = mw.Execute(ctx, "bad", i, nil, failing.execute)
+	}
+	_, _ = mw.Execute(ctx, "good", 1, nil, ok.execute)
+	_, _ = mw.Execute(ctx, "good", 1, nil, ok.execute)
+
+	// Only the ten most recent errors are kept.
+	for i := 10; i < 20; i++ {
+		if _, err := mw.Execute(ctx, "bad", i, nil, failing.execute); !errors.Is(err, ErrCachedError) {
+			t.Errorf("input %d: err = %v, want a cached error", i, err)
+		}
+	}
+	if _, err := mw.Execute(ctx, "bad", 0, nil, failing.execute); errors.Is(err, ErrCachedError) {
+		t.Error("oldest error should have been evicted from the negative cache")
+	}
+	if failing.calls != 21 {
+		t.Errorf("failing executor calls = %d, want 21", failing.calls)
+	}
+
+	stats := mw.Stats()
+	if stats.NegativeEntries != 10 {
+		t.Errorf("NegativeEntries = %d, want 10", stats.NegativeEntries)
+	}
+	if stats.NegativeStores != 21 || stats.NegativeHits != 10 {
+		t.Errorf("NegativeStores, NegativeHits = %d, %d; want 21, 10", stats.NegativeStores, stats.NegativeHits)
+	}
+	if positive := stats.Hits - stats.NegativeHits; positive != 1 {
+		t.Errorf("positive hits = %d, want 1", positive)
+	}
+	if bad := mw.PerToolStats()["bad"]; bad.NegativeHits != 10 || bad.Hits != 10 {
+		t.Errorf("per-tool stats = %+v", bad)
+	}
+}
+
+func TestNegativeCacheSize_RespectsNegativeTTL(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.NegativeTTL = 20 * time.Millisecond
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), policy, nil,
+		WithNegativeCacheSize(10))
+	failing := &mockExecutor{err: errValidation}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "bad", 1, nil, failing.execute)
+	_, _ = mw.Execute(ctx, "bad", 1, nil, failing.execute)
+	time.Sleep(40 * time.Millisecond)
+	_, _ = mw.Execute(ctx, "bad", 1, nil, failing.execute)
+	if failing.calls != 2 {
+		t.Errorf("executor calls = %d, want the error re-run after NegativeTTL", failing.calls)
+	}
+}
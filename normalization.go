@@ -0,0 +1,97 @@
+package toolcache
+
+import (
+	"maps"
+	"strings"
+)
+
+// KeyerNormalization gathers DefaultKeyer's hit-rate normalizations so
+// they can be tuned on the middleware in one place. Every knob is off in
+// the zero value, keeping keys exact. Paths use the DefaultKeyer
+// StringNormalizers syntax: map keys joined by ".", with "*" for any array
+// element.
+type KeyerNormalization struct {
+	// DropNullFields omits null map fields, so null and absent hash alike.
+	DropNullFields bool
+
+	// IgnoreFields lists fields left out of the key entirely.
+	IgnoreFields []string
+
+	// FloatPrecision rounds floats at each path to the given number of
+	// decimal places.
+	FloatPrecision map[string]int
+
+	// CaseInsensitiveFields lists string fields compared case-insensitively.
+	CaseInsensitiveFields []string
+
+	// SetArrays lists arrays whose element order and duplicates are ignored.
+	SetArrays []string
+
+	// BoolFields lists fields whose boolean spellings (true, "true", 1)
+	// hash alike.
+	BoolFields []string
+}
+
+// WithKeyerNormalization applies n to the middleware's keyer, which must
+// be a *DefaultKeyer (the default); other keyers are left unchanged. The
+// middleware keys with a copy of that keyer carrying n on top of its own
+// settings, where n's paths add to any the keyer already has. The copy
+// shares the original's epoch and collision tracking, so SetEpoch and
+// TrackCollisions on the original still take effect.
+func WithKeyerNormalization(n KeyerNormalization) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		if k, ok := m.keyer.(*DefaultKeyer); ok {
+			m.keyer = k.normalized(n)
+		}
+	}
+}
+
+// normalized returns a copy of k with n applied.
+func (k *DefaultKeyer) normalized(n KeyerNormalization) *DefaultKeyer {
+	derived := &DefaultKeyer{
+		NormalizeNumbers:      k.NormalizeNumbers,
+		InputIndependentTools: k.InputIndependentTools,
+		MaxMapFields:          k.MaxMapFields,
+		DropNullFields:        k.DropNullFields || n.DropNullFields,
+		StringNormalizers:     k.StringNormalizers,
+		FloatPrecision:        k.FloatPrecision,
+		IgnoreFields:          withPaths(k.IgnoreFields, n.IgnoreFields),
+		SetArrays:             withPaths(k.SetArrays, n.SetArrays),
+		BoolFields:            withPaths(k.BoolFields, n.BoolFields),
+		ReplaceUnsupported:    k.ReplaceUnsupported,
+		FallbackKeyFunc:       k.FallbackKeyFunc,
+		MaxDepth:              k.MaxDepth,
+		base:                  k.root(),
+	}
+	if len(n.FloatPrecision) > 0 {
+		derived.FloatPrecision = maps.Clone(k.FloatPrecision)
+		if derived.FloatPrecision == nil {
+			derived.FloatPrecision = make(map[string]int, len(n.FloatPrecision))
+		}
+		maps.Copy(derived.FloatPrecision, n.FloatPrecision)
+	}
+	if len(n.CaseInsensitiveFields) > 0 {
+		derived.StringNormalizers = maps.Clone(k.StringNormalizers)
+		if derived.StringNormalizers == nil {
+			derived.StringNormalizers = make(map[string]func(string) string, len(n.CaseInsensitiveFields))
+		}
+		for _, path := range n.CaseInsensitiveFields {
+			derived.StringNormalizers[path] = strings.ToLower
+		}
+	}
+	return derived
+}
+
+// withPaths returns set extended with paths, copying it only if paths
+// adds anything.
+func withPaths(set map[string]bool, paths []string) map[string]bool {
+	if len(paths) == 0 {
+		return set
+	}
+	extended := make(map[string]bool, len(set)+len(paths))
+	maps.Copy(extended, set)
+	for _, path := range paths {
+		extended[path] = true
+	}
+	return extended
+}
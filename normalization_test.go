@@ -0,0 +1,137 @@
+package toolcache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKeyerNormalization_EachKnob(t *testing.T) {
+	testCases := []struct {
+		name string
+		norm KeyerNormalization
+		a, b any
+	}{
+		{
+			name: "drop nulls",
+			norm: KeyerNormalization{DropNullFields: true},
+			a:    map[string]any{"q": "x", "limit": nil},
+			b:    map[string]any{"q": "x"},
+		},
+		{
+			name: "ignore fields",
+			norm: KeyerNormalization{IgnoreFields: []string{"request_id", "opts.trace"}},
+			a:    map[string]any{"q": "x", "request_id": "r1", "opts": map[string]any{"trace": "t1", "n": 1.0}},
+			b:    map[string]any{"q": "x", "request_id": "r2", "opts": map[string]any{"trace": "t2", "n": 1.0}},
+		},
+		{
+			name: "float rounding",
+			norm: KeyerNormalization{FloatPrecision: map[string]int{"lat": 3}},
+			a:    map[string]any{"lat": 51.50012},
+			b:    map[string]any{"lat": 51.49988},
+		},
+		{
+			name: "string case",
+			norm: KeyerNormalization{CaseInsensitiveFields: []string{"hosts.*"}},
+			a:    map[string]any{"hosts": []any{"Example.COM"}},
+			b:    map[string]any{"hosts": []any{"example.com"}},
+		},
+		{
+			name: "set arrays",
+			norm: KeyerNormalization{SetArrays: []string{"tags"}},
+			a:    map[string]any{"tags": []any{"b", "a", "a"}},
+			b:    map[string]any{"tags": []any{"a", "b"}},
+		},
+		{
+			name: "bool fields",
+			norm: KeyerNormalization{BoolFields: []string{"recursive"}},
+			a:    map[string]any{"recursive": "true"},
+			b:    map[string]any{"recursive": 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			for _, enabled := range []bool{false, true} {
+				var opts []MiddlewareOption
+				if enabled {
+					opts = append(opts, WithKeyerNormalization(tc.norm))
+				}
+				mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), nil, DefaultPolicy(), nil, opts...)
+				executor := &mockExecutor{result: []byte("ok")}
+				_, _ = mw.Execute(ctx, "tool", tc.a, nil, executor.execute)
+				_, _ = mw.Execute(ctx, "tool", tc.b, nil, executor.execute)
+
+				want := 2
+				if enabled {
+					want = 1
+				}
+				if executor.calls != want {
+					t.Errorf("enabled=%v: executor calls = %d, want %d", enabled, executor.calls, want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyerNormalization_KeepsFieldsElsewhere(t *testing.T) {
+	keyer := NewDefaultKeyer().normalized(KeyerNormalization{
+		IgnoreFields: []string{"request_id"},
+		SetArrays:    []string{"tags"},
+	})
+
+	k1, _ := keyer.Key("tool", map[string]any{"nested": map[string]any{"request_id": "r1"}})
+	k2, _ := keyer.Key("tool", map[string]any{"nested": map[string]any{"request_id": "r2"}})
+	if k1 == k2 {
+		t.Error("IgnoreFields should only match the listed path")
+	}
+
+	k1, _ = keyer.Key("tool", map[string]any{"order": []any{"a", "b"}})
+	k2, _ = keyer.Key("tool", map[string]any{"order": []any{"b", "a"}})
+	if k1 == k2 {
+		t.Error("arrays not listed in SetArrays must keep their order")
+	}
+}
+
+func TestKeyerNormalization_ExtendsKeyerSettings(t *testing.T) {
+	base := &DefaultKeyer{
+		NormalizeNumbers: true,
+		FloatPrecision:   map[string]int{"a": 1},
+	}
+	derived := base.normalized(KeyerNormalization{FloatPrecision: map[string]int{"b": 1}})
+
+	if !derived.NormalizeNumbers {
+		t.Error("keyer settings should carry over")
+	}
+	if len(derived.FloatPrecision) != 2 {
+		t.Errorf("FloatPrecision = %v, want both paths", derived.FloatPrecision)
+	}
+	if len(base.FloatPrecision) != 1 {
+		t.Errorf("original keyer modified: %v", base.FloatPrecision)
+	}
+}
+
+func TestKeyerNormalization_SharesEpoch(t *testing.T) {
+	base := NewDefaultKeyer()
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), base, DefaultPolicy(), nil,
+		WithKeyerNormalization(KeyerNormalization{DropNullFields: true}))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	base.SetEpoch(3)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("bumping the original keyer's epoch should force a miss, got %d calls", executor.calls)
+	}
+}
+
+func TestKeyerNormalization_IgnoresCustomKeyer(t *testing.T) {
+	custom := KeyerFunc(func(toolID string, _ any) (string, error) { return toolID, nil })
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), custom, DefaultPolicy(), nil,
+		WithKeyerNormalization(KeyerNormalization{DropNullFields: true}))
+	if key, _ := mw.safeKey("tool", nil); key != "tool" {
+		t.Errorf("custom keyer replaced: key = %q", key)
+	}
+}
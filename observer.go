@@ -0,0 +1,105 @@
+package toolcache
+
+import (
+	"context"
+	"time"
+)
+
+// EventKind identifies the kind of an Event.
+type EventKind int
+
+const (
+	// EventUnsafeOverride reports that a per-call WithAllowUnsafe override
+	// caused an otherwise-skipped tool to be cached.
+	EventUnsafeOverride EventKind = iota
+
+	// EventKeyed reports a key computation. Duration is the time spent in
+	// Keyer.Key and Err is any keying error.
+	EventKeyed
+
+	// EventSetFailed reports a cache write that failed after any retries
+	// configured with WithSetRetry. Err is the last error.
+	EventSetFailed
+
+	// EventSlowCacheOp reports a cache operation slower than the threshold
+	// of a SlowOpCache. Op names the operation, Duration is how long it
+	// took and Err is any error it returned.
+	EventSlowCacheOp
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventUnsafeOverride:
+		return "unsafe_override"
+	case EventKeyed:
+		return "keyed"
+	case EventSetFailed:
+		return "set_failed"
+	case EventSlowCacheOp:
+		return "slow_cache_op"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes something notable the middleware did.
+type Event struct {
+	Kind EventKind
+
+	// Middleware is the name set with WithName, so one Observer can serve
+	// several middleware instances.
+	Middleware string
+
+	ToolID   string
+	Key      string
+	Duration time.Duration
+	Err      error
+
+	// Op is the cache operation for EventSlowCacheOp: "get", "set" or
+	// "delete".
+	Op string
+}
+
+// Observer receives middleware events.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Latency: Observe runs inline with Execute and should return quickly.
+type Observer interface {
+	Observe(ctx context.Context, ev Event)
+}
+
+// ObserverFunc adapts an ordinary function to the Observer interface.
+type ObserverFunc func(ctx context.Context, ev Event)
+
+// Observe calls f(ctx, ev).
+func (f ObserverFunc) Observe(ctx context.Context, ev Event) {
+	f(ctx, ev)
+}
+
+// WithObserver registers an Observer for middleware events.
+func WithObserver(o Observer) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.observer = o
+	}
+}
+
+func (m *CacheMiddleware) observe(ctx context.Context, ev Event) {
+	if m.observer != nil {
+		ev.Middleware = m.name
+		m.observer.Observe(ctx, ev)
+	}
+}
+
+// WithName labels the middleware. The name is attached to every observer
+// Event and to Stats, so dashboards can tell several instances apart.
+func WithName(name string) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.name = name
+	}
+}
+
+// Name returns the name set with WithName, or "".
+func (m *CacheMiddleware) Name() string {
+	return m.name
+}
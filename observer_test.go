@@ -0,0 +1,70 @@
+package toolcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// recordingObserver captures events for assertions.
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (o *recordingObserver) Observe(_ context.Context, ev Event) {
+	o.mu.Lock()
+	o.events = append(o.events, ev)
+	o.mu.Unlock()
+}
+
+func (o *recordingObserver) byKind(kind EventKind) []Event {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var out []Event
+	for _, ev := range o.events {
+		if ev.Kind == kind {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func TestObserverFunc(t *testing.T) {
+	var got Event
+	obs := ObserverFunc(func(_ context.Context, ev Event) { got = ev })
+	obs.Observe(context.Background(), Event{Kind: EventUnsafeOverride, ToolID: "t"})
+	if got.Kind != EventUnsafeOverride || got.ToolID != "t" {
+		t.Errorf("ObserverFunc received %+v", got)
+	}
+	if EventUnsafeOverride.String() != "unsafe_override" {
+		t.Errorf("String() = %q", EventUnsafeOverride.String())
+	}
+}
+
+func TestWithName_LabelsEventsAndStats(t *testing.T) {
+	obs := &recordingObserver{}
+	mwA := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithName("search"), WithObserver(obs))
+	mwB := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithName("fetch"), WithObserver(obs))
+
+	exec := &mockExecutor{result: []byte("ok")}
+	_, _ = mwA.Execute(context.Background(), "t", 1, nil, exec.execute)
+	_, _ = mwB.Execute(context.Background(), "t", 1, nil, exec.execute)
+
+	keyed := obs.byKind(EventKeyed)
+	if len(keyed) != 2 {
+		t.Fatalf("expected 2 keyed events, got %d", len(keyed))
+	}
+	if keyed[0].Middleware != "search" || keyed[1].Middleware != "fetch" {
+		t.Errorf("event names = %q, %q", keyed[0].Middleware, keyed[1].Middleware)
+	}
+
+	if got := mwA.Stats().Name; got != "search" {
+		t.Errorf("Stats().Name = %q, want search", got)
+	}
+	if got := mwB.Name(); got != "fetch" {
+		t.Errorf("Name() = %q, want fetch", got)
+	}
+}
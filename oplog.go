@@ -0,0 +1,109 @@
+package toolcache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// OpResult classifies a recorded cache operation.
+type OpResult int
+
+const (
+	// OpHit means the result (or a cached error) was served from the cache.
+	OpHit OpResult = iota
+
+	// OpMiss means the executor ran for a cacheable call.
+	OpMiss
+
+	// OpSet means a result was written to the cache.
+	OpSet
+
+	// OpSkip means the call bypassed the cache.
+	OpSkip
+)
+
+func (r OpResult) String() string {
+	switch r {
+	case OpHit:
+		return "hit"
+	case OpMiss:
+		return "miss"
+	case OpSet:
+		return "set"
+	case OpSkip:
+		return "skip"
+	default:
+		return "unknown"
+	}
+}
+
+// OpRecord is one entry in the operation log. Key is empty for skips.
+type OpRecord struct {
+	Result OpResult
+	ToolID string
+	Key    string
+	At     time.Time
+}
+
+// opLog is a fixed-size ring of recent operations. Writers claim a slot
+// with an atomic counter and publish with an atomic store, so recording
+// never takes a lock.
+type opLog struct {
+	next  atomic.Uint64
+	slots []atomic.Pointer[OpRecord]
+}
+
+// WithOpLog records the last n cache operations for debugging; see
+// RecentOps. It is disabled by default. Values below 1 disable it.
+func WithOpLog(n int) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		if n < 1 {
+			m.ops = nil
+			return
+		}
+		m.ops = &opLog{slots: make([]atomic.Pointer[OpRecord], n)}
+	}
+}
+
+func (l *opLog) record(rec OpRecord) {
+	i := l.next.Add(1) - 1
+	l.slots[i%uint64(len(l.slots))].Store(&rec)
+}
+
+// recent returns the logged records, oldest first. Under concurrent
+// writes a slot may already hold a newer record than its position
+// suggests; the log is a debugging aid, not an audit trail.
+func (l *opLog) recent() []OpRecord {
+	n := l.next.Load()
+	size := uint64(len(l.slots))
+	start := uint64(0)
+	if n > size {
+		start = n - size
+	}
+
+	out := make([]OpRecord, 0, n-start)
+	for i := start; i < n; i++ {
+		if rec := l.slots[i%size].Load(); rec != nil {
+			out = append(out, *rec)
+		}
+	}
+	return out
+}
+
+// logOp records an operation in the op log and on the call's span.
+func (m *CacheMiddleware) logOp(ctx context.Context, result OpResult, toolID, key string) {
+	m.traceOp(ctx, result, key)
+	if m.ops != nil {
+		m.ops.record(OpRecord{Result: result, ToolID: toolID, Key: key, At: time.Now()})
+	}
+}
+
+// RecentOps returns up to the last n operations recorded with WithOpLog,
+// oldest first, or nil if the log is disabled.
+func (m *CacheMiddleware) RecentOps() []OpRecord {
+	if m.ops == nil {
+		return nil
+	}
+	return m.ops.recent()
+}
@@ -0,0 +1,92 @@
+package toolcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestOpLog_RecordsOperations(t *testing.T) {
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), keyer, DefaultPolicy(), nil, WithOpLog(10))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "read", 1, nil, executor.execute)                // miss + set
+	_, _ = mw.Execute(ctx, "read", 1, nil, executor.execute)                // hit
+	_, _ = mw.Execute(ctx, "write", 1, []string{"write"}, executor.execute) // skip
+
+	key, _ := keyer.Key("read", 1)
+	want := []OpRecord{
+		{Result: OpMiss, ToolID: "read", Key: key},
+		{Result: OpSet, ToolID: "read", Key: key},
+		{Result: OpHit, ToolID: "read", Key: key},
+		{Result: OpSkip, ToolID: "write"},
+	}
+	got := mw.RecentOps()
+	if len(got) != len(want) {
+		t.Fatalf("RecentOps() returned %d records, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].At.IsZero() {
+			t.Errorf("record %d has no timestamp", i)
+		}
+		got[i].At = want[i].At
+		if got[i] != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOpLog_Bounded(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil, WithOpLog(3))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	for i := range 5 {
+		_, _ = mw.Execute(ctx, fmt.Sprintf("tool-%d", i), nil, []string{"write"}, executor.execute)
+	}
+
+	got := mw.RecentOps()
+	if len(got) != 3 {
+		t.Fatalf("RecentOps() returned %d records, want 3", len(got))
+	}
+	for i, rec := range got {
+		if want := fmt.Sprintf("tool-%d", i+2); rec.ToolID != want {
+			t.Errorf("record %d tool = %q, want %q (oldest first)", i, rec.ToolID, want)
+		}
+	}
+}
+
+func TestOpLog_DisabledByDefault(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("ok")}
+	_, _ = mw.Execute(context.Background(), "t", nil, nil, executor.execute)
+	if ops := mw.RecentOps(); ops != nil {
+		t.Errorf("RecentOps() = %+v, want nil", ops)
+	}
+}
+
+func TestOpLog_Concurrent(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil, WithOpLog(8))
+	executor := func(context.Context, string, any) ([]byte, error) { return []byte("ok"), nil }
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				_, _ = mw.Execute(ctx, "t", g*100+i, nil, executor)
+				_ = mw.RecentOps()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(mw.RecentOps()); got != 8 {
+		t.Errorf("RecentOps() returned %d records, want 8", got)
+	}
+}
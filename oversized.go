@@ -0,0 +1,130 @@
+package toolcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// OversizedKeyMode selects how the middleware handles derived keys longer
+// than MaxKeyLength.
+type OversizedKeyMode int
+
+const (
+	// OversizedKeySkip executes the tool without caching. This is the default.
+	OversizedKeySkip OversizedKeyMode = iota
+
+	// OversizedKeyReject fails the call with an error wrapping ErrKeyTooLong
+	// without running the executor.
+	OversizedKeyReject
+
+	// OversizedKeyShorten replaces the tail of the key with its SHA-256 hash
+	// so it fits in MaxKeyLength, with room left for the suffixes of the
+	// negative-cache and ETag keys derived from it. With a stricter
+	// KeyValidator, the key is shortened further until the validator
+	// accepts it. The leading part of the key is preserved, so prefix-based
+	// lookups by tool keep working.
+	OversizedKeyShorten
+)
+
+// WithOversizedKeyMode sets how keys exceeding MaxKeyLength are handled.
+func WithOversizedKeyMode(mode OversizedKeyMode) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.oversizedKeys = mode
+	}
+}
+
+// shortenedKeyLength is the length of keys shortened by the middleware.
+// It leaves room for the suffixes appended to result keys for negative
+// caching and ETag revalidation, so those keys stay valid too.
+const shortenedKeyLength = MaxKeyLength - max(len(negativeKeySuffix), len(etagKeySuffix))
+
+// ShortenKey returns key unchanged if it fits in MaxKeyLength; otherwise it
+// keeps a prefix of the key and appends '#' and the hex SHA-256 of the full
+// key, producing a deterministic key of exactly MaxKeyLength bytes.
+func ShortenKey(key string) string {
+	return shortenKey(key, MaxKeyLength)
+}
+
+// shortenKey is ShortenKey with a limit other than MaxKeyLength.
+func shortenKey(key string, limit int) string {
+	if len(key) <= limit {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return key[:limit-1-2*sha256.Size] + "#" + hex.EncodeToString(sum[:])
+}
+
+// minShortenedKeyLength is the length of a shortened key that keeps none of
+// the original key: just '#' and the hex SHA-256.
+const minShortenedKeyLength = 1 + 2*sha256.Size
+
+// fitKey returns key if it passes validation. If the key is only too long,
+// it returns the longest shortened form of at most limit bytes that the
+// validator accepts, so a custom KeyValidator stricter than MaxKeyLength
+// still gets usable keys. ok is false if no valid key is found.
+func (m *CacheMiddleware) fitKey(key string, limit int) (string, bool) {
+	err := m.validateKey(key)
+	if err == nil {
+		return key, true
+	}
+	if !errors.Is(err, ErrKeyTooLong) {
+		return "", false
+	}
+
+	hi := min(limit, len(key)-1)
+	if hi < minShortenedKeyLength {
+		return "", false
+	}
+	// The common case: the validator accepts anything within limit.
+	if short := shortenKey(key, hi); m.validateKey(short) == nil {
+		return short, true
+	}
+	best, lo := "", minShortenedKeyLength
+	hi--
+	for lo <= hi {
+		n := lo + (hi-lo)/2
+		short := shortenKey(key, n)
+		switch err := m.validateKey(short); {
+		case err == nil:
+			best, lo = short, n+1
+		case errors.Is(err, ErrKeyTooLong):
+			hi = n - 1
+		default:
+			return "", false
+		}
+	}
+	return best, best != ""
+}
+
+// suffixKey returns the key under which data derived from result key is
+// stored, such as a cached error or a copy kept for revalidation. A key too
+// long to take the suffix is shortened as by fitKey, so the derived key is
+// valid whenever one can be, whatever the oversized key mode. ok is false
+// if the validator rejects every form of it.
+func (m *CacheMiddleware) suffixKey(key, suffix string) (string, bool) {
+	return m.fitKey(key+suffix, MaxKeyLength)
+}
+
+// checkKey validates a derived key. It returns the key to use, "" to run
+// uncached, or an error to fail the call.
+func (m *CacheMiddleware) checkKey(key string) (string, error) {
+	err := m.validateKey(key)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, ErrKeyTooLong) {
+		return "", nil
+	}
+
+	switch m.oversizedKeys {
+	case OversizedKeyReject:
+		return "", fmt.Errorf("toolcache: derived key of %d bytes: %w", len(key), err)
+	case OversizedKeyShorten:
+		short, _ := m.fitKey(key, shortenedKeyLength)
+		return short, nil
+	default:
+		return "", nil
+	}
+}
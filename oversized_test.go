@@ -0,0 +1,164 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// longKeyer produces keys longer than MaxKeyLength.
+var longKeyer = KeyerFunc(func(toolID string, input any) (string, error) {
+	suffix, _ := input.(string)
+	return "toolcache:" + toolID + ":" + strings.Repeat("x", MaxKeyLength) + suffix, nil
+})
+
+func TestOversizedKey_SkipByDefault(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(cache, longKeyer, DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := mw.Execute(ctx, "tool", "a", nil, executor.execute)
+		if err != nil || string(result) != "ok" {
+			t.Fatalf("call %d: result=%q err=%v", i, result, err)
+		}
+	}
+	if executor.calls != 2 {
+		t.Errorf("oversized keys should skip caching, got %d calls", executor.calls)
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("nothing should be cached, got %d entries", len(cache.entries))
+	}
+}
+
+func TestOversizedKey_Reject(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), longKeyer, DefaultPolicy(), nil,
+		WithOversizedKeyMode(OversizedKeyReject))
+	executor := &mockExecutor{result: []byte("ok")}
+
+	_, err := mw.Execute(context.Background(), "tool", "a", nil, executor.execute)
+	if !errors.Is(err, ErrKeyTooLong) {
+		t.Errorf("error = %v, want ErrKeyTooLong", err)
+	}
+	if executor.calls != 0 {
+		t.Errorf("executor should not run when rejecting, got %d calls", executor.calls)
+	}
+}
+
+func TestOversizedKey_Shorten(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(cache, longKeyer, DefaultPolicy(), nil,
+		WithOversizedKeyMode(OversizedKeyShorten))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", "a", nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", "a", nil, executor.execute)
+	if executor.calls != 1 {
+		t.Errorf("shortened key should cache, got %d calls", executor.calls)
+	}
+
+	// Inputs differing only past the kept prefix still get distinct keys.
+	_, _ = mw.Execute(ctx, "tool", "b", nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("distinct long keys should not collide, got %d calls", executor.calls)
+	}
+
+	for key := range cache.entries {
+		if len(key) != shortenedKeyLength {
+			t.Errorf("stored key length = %d, want %d", len(key), shortenedKeyLength)
+		}
+		if !strings.HasPrefix(key, "toolcache:tool:") {
+			t.Errorf("shortened key should keep its prefix: %q", key[:32])
+		}
+	}
+}
+
+func TestShortenKey(t *testing.T) {
+	short := "toolcache:tool:abc"
+	if got := ShortenKey(short); got != short {
+		t.Errorf("ShortenKey should not change short keys, got %q", got)
+	}
+
+	long := strings.Repeat("k", MaxKeyLength+100)
+	got := ShortenKey(long)
+	if len(got) != MaxKeyLength {
+		t.Errorf("len = %d, want %d", len(got), MaxKeyLength)
+	}
+	if ShortenKey(long) != got {
+		t.Error("ShortenKey must be deterministic")
+	}
+	if err := ValidateKey(got); err != nil {
+		t.Errorf("shortened key invalid: %v", err)
+	}
+}
+
+func TestOversizedKey_WarmAll(t *testing.T) {
+	ctx := context.Background()
+
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), longKeyer, DefaultPolicy(), nil,
+		WithOversizedKeyMode(OversizedKeyShorten))
+	warm := &mockExecutor{result: []byte("ok")}
+	results := mw.WarmAll(ctx, []WarmRequest{{ToolID: "tool", Input: "a"}}, warm.execute)
+	if results[0].Status != WarmStored || len(results[0].Key) != shortenedKeyLength {
+		t.Fatalf("Shorten: WarmAll() = %+v, want stored under the shortened key", results[0])
+	}
+	executor := &mockExecutor{result: []byte("ok")}
+	_, _ = mw.Execute(ctx, "tool", "a", nil, executor.execute)
+	if executor.calls != 0 {
+		t.Error("Execute should hit the entry stored by WarmAll")
+	}
+
+	mw = NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), longKeyer, DefaultPolicy(), nil,
+		WithOversizedKeyMode(OversizedKeyReject))
+	warm = &mockExecutor{result: []byte("ok")}
+	results = mw.WarmAll(ctx, []WarmRequest{{ToolID: "tool", Input: "a"}}, warm.execute)
+	if results[0].Status != WarmSkipped || !errors.Is(results[0].Err, ErrKeyTooLong) {
+		t.Errorf("Reject: WarmAll() = %+v, want skipped with ErrKeyTooLong", results[0])
+	}
+	if warm.calls != 0 {
+		t.Errorf("Reject: executor ran %d times during warm-up", warm.calls)
+	}
+}
+
+func TestOversizedKey_ShortenWithNegativeCaching(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), longKeyer, negativePolicy(), nil,
+		WithOversizedKeyMode(OversizedKeyShorten), WithCacheableError(isPermanent))
+	executor := &mockExecutor{err: errValidation}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := mw.Execute(ctx, "tool", "a", nil, executor.execute); err == nil {
+			t.Fatalf("call %d: expected an error", i)
+		}
+	}
+	if executor.calls != 1 {
+		t.Errorf("errors for shortened keys should be negatively cached, got %d calls", executor.calls)
+	}
+}
+
+func TestOversizedKey_BoundaryWithNegativeCaching(t *testing.T) {
+	// A key just under MaxKeyLength is valid, but appending the suffix
+	// of its negative-cache key would push that key past the limit.
+	boundary := KeyerFunc(func(toolID string, _ any) (string, error) {
+		prefix := "toolcache:" + toolID + ":"
+		return prefix + strings.Repeat("x", MaxKeyLength-2-len(prefix)), nil
+	})
+	for _, mode := range []OversizedKeyMode{OversizedKeySkip, OversizedKeyReject, OversizedKeyShorten} {
+		mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), boundary, negativePolicy(), nil,
+			WithOversizedKeyMode(mode), WithCacheableError(isPermanent))
+		executor := &mockExecutor{err: errValidation}
+		ctx := context.Background()
+
+		for i := 0; i < 2; i++ {
+			if _, err := mw.Execute(ctx, "tool", nil, nil, executor.execute); !errors.Is(err, ErrCachedError) && !errors.Is(err, errValidation) {
+				t.Fatalf("mode %d, call %d: err = %v", mode, i, err)
+			}
+		}
+		if executor.calls != 1 {
+			t.Errorf("mode %d: errors for boundary-length keys should be negatively cached, got %d calls", mode, executor.calls)
+		}
+	}
+}
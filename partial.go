@@ -0,0 +1,45 @@
+package toolcache
+
+import (
+	"context"
+	"time"
+)
+
+// WithPartialResultTTL caches partial results: when an executor returns a
+// non-empty value together with an error (e.g. a timeout with partial
+// data), the value is stored for ttl, capped by the policy TTL, and both the
+// value and the error are returned to the caller. Later calls within ttl
+// are served the partial value as an ordinary hit. The error itself is not
+// negatively cached in that case. Partial values go through
+// WithResultTransform and WithMaxResultBytes like complete results; one
+// that is not stored is discarded.
+//
+// By default (ttl <= 0) such values are discarded and only the error is
+// returned.
+func WithPartialResultTTL(ttl time.Duration) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.partialTTL = ttl
+	}
+}
+
+// cachePartial stores a partial result if enabled, applying
+// WithResultTransform and WithMaxResultBytes as for complete results. It
+// returns the bytes stored and the bytes to return, and reports whether
+// the value was stored.
+func (m *CacheMiddleware) cachePartial(ctx context.Context, toolID, key string, value []byte, info *ExecInfo) (stored, returned []byte, ok bool) {
+	if m.partialTTL <= 0 || len(value) == 0 {
+		return nil, nil, false
+	}
+	ttl := min(m.partialTTL, m.policy.EffectiveTTL(0))
+	if ttl <= 0 || m.deadlineTooShort(ctx) {
+		return nil, nil, false
+	}
+	stored, returned, err := m.transformResult(toolID, value)
+	if err != nil || m.oversizedResult(toolID, stored) {
+		return nil, nil, false
+	}
+	if m.store(ctx, toolID, key, stored, ttl, info) != nil {
+		return nil, nil, false
+	}
+	return stored, returned, true
+}
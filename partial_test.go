@@ -0,0 +1,90 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errPartial = errors.New("timed out after first page")
+
+func TestPartialResult_DiscardedByDefault(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("page-1"), err: errPartial}
+	ctx := context.Background()
+
+	got, err := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if !errors.Is(err, errPartial) || got != nil {
+		t.Fatalf("Execute() = %q, %v; want nil, errPartial", got, err)
+	}
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("partial value should not be cached, got %d calls", executor.calls)
+	}
+}
+
+func TestPartialResult_Cached(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), negativePolicy(), nil,
+		WithPartialResultTTL(time.Minute))
+	executor := &mockExecutor{result: []byte("page-1"), err: errPartial}
+	ctx := context.Background()
+
+	got, err := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if !errors.Is(err, errPartial) || string(got) != "page-1" {
+		t.Fatalf("Execute() = %q, %v; want page-1, errPartial", got, err)
+	}
+
+	got, err = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if err != nil || string(got) != "page-1" {
+		t.Errorf("second call = %q, %v; want cached partial value", got, err)
+	}
+	if executor.calls != 1 {
+		t.Errorf("expected 1 executor call, got %d", executor.calls)
+	}
+}
+
+func TestPartialResult_ShortTTL(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithPartialResultTTL(time.Millisecond))
+	executor := &mockExecutor{result: []byte("page-1"), err: errPartial}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	time.Sleep(5 * time.Millisecond)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("partial value should expire after its TTL, got %d calls", executor.calls)
+	}
+}
+
+func TestPartialResult_EmptyValueNotCached(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithPartialResultTTL(time.Minute))
+	executor := &mockExecutor{err: errPartial}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("errors without a value should not be cached, got %d calls", executor.calls)
+	}
+}
+
+func TestPartialResult_Transformed(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	upper := func(_ string, result []byte) ([]byte, error) { return bytes.ToUpper(result), nil }
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithPartialResultTTL(time.Minute), WithResultTransform(upper, TransformReturn))
+	executor := &mockExecutor{result: []byte("page-1"), err: errPartial}
+	ctx := context.Background()
+
+	got, err := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if !errors.Is(err, errPartial) || string(got) != "PAGE-1" {
+		t.Fatalf("Execute() = %q, %v; want the transformed partial value", got, err)
+	}
+	if got, _ := mw.Execute(ctx, "tool", 1, nil, executor.execute); string(got) != "PAGE-1" {
+		t.Errorf("cached partial value = %q, want PAGE-1", got)
+	}
+}
@@ -0,0 +1,212 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// partitionLockStripes is the number of locks PartitionedCache spreads
+// keys over to serialize writes to the same key.
+const partitionLockStripes = 64
+
+// TTLClass is one partition of a PartitionedCache.
+type TTLClass struct {
+	// Name identifies the class in PartitionedStats, e.g. "short".
+	Name string
+
+	// MaxTTL is the largest TTL routed to this class. Zero means no upper
+	// bound; only the longest-lived class may use it. TTLs above every
+	// bound go to the class with the largest MaxTTL.
+	MaxTTL time.Duration
+
+	// Cache stores the class's entries, with its own limits and eviction.
+	Cache Cache
+}
+
+// PartitionStats holds counters for one TTL class.
+type PartitionStats struct {
+	// Hits counts Gets served by the class.
+	Hits uint64
+
+	// Sets counts successful Sets routed to the class.
+	Sets uint64
+}
+
+// PartitionedStats reports PartitionedCache counters.
+type PartitionedStats struct {
+	// PartitionStats aggregates all classes.
+	PartitionStats
+
+	// Misses counts Gets found in no class.
+	Misses uint64
+
+	// Classes holds per-class counters keyed by TTLClass.Name.
+	Classes map[string]PartitionStats
+}
+
+type partition struct {
+	TTLClass
+	hits atomic.Uint64
+	sets atomic.Uint64
+}
+
+// PartitionedCache splits entries into TTL classes, each backed by its own
+// Cache, so churn among short-lived entries cannot evict long-lived ones
+// and expiry work stays within a class. Set routes by TTL to the class
+// with the smallest MaxTTL that fits and removes the key from every other
+// class. Get checks the classes from shortest to longest, so a miss costs
+// one lookup per class. Sets and Deletes of the same key are serialized,
+// so concurrent Sets routed to different classes leave exactly one entry.
+type PartitionedCache struct {
+	parts  []*partition
+	misses atomic.Uint64
+
+	seed  maphash.Seed
+	locks [partitionLockStripes]sync.Mutex
+}
+
+// NewPartitionedCache creates a PartitionedCache over classes, which may be
+// given in any order. Names must be unique and non-empty, caches non-nil,
+// and at most one class may have MaxTTL 0.
+func NewPartitionedCache(classes ...TTLClass) (*PartitionedCache, error) {
+	if len(classes) == 0 {
+		return nil, errors.New("toolcache: partitioned cache needs at least one TTL class")
+	}
+
+	parts := make([]*partition, 0, len(classes))
+	names := make(map[string]bool, len(classes))
+	for _, class := range classes {
+		switch {
+		case class.Name == "":
+			return nil, errors.New("toolcache: TTL class has no name")
+		case names[class.Name]:
+			return nil, fmt.Errorf("toolcache: duplicate TTL class %q", class.Name)
+		case class.Cache == nil:
+			return nil, fmt.Errorf("toolcache: TTL class %q: %w", class.Name, ErrNilCache)
+		case class.MaxTTL < 0:
+			return nil, fmt.Errorf("toolcache: TTL class %q has negative MaxTTL %v", class.Name, class.MaxTTL)
+		}
+		names[class.Name] = true
+		parts = append(parts, &partition{TTLClass: class})
+	}
+
+	sort.SliceStable(parts, func(i, j int) bool {
+		a, b := parts[i].MaxTTL, parts[j].MaxTTL
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i].MaxTTL == 0 {
+			return nil, errors.New("toolcache: only one TTL class may be unbounded")
+		}
+		if parts[i].MaxTTL == parts[i+1].MaxTTL {
+			return nil, fmt.Errorf("toolcache: TTL classes %q and %q share MaxTTL %v",
+				parts[i].Name, parts[i+1].Name, parts[i].MaxTTL)
+		}
+	}
+
+	return &PartitionedCache{parts: parts, seed: maphash.MakeSeed()}, nil
+}
+
+// lock serializes writes to key, returning the unlock function.
+func (c *PartitionedCache) lock(key string) func() {
+	mu := &c.locks[maphash.String(c.seed, key)%partitionLockStripes]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// route returns the class for an entry with the given TTL.
+func (c *PartitionedCache) route(ttl time.Duration) *partition {
+	for _, p := range c.parts {
+		if p.MaxTTL == 0 || ttl <= p.MaxTTL {
+			return p
+		}
+	}
+	return c.parts[len(c.parts)-1]
+}
+
+func (c *PartitionedCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	for _, p := range c.parts {
+		if value, ok := p.Cache.Get(ctx, key); ok {
+			p.hits.Add(1)
+			return value, true
+		}
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+// Set stores value in the class for ttl and removes key from the others,
+// so an entry whose TTL changes class is never served stale.
+func (c *PartitionedCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	defer c.lock(key)()
+
+	target := c.route(ttl)
+	if err := target.Cache.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	target.sets.Add(1)
+
+	var errs []error
+	for _, p := range c.parts {
+		if p != target {
+			if err := p.Cache.Delete(ctx, key); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Delete removes key from every class.
+func (c *PartitionedCache) Delete(ctx context.Context, key string) error {
+	defer c.lock(key)()
+
+	var errs []error
+	for _, p := range c.parts {
+		if err := p.Cache.Delete(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush flushes every class whose cache implements Flushable.
+func (c *PartitionedCache) Flush(ctx context.Context) error {
+	caches := make([]Cache, len(c.parts))
+	for i, p := range c.parts {
+		caches[i] = p.Cache
+	}
+	return FlushAll(ctx, caches...)
+}
+
+// Stats returns per-class and aggregate counters.
+func (c *PartitionedCache) Stats() PartitionedStats {
+	stats := PartitionedStats{
+		Misses:  c.misses.Load(),
+		Classes: make(map[string]PartitionStats, len(c.parts)),
+	}
+	for _, p := range c.parts {
+		ps := PartitionStats{Hits: p.hits.Load(), Sets: p.sets.Load()}
+		stats.Classes[p.Name] = ps
+		stats.Hits += ps.Hits
+		stats.Sets += ps.Sets
+	}
+	return stats
+}
+
+var (
+	_ Cache     = (*PartitionedCache)(nil)
+	_ Flushable = (*PartitionedCache)(nil)
+)
@@ -0,0 +1,185 @@
+package toolcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fifoCache is a MemoryCache that evicts its oldest key beyond capacity.
+type fifoCache struct {
+	*MemoryCache
+	capacity int
+	order    []string
+}
+
+func newFIFOCache(capacity int) *fifoCache {
+	return &fifoCache{MemoryCache: NewMemoryCache(DefaultPolicy()), capacity: capacity}
+}
+
+func (c *fifoCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.order = append(c.order, key)
+	if len(c.order) > c.capacity {
+		_ = c.MemoryCache.Delete(ctx, c.order[0])
+		c.order = c.order[1:]
+	}
+	return c.MemoryCache.Set(ctx, key, value, ttl)
+}
+
+func TestPartitionedCache_ShortChurnKeepsLongEntries(t *testing.T) {
+	long := NewMemoryCache(DefaultPolicy())
+	cache, err := NewPartitionedCache(
+		TTLClass{Name: "long", Cache: long},
+		TTLClass{Name: "short", MaxTTL: time.Minute, Cache: newFIFOCache(10)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("ref-%d", i), []byte("r"), time.Hour)
+	}
+	for i := 0; i < 1000; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("tmp-%d", i), []byte("t"), 30*time.Second)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, ok := cache.Get(ctx, fmt.Sprintf("ref-%d", i)); !ok {
+			t.Errorf("long-lived ref-%d was evicted by short-lived churn", i)
+		}
+	}
+	if _, ok := cache.Get(ctx, "tmp-0"); ok {
+		t.Error("oldest short-lived entry should have been evicted by its own class")
+	}
+	if _, ok := cache.Get(ctx, "tmp-999"); !ok {
+		t.Error("newest short-lived entry should be present")
+	}
+	if got := len(long.entries); got != 5 {
+		t.Errorf("long class holds %d entries, want 5", got)
+	}
+
+	stats := cache.Stats()
+	if stats.Classes["long"] != (PartitionStats{Hits: 5, Sets: 5}) {
+		t.Errorf("long stats = %+v", stats.Classes["long"])
+	}
+	if stats.Classes["short"] != (PartitionStats{Hits: 1, Sets: 1000}) {
+		t.Errorf("short stats = %+v", stats.Classes["short"])
+	}
+	if stats.Hits != 6 || stats.Sets != 1005 || stats.Misses != 1 {
+		t.Errorf("aggregate stats = %+v", stats)
+	}
+}
+
+func TestPartitionedCache_Routing(t *testing.T) {
+	short, medium, long := NewMemoryCache(DefaultPolicy()), NewMemoryCache(DefaultPolicy()), NewMemoryCache(DefaultPolicy())
+	cache, err := NewPartitionedCache(
+		TTLClass{Name: "medium", MaxTTL: time.Hour, Cache: medium},
+		TTLClass{Name: "short", MaxTTL: time.Minute, Cache: short},
+		TTLClass{Name: "long", MaxTTL: 24 * time.Hour, Cache: long},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		ttl  time.Duration
+		want *MemoryCache
+	}{
+		{time.Second, short},
+		{time.Minute, short},
+		{time.Minute + 1, medium},
+		{48 * time.Hour, long},
+	}
+	for _, tt := range tests {
+		_ = cache.Set(ctx, "k", []byte("v"), tt.ttl)
+		for _, mc := range []*MemoryCache{short, medium, long} {
+			_, ok := mc.entries["k"]
+			if ok != (mc == tt.want) {
+				t.Errorf("ttl %v: entry presence in class = %v", tt.ttl, ok)
+			}
+		}
+	}
+
+	_ = cache.Delete(ctx, "k")
+	if _, ok := cache.Get(ctx, "k"); ok {
+		t.Error("Delete should remove the key from every class")
+	}
+}
+
+// slowSetCache pauses after each Set, widening the window between a
+// PartitionedCache's write to one class and its Deletes from the others.
+type slowSetCache struct {
+	*MemoryCache
+}
+
+func (c slowSetCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := c.MemoryCache.Set(ctx, key, value, ttl)
+	time.Sleep(time.Millisecond)
+	return err
+}
+
+func TestPartitionedCache_ConcurrentSetsAcrossClasses(t *testing.T) {
+	short, long := NewMemoryCache(DefaultPolicy()), NewMemoryCache(DefaultPolicy())
+	cache, err := NewPartitionedCache(
+		TTLClass{Name: "short", MaxTTL: time.Minute, Cache: slowSetCache{short}},
+		TTLClass{Name: "long", Cache: slowSetCache{long}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for i := range 20 {
+		key := fmt.Sprintf("k%d", i)
+		var wg sync.WaitGroup
+		for _, ttl := range []time.Duration{time.Second, time.Hour} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = cache.Set(ctx, key, []byte("v"), ttl)
+			}()
+		}
+		wg.Wait()
+		if short.Len()+long.Len() != i+1 {
+			t.Fatalf("after racing Sets of %s, classes hold %d entries, want %d",
+				key, short.Len()+long.Len(), i+1)
+		}
+	}
+}
+
+func TestPartitionedCache_Flush(t *testing.T) {
+	a, b := NewMemoryCache(DefaultPolicy()), NewMemoryCache(DefaultPolicy())
+	cache, _ := NewPartitionedCache(TTLClass{Name: "a", MaxTTL: time.Minute, Cache: a}, TTLClass{Name: "b", Cache: b})
+	ctx := context.Background()
+	_ = cache.Set(ctx, "x", []byte("1"), time.Second)
+	_ = cache.Set(ctx, "y", []byte("2"), time.Hour)
+
+	if err := cache.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.entries)+len(b.entries) != 0 {
+		t.Error("Flush should empty every class")
+	}
+}
+
+func TestNewPartitionedCache_Invalid(t *testing.T) {
+	mc := NewMemoryCache(DefaultPolicy())
+	tests := map[string][]TTLClass{
+		"none":          nil,
+		"unnamed":       {{Cache: mc}},
+		"duplicate":     {{Name: "a", MaxTTL: time.Minute, Cache: mc}, {Name: "a", Cache: mc}},
+		"nil cache":     {{Name: "a"}},
+		"negative":      {{Name: "a", MaxTTL: -time.Second, Cache: mc}},
+		"two unbounded": {{Name: "a", Cache: mc}, {Name: "b", Cache: mc}},
+		"same bound":    {{Name: "a", MaxTTL: time.Minute, Cache: mc}, {Name: "b", MaxTTL: time.Minute, Cache: mc}},
+	}
+	for name, classes := range tests {
+		if _, err := NewPartitionedCache(classes...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package toolcache
+
+import (
+	"context"
+	"time"
+)
+
+// Pin exempts the entry under key from TTL expiry and from EvictFraction
+// until Unpin, for entries that must always be present, such as
+// configuration or capability manifests. The pin belongs to the key: it
+// covers later overwrites and may be set before the entry is stored.
+// Explicit removal with Delete, DeleteTree or Flush still removes pinned
+// entries, leaving the pin in place.
+//
+// Pinned entries stay in Snapshot, KeysWithPrefix, LargestEntries and
+// WriteSnapshot past their TTL. A snapshot keeps the original expiry, so
+// ReadSnapshot drops entries that expired while pinned.
+func (c *MemoryCache) Pin(_ context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.pinned == nil {
+		c.pinned = make(map[string]struct{})
+	}
+	c.pinned[key] = struct{}{}
+	c.mu.Unlock()
+	return nil
+}
+
+// Unpin removes the pin on key. An entry whose TTL elapsed while pinned
+// expires on its next access.
+func (c *MemoryCache) Unpin(_ context.Context, key string) error {
+	c.mu.Lock()
+	delete(c.pinned, key)
+	c.mu.Unlock()
+	return nil
+}
+
+// Pinned reports whether key is pinned.
+func (c *MemoryCache) Pinned(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.pinnedLocked(key)
+}
+
+// pinnedLocked reports whether key is pinned. Callers must hold c.mu.
+func (c *MemoryCache) pinnedLocked(key string) bool {
+	_, ok := c.pinned[key]
+	return ok
+}
+
+// staleLocked reports whether key's entry has expired at now and is not
+// pinned. Callers must hold c.mu.
+func (c *MemoryCache) staleLocked(key string, entry *cacheEntry, now time.Time) bool {
+	return c.expired(entry.expiresAt, now) && !c.pinnedLocked(key)
+}
@@ -0,0 +1,100 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPin_SurvivesTTLAndEviction(t *testing.T) {
+	for name, opts := range map[string][]MemoryCacheOption{
+		"default": nil,
+		"pooled":  {WithEntryPooling()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			clock := NewManualClock(time.Unix(0, 0))
+			c := NewMemoryCacheWithOptions(DefaultPolicy(), append(opts, WithClock(clock))...)
+			ctx := context.Background()
+
+			_ = c.Set(ctx, "manifest", []byte("caps"), time.Minute)
+			_ = c.Set(ctx, "other", []byte("x"), time.Minute)
+			if err := c.Pin(ctx, "manifest"); err != nil {
+				t.Fatal(err)
+			}
+
+			if n := c.EvictFraction(1); n != 1 {
+				t.Errorf("EvictFraction(1) = %d, want only the unpinned entry", n)
+			}
+			clock.Advance(time.Hour)
+			if got, ok := c.Get(ctx, "manifest"); !ok || string(got) != "caps" {
+				t.Fatalf("pinned entry lost: %q, %v", got, ok)
+			}
+
+			// Still reported in stats and byte accounting.
+			infos, _ := c.Snapshot(ctx)
+			if len(infos) != 1 || infos[0].Key != "manifest" || infos[0].Size != 4 {
+				t.Errorf("Snapshot() = %+v", infos)
+			}
+			if largest, _ := c.LargestEntries(ctx, 5); len(largest) != 1 || largest[0].Size != 4 {
+				t.Errorf("LargestEntries() = %+v", largest)
+			}
+
+			if err := c.Unpin(ctx, "manifest"); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := c.Get(ctx, "manifest"); ok {
+				t.Error("entry should expire once unpinned")
+			}
+		})
+	}
+}
+
+func TestPin_CollectibleByEvictionAfterUnpin(t *testing.T) {
+	c := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "k", []byte("v"), time.Minute)
+	_ = c.Pin(ctx, "k")
+	if n := c.EvictFraction(1); n != 0 {
+		t.Fatalf("EvictFraction removed %d pinned entries", n)
+	}
+	_ = c.Unpin(ctx, "k")
+	if n := c.EvictFraction(1); n != 1 {
+		t.Errorf("EvictFraction(1) = %d after Unpin, want 1", n)
+	}
+}
+
+func TestPin_BelongsToKey(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(clock))
+	ctx := context.Background()
+
+	// Pinning before the entry exists covers it once stored.
+	_ = c.Pin(ctx, "config")
+	if !c.Pinned("config") || c.Pinned("other") {
+		t.Error("Pinned() should report only pinned keys")
+	}
+	_ = c.Set(ctx, "config", []byte("v1"), time.Second)
+	_ = c.Set(ctx, "config", []byte("v2"), time.Second)
+	clock.Advance(time.Minute)
+	if got, ok := c.Get(ctx, "config"); !ok || string(got) != "v2" {
+		t.Errorf("overwritten pinned entry = %q, %v", got, ok)
+	}
+
+	// Explicit deletion still wins, but the pin remains.
+	_ = c.Delete(ctx, "config")
+	if _, ok := c.Get(ctx, "config"); ok {
+		t.Error("Delete should remove a pinned entry")
+	}
+	if !c.Pinned("config") {
+		t.Error("Delete should not remove the pin")
+	}
+}
+
+func TestPin_InvalidKey(t *testing.T) {
+	c := NewMemoryCache(DefaultPolicy())
+	if err := c.Pin(context.Background(), ""); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Pin(\"\") = %v, want ErrInvalidKey", err)
+	}
+}
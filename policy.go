@@ -1,6 +1,10 @@
 package toolcache
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // Policy defines caching behavior including TTL defaults and limits.
 type Policy struct {
@@ -15,6 +19,16 @@ type Policy struct {
 	// AllowUnsafe permits caching of results from tools marked as unsafe.
 	// Default is false.
 	AllowUnsafe bool
+
+	// NegativeTTL is the TTL used when caching executor errors. It is
+	// clamped to MaxTTL. A value of 0 disables negative caching.
+	NegativeTTL time.Duration
+
+	// EmptyResultTTL is the TTL used instead of DefaultTTL when an executor
+	// returns a zero-length value, since "not found" answers are usually
+	// cheaper and more volatile than full results. It is clamped to MaxTTL.
+	// A value of 0 disables caching empty results.
+	EmptyResultTTL time.Duration
 }
 
 // EffectiveTTL computes the TTL to use given an optional override.
@@ -43,6 +57,32 @@ func (p Policy) EffectiveTTL(override time.Duration) time.Duration {
 	return ttl
 }
 
+// EffectiveNegativeTTL computes the TTL for cached errors: NegativeTTL
+// clamped to MaxTTL, or 0 when negative caching is disabled.
+func (p Policy) EffectiveNegativeTTL() time.Duration {
+	ttl := p.NegativeTTL
+	if p.MaxTTL > 0 && ttl > p.MaxTTL {
+		ttl = p.MaxTTL
+	}
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// EffectiveEmptyResultTTL computes the TTL for empty results:
+// EmptyResultTTL clamped to MaxTTL, or 0 when they are not cached.
+func (p Policy) EffectiveEmptyResultTTL() time.Duration {
+	ttl := p.EmptyResultTTL
+	if p.MaxTTL > 0 && ttl > p.MaxTTL {
+		ttl = p.MaxTTL
+	}
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
 // ShouldCache reports whether caching is enabled by default.
 // Returns true if DefaultTTL > 0.
 func (p Policy) ShouldCache() bool {
@@ -53,11 +93,13 @@ func (p Policy) ShouldCache() bool {
 //   - DefaultTTL: 5 minutes
 //   - MaxTTL: 1 hour
 //   - AllowUnsafe: false
+//   - EmptyResultTTL: 5 minutes
 func DefaultPolicy() Policy {
 	return Policy{
-		DefaultTTL:  5 * time.Minute,
-		MaxTTL:      1 * time.Hour,
-		AllowUnsafe: false,
+		DefaultTTL:     5 * time.Minute,
+		MaxTTL:         1 * time.Hour,
+		AllowUnsafe:    false,
+		EmptyResultTTL: 5 * time.Minute,
 	}
 }
 
@@ -69,3 +111,94 @@ func NoCachePolicy() Policy {
 		AllowUnsafe: false,
 	}
 }
+
+// Validate reports whether the policy is internally consistent: no negative
+// TTLs, and DefaultTTL, NegativeTTL and EmptyResultTTL within MaxTTL when
+// it is set.
+func (p Policy) Validate() error {
+	switch {
+	case p.DefaultTTL < 0:
+		return fmt.Errorf("%w: negative DefaultTTL %v", ErrInvalidPolicy, p.DefaultTTL)
+	case p.MaxTTL < 0:
+		return fmt.Errorf("%w: negative MaxTTL %v", ErrInvalidPolicy, p.MaxTTL)
+	case p.NegativeTTL < 0:
+		return fmt.Errorf("%w: negative NegativeTTL %v", ErrInvalidPolicy, p.NegativeTTL)
+	case p.EmptyResultTTL < 0:
+		return fmt.Errorf("%w: negative EmptyResultTTL %v", ErrInvalidPolicy, p.EmptyResultTTL)
+	case p.MaxTTL > 0 && p.DefaultTTL > p.MaxTTL:
+		return fmt.Errorf("%w: DefaultTTL %v exceeds MaxTTL %v", ErrInvalidPolicy, p.DefaultTTL, p.MaxTTL)
+	case p.MaxTTL > 0 && p.NegativeTTL > p.MaxTTL:
+		return fmt.Errorf("%w: NegativeTTL %v exceeds MaxTTL %v", ErrInvalidPolicy, p.NegativeTTL, p.MaxTTL)
+	case p.MaxTTL > 0 && p.EmptyResultTTL > p.MaxTTL:
+		return fmt.Errorf("%w: EmptyResultTTL %v exceeds MaxTTL %v", ErrInvalidPolicy, p.EmptyResultTTL, p.MaxTTL)
+	}
+	return nil
+}
+
+// policyJSON is the wire form of Policy, with durations as strings such as
+// "5m" or "1h30m".
+type policyJSON struct {
+	DefaultTTL     string `json:"default_ttl,omitempty"`
+	MaxTTL         string `json:"max_ttl,omitempty"`
+	AllowUnsafe    bool   `json:"allow_unsafe,omitempty"`
+	NegativeTTL    string `json:"negative_ttl,omitempty"`
+	EmptyResultTTL string `json:"empty_result_ttl,omitempty"`
+}
+
+// MarshalJSON encodes the policy with durations as strings, e.g.
+// {"default_ttl":"5m0s","max_ttl":"1h0m0s"}. Zero durations are omitted.
+func (p Policy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(policyJSON{
+		DefaultTTL:     formatDuration(p.DefaultTTL),
+		MaxTTL:         formatDuration(p.MaxTTL),
+		AllowUnsafe:    p.AllowUnsafe,
+		NegativeTTL:    formatDuration(p.NegativeTTL),
+		EmptyResultTTL: formatDuration(p.EmptyResultTTL),
+	})
+}
+
+// UnmarshalJSON decodes a policy written by MarshalJSON or by hand.
+// Durations use time.ParseDuration syntax; missing fields are zero. The
+// result must pass Validate.
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var raw policyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var decoded Policy
+	fields := []struct {
+		name string
+		src  string
+		dst  *time.Duration
+	}{
+		{"default_ttl", raw.DefaultTTL, &decoded.DefaultTTL},
+		{"max_ttl", raw.MaxTTL, &decoded.MaxTTL},
+		{"negative_ttl", raw.NegativeTTL, &decoded.NegativeTTL},
+		{"empty_result_ttl", raw.EmptyResultTTL, &decoded.EmptyResultTTL},
+	}
+	for _, f := range fields {
+		if f.src == "" {
+			continue
+		}
+		d, err := time.ParseDuration(f.src)
+		if err != nil {
+			return fmt.Errorf("toolcache: policy %s: %w", f.name, err)
+		}
+		*f.dst = d
+	}
+	decoded.AllowUnsafe = raw.AllowUnsafe
+
+	if err := decoded.Validate(); err != nil {
+		return err
+	}
+	*p = decoded
+	return nil
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
@@ -1,6 +1,9 @@
 package toolcache
 
 import (
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -81,6 +84,9 @@ func TestPolicy_DefaultPolicy(t *testing.T) {
 	if p.AllowUnsafe {
 		t.Error("DefaultPolicy().AllowUnsafe = true, want false")
 	}
+	if p.EmptyResultTTL != 5*time.Minute {
+		t.Errorf("DefaultPolicy().EmptyResultTTL = %v, want %v", p.EmptyResultTTL, 5*time.Minute)
+	}
 }
 
 func TestPolicy_NoCachePolicy(t *testing.T) {
@@ -211,3 +217,124 @@ func TestPolicy_ShouldCache(t *testing.T) {
 		})
 	}
 }
+
+func TestPolicy_EffectiveNegativeTTL(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy Policy
+		want   time.Duration
+	}{
+		{"disabled", Policy{DefaultTTL: time.Minute}, 0},
+		{"enabled", Policy{NegativeTTL: 10 * time.Second}, 10 * time.Second},
+		{"clamped", Policy{NegativeTTL: time.Hour, MaxTTL: time.Minute}, time.Minute},
+		{"negative", Policy{NegativeTTL: -time.Second}, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.policy.EffectiveNegativeTTL(); got != tc.want {
+				t.Errorf("EffectiveNegativeTTL() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_EffectiveEmptyResultTTL(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy Policy
+		want   time.Duration
+	}{
+		{"disabled", Policy{DefaultTTL: time.Minute}, 0},
+		{"enabled", Policy{EmptyResultTTL: 10 * time.Second}, 10 * time.Second},
+		{"clamped", Policy{EmptyResultTTL: time.Hour, MaxTTL: time.Minute}, time.Minute},
+		{"negative", Policy{EmptyResultTTL: -time.Second}, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.policy.EffectiveEmptyResultTTL(); got != tc.want {
+				t.Errorf("EffectiveEmptyResultTTL() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{"default", DefaultPolicy(), false},
+		{"zero", Policy{}, false},
+		{"no max", Policy{DefaultTTL: 24 * time.Hour}, false},
+		{"negative default", Policy{DefaultTTL: -time.Second}, true},
+		{"negative max", Policy{MaxTTL: -time.Second}, true},
+		{"negative negative ttl", Policy{NegativeTTL: -time.Second}, true},
+		{"default above max", Policy{DefaultTTL: 2 * time.Hour, MaxTTL: time.Hour}, true},
+		{"negative ttl above max", Policy{NegativeTTL: 2 * time.Hour, MaxTTL: time.Hour}, true},
+		{"negative empty result ttl", Policy{EmptyResultTTL: -time.Second}, true},
+		{"empty result ttl above max", Policy{EmptyResultTTL: 2 * time.Hour, MaxTTL: time.Hour}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("Validate() = %v, want ErrInvalidPolicy", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_JSONRoundTrip(t *testing.T) {
+	want := Policy{
+		DefaultTTL:     5 * time.Minute,
+		MaxTTL:         time.Hour,
+		AllowUnsafe:    true,
+		NegativeTTL:    30 * time.Second,
+		EmptyResultTTL: 10 * time.Second,
+	}
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"default_ttl":"5m0s"`) {
+		t.Errorf("durations should be encoded as strings: %s", data)
+	}
+
+	var got Policy
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestPolicy_UnmarshalJSON(t *testing.T) {
+	var p Policy
+	if err := json.Unmarshal([]byte(`{"default_ttl":"5m","max_ttl":"1h"}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != (Policy{DefaultTTL: 5 * time.Minute, MaxTTL: time.Hour}) {
+		t.Errorf("decoded %+v", p)
+	}
+
+	for name, doc := range map[string]string{
+		"bad duration": `{"default_ttl":"five minutes"}`,
+		"number":       `{"default_ttl":300}`,
+		"invalid":      `{"default_ttl":"2h","max_ttl":"1h"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			before := p
+			if err := json.Unmarshal([]byte(doc), &p); err == nil {
+				t.Errorf("expected error for %s", doc)
+			}
+			if p != before {
+				t.Errorf("failed unmarshal modified the policy: %+v", p)
+			}
+		})
+	}
+}
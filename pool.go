@@ -0,0 +1,118 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// maxPooledValueSize is the largest value buffer kept for reuse; larger
+// buffers are left to the garbage collector so one big value cannot pin
+// memory in the pool.
+const maxPooledValueSize = 64 << 10
+
+var (
+	entryPool = sync.Pool{New: func() any { return new(cacheEntry) }}
+	valuePool = sync.Pool{New: func() any { return new([]byte) }}
+)
+
+// WithEntryPooling makes Set reuse entry structs and value buffers from
+// entries removed by Delete, expiry, overwrites and EvictFraction, cutting
+// allocations and GC pressure for high-churn workloads.
+//
+// Reads copy values while holding the cache lock, so a buffer is never
+// recycled while a caller can still see it: Get returns a copy and
+// GetString a string that does not share the buffer. Pooling has no
+// effect with WithZeroCopy, whose callers hold the cached slices, and
+// entries stored by SetImmutable, SetThunk or SetWithDeps are never
+// pooled.
+func WithEntryPooling() MemoryCacheOption {
+	return func(c *MemoryCache) {
+		c.pooling = true
+	}
+}
+
+// pools reports whether Set uses pooled entries.
+func (c *MemoryCache) pools() bool {
+	return c.pooling && !c.zeroCopy
+}
+
+// newPooledEntry returns an entry holding a copy of value in a reused
+// buffer.
+func newPooledEntry(value []byte, meta EntryMeta, expiresAt time.Time) *cacheEntry {
+	entry := entryPool.Get().(*cacheEntry)
+	buf := valuePool.Get().(*[]byte)
+	stored := append((*buf)[:0], value...)
+	if stored == nil && value != nil {
+		stored = []byte{}
+	}
+	*entry = cacheEntry{value: stored, meta: meta, expiresAt: expiresAt, buf: buf}
+	return entry
+}
+
+// releaseLocked returns a pooled entry removed from the map to the pools.
+// Other entries are left to the garbage collector. Callers must hold c.mu
+// for writing.
+func (c *MemoryCache) releaseLocked(entry *cacheEntry) {
+	if entry == nil || entry.buf == nil {
+		return
+	}
+	if cap(entry.value) <= maxPooledValueSize {
+		*entry.buf = entry.value[:0]
+		valuePool.Put(entry.buf)
+	}
+	*entry = cacheEntry{}
+	entryPool.Put(entry)
+}
+
+// getPooled is GetWithMeta for caches with pooling enabled. Unlike
+// lookup, it inspects and copies the entry under the read lock, since a
+// pooled entry may be recycled as soon as the lock is released.
+func (c *MemoryCache) getPooled(ctx context.Context, key string) ([]byte, EntryMeta, bool) {
+	now := c.clock.Now()
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	if !ok {
+		c.mu.RUnlock()
+		return nil, EntryMeta{}, false
+	}
+	if c.staleLocked(key, entry, now) {
+		c.mu.RUnlock()
+		c.removeExpired(key, now)
+		return nil, EntryMeta{}, false
+	}
+	if entry.thunk != nil {
+		c.mu.RUnlock()
+		// Thunk entries are never pooled, so entry stays valid.
+		materialized, ok := c.materialize(ctx, key, entry)
+		if !ok {
+			return nil, EntryMeta{}, false
+		}
+		c.touch(materialized, now)
+		return bytes.Clone(materialized.value), materialized.meta, true
+	}
+
+	c.promoteRLocked(entry)
+	c.touch(entry, now)
+	value, meta := entry.value, entry.meta
+	if !entry.immutable {
+		value = bytes.Clone(value)
+	}
+	c.mu.RUnlock()
+	return value, meta, true
+}
+
+// removeExpired deletes key if its current entry has expired at now.
+func (c *MemoryCache) removeExpired(key string, now time.Time) {
+	c.mu.Lock()
+	// Re-check under the write lock: a concurrent Set may have replaced
+	// the entry, possibly reusing the same pooled struct.
+	if entry, ok := c.entries[key]; ok && c.staleLocked(key, entry, now) {
+		c.removeLocked(key, entry)
+		c.emitEvict(key, EvictReasonExpired)
+		c.releaseLocked(entry)
+	}
+	c.mu.Unlock()
+}
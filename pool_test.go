@@ -0,0 +1,185 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEntryPooling_ReturnedValuesNotReused(t *testing.T) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithEntryPooling())
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", []byte("first"), time.Minute)
+	got, ok := c.Get(ctx, "a")
+	if !ok || string(got) != "first" {
+		t.Fatalf("Get() = %q, %v", got, ok)
+	}
+	str, _ := c.GetString(ctx, "a")
+
+	// Recycle a's buffer into b.
+	_ = c.Delete(ctx, "a")
+	_ = c.Set(ctx, "b", []byte("XXXXX"), time.Minute)
+
+	if string(got) != "first" || str != "first" {
+		t.Errorf("values read before Delete changed to %q, %q", got, str)
+	}
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Error("deleted entry served")
+	}
+	if got, _ := c.Get(ctx, "b"); string(got) != "XXXXX" {
+		t.Errorf("Get(b) = %q", got)
+	}
+}
+
+func TestEntryPooling_Semantics(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithEntryPooling(), WithClock(clock))
+	ctx := context.Background()
+
+	// Overwrite keeps the latest value and metadata.
+	_ = c.Set(ctx, "k", []byte("one"), time.Minute)
+	_ = c.SetWithMeta(ctx, "k", []byte("two"), EntryMeta{ContentType: "text/plain"}, time.Minute)
+	value, meta, ok := c.GetWithMeta(ctx, "k")
+	if !ok || string(value) != "two" || meta.ContentType != "text/plain" {
+		t.Errorf("GetWithMeta() = %q, %+v, %v", value, meta, ok)
+	}
+
+	// Empty values stay distinguishable from misses.
+	_ = c.Set(ctx, "empty", []byte{}, time.Minute)
+	if value, ok := c.Get(ctx, "empty"); !ok || value == nil || len(value) != 0 {
+		t.Errorf("Get(empty) = %#v, %v", value, ok)
+	}
+
+	// Expiry still applies.
+	clock.Advance(2 * time.Minute)
+	if _, ok := c.Get(ctx, "k"); ok {
+		t.Error("expired pooled entry served")
+	}
+	if keys, _ := c.KeysWithPrefix(ctx, ""); len(keys) != 0 {
+		t.Errorf("KeysWithPrefix() = %v, want no live entries", keys)
+	}
+
+	// Non-pooled setters still work alongside pooled ones.
+	_ = c.SetImmutable(ctx, "imm", []byte("shared"), time.Minute)
+	calls := 0
+	_ = c.SetThunk(ctx, "lazy", func(context.Context) ([]byte, error) {
+		calls++
+		return []byte("computed"), nil
+	}, time.Minute, time.Minute)
+	if got, _ := c.Get(ctx, "imm"); string(got) != "shared" {
+		t.Errorf("Get(imm) = %q", got)
+	}
+	if got, _ := c.Get(ctx, "lazy"); string(got) != "computed" || calls != 1 {
+		t.Errorf("Get(lazy) = %q after %d calls", got, calls)
+	}
+}
+
+func TestEntryPooling_SnapshotCopiesValues(t *testing.T) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithEntryPooling())
+	ctx := context.Background()
+	_ = c.Set(ctx, "k", []byte("value"), time.Minute)
+
+	var buf bytes.Buffer
+	if err := c.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	dst := NewMemoryCache(DefaultPolicy())
+	if _, err := dst.ReadSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := dst.Get(ctx, "k"); string(got) != "value" {
+		t.Errorf("restored value = %q", got)
+	}
+}
+
+func TestEntryPooling_IgnoredWithZeroCopy(t *testing.T) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithEntryPooling(), WithZeroCopy())
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", []byte("first"), time.Minute)
+	got, _ := c.Get(ctx, "a")
+	_ = c.Delete(ctx, "a")
+	_ = c.Set(ctx, "b", []byte("XXXXX"), time.Minute)
+	if string(got) != "first" {
+		t.Errorf("zero-copy slice reused after Delete: %q", got)
+	}
+}
+
+func TestEntryPooling_Concurrent(t *testing.T) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithEntryPooling())
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("k%d", i%16)
+				want := []byte(key + "-value")
+				switch i % 4 {
+				case 0:
+					_ = c.Set(ctx, key, want, time.Minute)
+				case 1:
+					_ = c.Delete(ctx, key)
+				case 2:
+					if got, ok := c.Get(ctx, key); ok && !bytes.Equal(got, want) {
+						t.Errorf("Get(%s) = %q, want %q", key, got, want)
+					}
+				default:
+					c.EvictFraction(0.1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func benchmarkSetDelete(b *testing.B, opts ...MemoryCacheOption) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), opts...)
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("x"), 256)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.Set(ctx, "key", value, time.Minute)
+		_ = c.Delete(ctx, "key")
+	}
+}
+
+func BenchmarkMemoryCache_SetDelete(b *testing.B) {
+	benchmarkSetDelete(b)
+}
+
+func BenchmarkMemoryCache_SetDeletePooled(b *testing.B) {
+	benchmarkSetDelete(b, WithEntryPooling())
+}
+
+func TestEntryPooling_FlushAndDeleteTreeRelease(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithEntryPooling())
+	_ = cache.Set(ctx, "a", []byte("v"), time.Minute)
+	_ = cache.Set(ctx, ChildKey("a", "b"), []byte("v"), time.Minute)
+	parent, child := cache.entries["a"], cache.entries[ChildKey("a", "b")]
+
+	if _, err := cache.DeleteTree(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if parent.buf != nil || child.buf != nil {
+		t.Error("DeleteTree should return pooled entries to the pool")
+	}
+
+	_ = cache.Set(ctx, "c", []byte("v"), time.Minute)
+	flushed := cache.entries["c"]
+	if err := cache.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if flushed.buf != nil {
+		t.Error("Flush should return pooled entries to the pool")
+	}
+}
@@ -0,0 +1,161 @@
+package toolcache
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// Defaults for MemoryPressureConfig.
+const (
+	DefaultPressureThreshold = 0.9
+	DefaultPressureFraction  = 0.25
+	DefaultPressureInterval  = time.Second
+)
+
+// MemoryStats is a sample of process memory usage.
+type MemoryStats struct {
+	// HeapBytes is the current heap in use.
+	HeapBytes uint64
+
+	// LimitBytes is the budget HeapBytes is measured against. Zero or
+	// math.MaxInt64 means no limit, and no pressure is ever reported.
+	LimitBytes uint64
+}
+
+// MemoryStatsSource samples process memory usage.
+type MemoryStatsSource func() MemoryStats
+
+// RuntimeMemoryStats samples the Go runtime: HeapBytes is the live heap
+// allocation and LimitBytes is the soft limit set with debug.SetMemoryLimit
+// (or GOMEMLIMIT). It calls runtime.ReadMemStats, which briefly stops the
+// world, so sample at a modest interval.
+func RuntimeMemoryStats() MemoryStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	limit := debug.SetMemoryLimit(-1)
+	if limit < 0 {
+		limit = 0
+	}
+	return MemoryStats{HeapBytes: ms.HeapAlloc, LimitBytes: uint64(limit)}
+}
+
+// MemoryPressureConfig controls best-effort shrinking under memory pressure.
+// Zero fields take the Default* values.
+type MemoryPressureConfig struct {
+	// Source samples memory usage. Defaults to RuntimeMemoryStats.
+	Source MemoryStatsSource
+
+	// Threshold is the fraction of LimitBytes above which the cache sheds
+	// entries.
+	Threshold float64
+
+	// Fraction is the share of entries evicted each time the threshold is
+	// crossed.
+	Fraction float64
+
+	// Interval is how often WatchMemoryPressure samples.
+	Interval time.Duration
+}
+
+func (cfg MemoryPressureConfig) withDefaults() MemoryPressureConfig {
+	if cfg.Source == nil {
+		cfg.Source = RuntimeMemoryStats
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultPressureThreshold
+	}
+	if cfg.Fraction <= 0 {
+		cfg.Fraction = DefaultPressureFraction
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultPressureInterval
+	}
+	return cfg
+}
+
+// underPressure reports whether s exceeds threshold of its limit.
+func (s MemoryStats) underPressure(threshold float64) bool {
+	if s.LimitBytes == 0 || s.LimitBytes >= math.MaxInt64 {
+		return false
+	}
+	return float64(s.HeapBytes) > threshold*float64(s.LimitBytes)
+}
+
+// RelieveMemoryPressure samples memory once and, if usage is above the
+// configured threshold, evicts the configured fraction of entries. It
+// returns the number of entries evicted.
+func (c *MemoryCache) RelieveMemoryPressure(cfg MemoryPressureConfig) int {
+	cfg = cfg.withDefaults()
+	if !cfg.Source().underPressure(cfg.Threshold) {
+		return 0
+	}
+	return c.EvictFraction(cfg.Fraction)
+}
+
+// WatchMemoryPressure calls RelieveMemoryPressure every cfg.Interval until
+// ctx is done. Run it in its own goroutine.
+func (c *MemoryCache) WatchMemoryPressure(ctx context.Context, cfg MemoryPressureConfig) {
+	cfg = cfg.withDefaults()
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.RelieveMemoryPressure(cfg)
+		}
+	}
+}
+
+// EvictFraction removes roughly fraction (0..1] of the cache's entries and
+// returns how many were removed. Entries of the lowest priority (see
+// EntryMeta.Priority) go first; within a priority, expired entries go
+// first, then those closest to expiry. Pinned entries are never removed.
+// Removed entries are reported as EvictReasonPressure.
+func (c *MemoryCache) EvictFraction(fraction float64) int {
+	if fraction <= 0 {
+		return 0
+	}
+	fraction = min(fraction, 1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := int(math.Ceil(fraction * float64(len(c.entries))))
+	if n == 0 {
+		return 0
+	}
+
+	type victim struct {
+		key       string
+		priority  int
+		expiresAt time.Time
+	}
+	victims := make([]victim, 0, len(c.entries))
+	for key, entry := range c.entries {
+		if !c.pinnedLocked(key) {
+			victims = append(victims, victim{key, entry.meta.Priority, entry.expiresAt})
+		}
+	}
+	n = min(n, len(victims))
+	sort.Slice(victims, func(i, j int) bool {
+		if victims[i].priority != victims[j].priority {
+			return victims[i].priority < victims[j].priority
+		}
+		return victims[i].expiresAt.Before(victims[j].expiresAt)
+	})
+
+	for _, v := range victims[:n] {
+		entry := c.entries[v.key]
+		c.removeLocked(v.key, entry)
+		c.emitEvict(v.key, EvictReasonPressure)
+		c.releaseLocked(entry)
+	}
+	return n
+}
@@ -0,0 +1,132 @@
+package toolcache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fillCache(t *testing.T, c *MemoryCache, n int) {
+	t.Helper()
+	for i := range n {
+		// Later entries live longer, so eviction order is predictable.
+		ttl := time.Minute + time.Duration(i)*time.Second
+		if err := c.Set(context.Background(), fmt.Sprintf("k%02d", i), []byte("v"), ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEvictFraction(t *testing.T) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithEvictionEvents(16))
+	fillCache(t, c, 8)
+
+	if got := c.EvictFraction(0.25); got != 2 {
+		t.Fatalf("EvictFraction = %d, want 2", got)
+	}
+	for _, key := range []string{"k00", "k01"} {
+		if _, ok := c.Get(context.Background(), key); ok {
+			t.Errorf("%s should have been evicted first", key)
+		}
+	}
+	if _, ok := c.Get(context.Background(), "k07"); !ok {
+		t.Error("longest-lived entry should survive")
+	}
+
+	ev := <-c.EvictionEvents()
+	if ev.Reason != EvictReasonPressure {
+		t.Errorf("Reason = %v, want pressure", ev.Reason)
+	}
+}
+
+func TestEvictFraction_Bounds(t *testing.T) {
+	c := NewMemoryCache(DefaultPolicy())
+	fillCache(t, c, 4)
+
+	if got := c.EvictFraction(0); got != 0 {
+		t.Errorf("EvictFraction(0) = %d, want 0", got)
+	}
+	if got := c.EvictFraction(5); got != 4 {
+		t.Errorf("EvictFraction(5) = %d, want 4", got)
+	}
+	if got := c.EvictFraction(0.5); got != 0 {
+		t.Errorf("EvictFraction on empty cache = %d, want 0", got)
+	}
+}
+
+func TestRelieveMemoryPressure(t *testing.T) {
+	c := NewMemoryCache(DefaultPolicy())
+	fillCache(t, c, 10)
+
+	stats := MemoryStats{HeapBytes: 50, LimitBytes: 100}
+	cfg := MemoryPressureConfig{
+		Source:    func() MemoryStats { return stats },
+		Threshold: 0.8,
+		Fraction:  0.5,
+	}
+
+	if got := c.RelieveMemoryPressure(cfg); got != 0 {
+		t.Fatalf("below threshold evicted %d entries", got)
+	}
+
+	stats.HeapBytes = 90
+	if got := c.RelieveMemoryPressure(cfg); got != 5 {
+		t.Fatalf("above threshold evicted %d entries, want 5", got)
+	}
+}
+
+func TestRelieveMemoryPressure_NoLimit(t *testing.T) {
+	c := NewMemoryCache(DefaultPolicy())
+	fillCache(t, c, 4)
+
+	cfg := MemoryPressureConfig{
+		Source: func() MemoryStats { return MemoryStats{HeapBytes: 1 << 40} },
+	}
+	if got := c.RelieveMemoryPressure(cfg); got != 0 {
+		t.Errorf("no limit should never report pressure, evicted %d", got)
+	}
+}
+
+func TestWatchMemoryPressure(t *testing.T) {
+	c := NewMemoryCache(DefaultPolicy())
+	fillCache(t, c, 8)
+
+	var heap atomic.Uint64
+	heap.Store(10)
+	cfg := MemoryPressureConfig{
+		Source:   func() MemoryStats { return MemoryStats{HeapBytes: heap.Load(), LimitBytes: 100} },
+		Fraction: 0.5,
+		Interval: time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.WatchMemoryPressure(ctx, cfg)
+		close(done)
+	}()
+
+	heap.Store(95)
+	deadline := time.Now().Add(time.Second)
+	for {
+		snap, _ := c.Snapshot(context.Background())
+		if len(snap) < 8 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("watcher did not evict after crossing the threshold")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestRuntimeMemoryStats(t *testing.T) {
+	if s := RuntimeMemoryStats(); s.HeapBytes == 0 {
+		t.Error("expected non-zero heap usage")
+	}
+}
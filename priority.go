@@ -0,0 +1,28 @@
+package toolcache
+
+import (
+	"context"
+	"time"
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context that stores results of calls made with it
+// at priority, for entries expensive enough to recompute that they should
+// outlast others under eviction. See EntryMeta.Priority. It takes effect
+// only with a cache implementing MetaCache, and overrides any priority in
+// the metadata reported by a MetaExecutor.
+func WithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityFromContext(ctx context.Context) (priority int, ok bool) {
+	priority, ok = ctx.Value(priorityKey{}).(int)
+	return priority, ok
+}
+
+// SetWithPriority stores value under key at priority; see
+// EntryMeta.Priority.
+func (c *MemoryCache) SetWithPriority(ctx context.Context, key string, value []byte, priority int, ttl time.Duration) error {
+	return c.SetWithMeta(ctx, key, value, EntryMeta{Priority: priority}, ttl)
+}
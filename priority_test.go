@@ -0,0 +1,113 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPriority_MaxEntriesEvictsLowerFirst(t *testing.T) {
+	for name, opts := range map[string][]MemoryCacheOption{
+		"default": nil,
+		"pooled":  {WithEntryPooling()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewMemoryCacheWithOptions(DefaultPolicy(), append(opts, WithMaxEntries(3))...)
+			ctx := context.Background()
+
+			_ = c.SetWithPriority(ctx, "critical", []byte("v"), 10, time.Minute)
+			for _, key := range []string{"a", "b", "c", "d"} {
+				_ = c.Set(ctx, key, []byte(key), time.Minute)
+			}
+			if got := lruKeys(t, c); !slices.Equal(got, []string{"critical", "d", "c"}) {
+				t.Errorf("entries = %v, want the critical entry kept despite being least recent", got)
+			}
+
+			// Overwriting at a lower priority makes the entry evictable again.
+			_ = c.Set(ctx, "critical", []byte("v"), time.Minute)
+			_ = c.Set(ctx, "e", []byte("e"), time.Minute)
+			if got := lruKeys(t, c); !slices.Equal(got, []string{"e", "critical", "d"}) {
+				t.Errorf("entries = %v after demoting critical", got)
+			}
+		})
+	}
+}
+
+func TestPriority_NewEntryDoesNotDisplaceHigher(t *testing.T) {
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithMaxEntries(2), WithEvictionEvents(4))
+	ctx := context.Background()
+	_ = c.SetWithPriority(ctx, "h1", []byte("v"), 5, time.Minute)
+	_ = c.SetWithPriority(ctx, "h2", []byte("v"), 5, time.Minute)
+
+	if err := c.Set(ctx, "low", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got := lruKeys(t, c); !slices.Equal(got, []string{"h2", "h1"}) {
+		t.Errorf("entries = %v, want both high-priority entries kept", got)
+	}
+	if ev := <-c.EvictionEvents(); ev.Key != "low" || ev.Reason != EvictReasonCapacity {
+		t.Errorf("event = %s/%s, want low/capacity", ev.Key, ev.Reason)
+	}
+
+	// A pinned key is never evicted, even at a lower priority.
+	_ = c.Pin(ctx, "pinned")
+	_ = c.Set(ctx, "pinned", []byte("v"), time.Minute)
+	if _, ok := c.Get(ctx, "pinned"); !ok {
+		t.Error("pinned entry should be kept")
+	}
+}
+
+func TestPriority_EvictFraction(t *testing.T) {
+	c := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	_ = c.SetWithPriority(ctx, "critical", []byte("v"), 1, time.Second)
+	for _, key := range []string{"a", "b", "c"} {
+		_ = c.Set(ctx, key, []byte(key), time.Hour)
+	}
+	_ = c.SetWithPriority(ctx, "scratch", []byte("v"), -1, 2*time.Hour)
+
+	if got := c.EvictFraction(0.6); got != 3 {
+		t.Fatalf("EvictFraction = %d, want 3", got)
+	}
+	keys, _ := c.KeysWithPrefix(ctx, "")
+	if len(keys) != 2 || !slices.Contains(keys, "critical") {
+		t.Errorf("remaining keys = %v, want critical kept despite expiring first", keys)
+	}
+}
+
+func TestPriority_ExecuteContext(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithMaxEntries(2))
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil)
+	expensive := &mockExecutor{result: []byte("slow")}
+	cheap := &mockExecutor{result: []byte("fast")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(WithPriority(ctx, 1), "report", 1, nil, expensive.execute)
+	for i := range 3 {
+		_, _ = mw.Execute(ctx, "lookup", i, nil, cheap.execute)
+	}
+	_, _ = mw.Execute(ctx, "report", 1, nil, expensive.execute)
+	if expensive.calls != 1 {
+		t.Errorf("prioritized result should survive eviction, got %d calls", expensive.calls)
+	}
+}
+
+func TestPriority_SnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryCache(DefaultPolicy())
+	_ = src.SetWithPriority(ctx, "k", []byte("v"), 7, time.Minute)
+
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	dst := NewMemoryCache(DefaultPolicy())
+	if _, err := dst.ReadSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, meta, _ := dst.GetWithMeta(ctx, "k"); meta.Priority != 7 {
+		t.Errorf("Priority = %d, want 7", meta.Priority)
+	}
+}
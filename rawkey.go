@@ -0,0 +1,78 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRawKeyUnsupported is returned when ExecuteRaw is used with a keyer
+// that cannot key pre-serialized input.
+var ErrRawKeyUnsupported = errors.New("toolcache: keyer does not support raw input")
+
+// RawKeyer is implemented by keyers that can derive a key from input the
+// caller has already serialized. DefaultKeyer and RoutingKeyer implement it.
+type RawKeyer interface {
+	KeyRaw(toolID string, canonical []byte) (string, error)
+}
+
+// ExecuteRaw behaves like Execute for callers that already hold the
+// canonical serialization of their input, e.g. from an upstream cache
+// layer. The bytes are hashed as-is by the keyer's KeyRaw, skipping
+// canonicalization, and passed to the executor as its input. With
+// DefaultKeyer, the key matches the one Execute derives for a structured
+// input whose canonical JSON is canonical; the caller is responsible for
+// keeping the serialization stable.
+//
+// If the keyer does not implement RawKeyer, the call runs uncached and an
+// EventKeyed with ErrRawKeyUnsupported is reported.
+func (m *CacheMiddleware) ExecuteRaw(ctx context.Context, toolID string, canonical []byte, tags []string, executor ToolExecutor) (result []byte, err error) {
+	ctx, endSpan := m.startSpan(ctx, toolID)
+	defer func() { endSpan(err) }()
+
+	if m.cache == nil {
+		return nil, ErrNilCache
+	}
+	if m.shouldSkip(ctx, toolID, tags) {
+		return m.executeUncached(ctx, toolID, canonical, executor)
+	}
+
+	start := time.Now()
+	key, err := m.safeRawKey(toolID, canonical)
+	keying := time.Since(start)
+	m.observe(ctx, Event{Kind: EventKeyed, ToolID: toolID, Key: key, Duration: keying, Err: err})
+	if err != nil {
+		m.stats.record(toolID, ToolStats{Phases: PhaseTimings{Keying: keying}})
+		return m.executeUncached(ctx, toolID, canonical, executor)
+	}
+
+	key, err = m.checkKey(m.tagKey(key, tags))
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return m.executeUncached(ctx, toolID, canonical, executor)
+	}
+
+	return m.executeKeyed(ctx, toolID, key, canonical, executor, nil, keying)
+}
+
+// safeRawKey is the RawKeyer counterpart of safeKey.
+func (m *CacheMiddleware) safeRawKey(toolID string, canonical []byte) (key string, err error) {
+	raw, ok := m.keyer.(RawKeyer)
+	if !ok {
+		return "", ErrRawKeyUnsupported
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			key, err = "", fmt.Errorf("%w: %v", ErrKeyerPanic, r)
+		}
+	}()
+	return raw.KeyRaw(toolID, canonical)
+}
+
+var (
+	_ RawKeyer = (*DefaultKeyer)(nil)
+	_ RawKeyer = (*RoutingKeyer)(nil)
+)
@@ -0,0 +1,104 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDefaultKeyer_KeyRawMatchesKey(t *testing.T) {
+	keyer := NewDefaultKeyer()
+	keyer.SetEpoch(3)
+	input := map[string]any{"b": []any{1, "x"}, "a": true}
+
+	canonical, err := canonicalJSON(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structured, _ := keyer.Key("tool", input)
+	raw, err := keyer.KeyRaw("tool", canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != structured {
+		t.Errorf("KeyRaw(%s) = %s, want %s", canonical, raw, structured)
+	}
+
+	// Bytes are hashed as-is: non-canonical whitespace gives another key.
+	spaced, _ := keyer.KeyRaw("tool", []byte(`{"a": true, "b": [1, "x"]}`))
+	if spaced == structured {
+		t.Error("KeyRaw must not re-canonicalize its input")
+	}
+}
+
+func TestDefaultKeyer_KeyRawInputIndependent(t *testing.T) {
+	keyer := &DefaultKeyer{InputIndependentTools: map[string]bool{"caps": true}}
+	k1, _ := keyer.KeyRaw("caps", []byte(`{"x":1}`))
+	k2, _ := keyer.Key("caps", map[string]any{"y": 2})
+	if k1 != k2 {
+		t.Error("input-independent tools should ignore raw input too")
+	}
+}
+
+func TestExecuteRaw_SharesEntriesWithExecute(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	if _, err := mw.Execute(ctx, "tool", map[string]any{"q": "go"}, nil, executor.execute); err != nil {
+		t.Fatal(err)
+	}
+	got, err := mw.ExecuteRaw(ctx, "tool", []byte(`{"q":"go"}`), nil, executor.execute)
+	if err != nil || string(got) != "ok" {
+		t.Fatalf("ExecuteRaw = %q, %v", got, err)
+	}
+	if executor.calls != 1 {
+		t.Errorf("raw and structured input should share a key, got %d calls", executor.calls)
+	}
+}
+
+func TestExecuteRaw_PassesBytesToExecutor(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	var seen any
+	_, _ = mw.ExecuteRaw(context.Background(), "tool", []byte(`[1]`), nil, func(_ context.Context, _ string, input any) ([]byte, error) {
+		seen = input
+		return []byte("ok"), nil
+	})
+	if b, ok := seen.([]byte); !ok || string(b) != "[1]" {
+		t.Errorf("executor input = %#v, want the raw bytes", seen)
+	}
+}
+
+func TestExecuteRaw_UnsupportedKeyerRunsUncached(t *testing.T) {
+	keyer := KeyerFunc(func(toolID string, _ any) (string, error) { return "k:" + toolID, nil })
+	obs := &recordingObserver{}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), keyer, DefaultPolicy(), nil, WithObserver(obs))
+	executor := &mockExecutor{result: []byte("ok")}
+
+	for i := 0; i < 2; i++ {
+		if _, err := mw.ExecuteRaw(context.Background(), "tool", []byte(`1`), nil, executor.execute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if executor.calls != 2 {
+		t.Errorf("calls = %d, want 2 uncached calls", executor.calls)
+	}
+	events := obs.byKind(EventKeyed)
+	if len(events) == 0 || !errors.Is(events[0].Err, ErrRawKeyUnsupported) {
+		t.Errorf("EventKeyed = %+v, want ErrRawKeyUnsupported", events)
+	}
+}
+
+func TestRoutingKeyer_KeyRaw(t *testing.T) {
+	plain := KeyerFunc(func(string, any) (string, error) { return "plain", nil })
+	keyer := NewRoutingKeyer(nil, map[string]Keyer{"fs": plain})
+
+	if _, err := keyer.KeyRaw("fs:read", []byte(`1`)); !errors.Is(err, ErrRawKeyUnsupported) {
+		t.Errorf("KeyRaw via a plain keyer = %v, want ErrRawKeyUnsupported", err)
+	}
+	got, err := keyer.KeyRaw("web:get", []byte(`1`))
+	want, _ := NewDefaultKeyer().Key("web:get", 1)
+	if err != nil || got != want {
+		t.Errorf("KeyRaw via fallback = %q, %v; want %q", got, err, want)
+	}
+}
@@ -0,0 +1,26 @@
+package toolcache
+
+// WithMaxResultBytes makes the middleware return successful results
+// larger than n bytes without caching them, counting each in
+// ToolStats.OversizedResults. Unlike a cache's own size limits, which
+// reject writes, this is a policy decision made once the result is known,
+// so huge outputs never reach the cache at all. The limit applies to the
+// bytes that would be stored, after any WithResultTransform, and to
+// partial results kept by WithPartialResultTTL, which are discarded when
+// too large. WarmAll reports such requests as WarmSkipped. A value of 0 (the default)
+// disables the limit.
+func WithMaxResultBytes(n int) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.maxResultBytes = max(n, 0)
+	}
+}
+
+// oversizedResult reports whether value exceeds the WithMaxResultBytes
+// limit, recording it in toolID's stats if so.
+func (m *CacheMiddleware) oversizedResult(toolID string, value []byte) bool {
+	if m.maxResultBytes <= 0 || len(value) <= m.maxResultBytes {
+		return false
+	}
+	m.stats.record(toolID, ToolStats{OversizedResults: 1})
+	return true
+}
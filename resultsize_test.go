@@ -0,0 +1,120 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaxResultBytes(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		wantCalls  int
+		wantCached bool
+	}{
+		{name: "below", size: 99, wantCalls: 1, wantCached: true},
+		{name: "at", size: 100, wantCalls: 1, wantCached: true},
+		{name: "above", size: 101, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+				WithMaxResultBytes(100))
+			result := bytes.Repeat([]byte("x"), tt.size)
+			executor := &mockExecutor{result: result}
+			ctx := context.Background()
+
+			for range 2 {
+				got, err := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+				if err != nil || !bytes.Equal(got, result) {
+					t.Fatalf("Execute() = %d bytes, %v; want the full result", len(got), err)
+				}
+			}
+			if executor.calls != tt.wantCalls {
+				t.Errorf("executor calls = %d, want %d", executor.calls, tt.wantCalls)
+			}
+
+			stats := mw.PerToolStats()["tool"]
+			wantOversized := uint64(0)
+			if !tt.wantCached {
+				wantOversized = 2
+			}
+			if stats.OversizedResults != wantOversized {
+				t.Errorf("OversizedResults = %d, want %d", stats.OversizedResults, wantOversized)
+			}
+			if tt.wantCached && stats.BytesStored != uint64(tt.size) {
+				t.Errorf("BytesStored = %d, want %d", stats.BytesStored, tt.size)
+			}
+			if !tt.wantCached && stats.BytesStored != 0 {
+				t.Errorf("oversized result stored %d bytes", stats.BytesStored)
+			}
+		})
+	}
+}
+
+func TestMaxResultBytes_AppliesAfterTransform(t *testing.T) {
+	shrink := func(_ string, result []byte) ([]byte, error) {
+		return result[:10], nil
+	}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithMaxResultBytes(50), WithResultTransform(shrink, TransformCacheOnly))
+	executor := &mockExecutor{result: bytes.Repeat([]byte("x"), 100)}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 1 {
+		t.Errorf("transformed result within the limit should be cached, got %d calls", executor.calls)
+	}
+}
+
+func TestMaxResultBytes_DisabledByDefault(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &mockExecutor{result: bytes.Repeat([]byte("x"), 1<<20)}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 1 || mw.Stats().OversizedResults != 0 {
+		t.Errorf("calls = %d, OversizedResults = %d; want 1, 0", executor.calls, mw.Stats().OversizedResults)
+	}
+}
+
+func TestMaxResultBytes_WarmAll(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil, WithMaxResultBytes(4))
+	executor := func(_ context.Context, _ string, input any) ([]byte, error) {
+		return []byte(input.(string)), nil
+	}
+
+	results := mw.WarmAll(context.Background(), []WarmRequest{
+		{ToolID: "t", Input: "tiny"},
+		{ToolID: "t", Input: "large"},
+	}, executor)
+	if results[0].Status != WarmStored || results[1].Status != WarmSkipped {
+		t.Errorf("statuses = %v, %v; want stored, skipped", results[0].Status, results[1].Status)
+	}
+	if _, ok := cache.Get(context.Background(), results[1].Key); ok {
+		t.Error("oversized warm result should not be cached")
+	}
+}
+
+func TestMaxResultBytes_PartialResults(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithPartialResultTTL(time.Minute), WithMaxResultBytes(4))
+	executor := &mockExecutor{result: []byte("page-1-of-many"), err: errPartial}
+
+	if _, err := mw.Execute(context.Background(), "tool", 1, nil, executor.execute); !errors.Is(err, errPartial) {
+		t.Fatalf("Execute() error = %v, want errPartial", err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("an oversized partial result should not be cached, got %d entries", cache.Len())
+	}
+	if got := mw.PerToolStats()["tool"].OversizedResults; got != 1 {
+		t.Errorf("OversizedResults = %d, want 1", got)
+	}
+}
@@ -0,0 +1,37 @@
+package toolcache
+
+import "time"
+
+// TTLFromResultFunc derives a cache TTL from an executor result, for tools
+// whose responses carry their own expiry (e.g. a "cache_until" field).
+type TTLFromResultFunc func(toolID string, result []byte) (time.Duration, error)
+
+// WithTTLFromResult consults fn for the TTL of each successful result. The
+// TTL replaces Policy.DefaultTTL and is still clamped to Policy.MaxTTL. A
+// non-positive TTL means the result is already stale and is not cached. If
+// fn returns an error, the policy TTL is used. fn sees the result as
+// returned by the executor, before any WithResultTransform.
+func WithTTLFromResult(fn TTLFromResultFunc) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.ttlFromResult = fn
+	}
+}
+
+// resultTTL returns the TTL to store result with. Empty results use
+// Policy.EmptyResultTTL and are not passed to the TTL function.
+func (m *CacheMiddleware) resultTTL(toolID string, result []byte) time.Duration {
+	if len(result) == 0 {
+		return m.policy.EffectiveEmptyResultTTL()
+	}
+	if m.ttlFromResult == nil {
+		return m.policy.EffectiveTTL(0)
+	}
+	ttl, err := m.ttlFromResult(toolID, result)
+	if err != nil {
+		return m.policy.EffectiveTTL(0)
+	}
+	if ttl <= 0 {
+		return 0
+	}
+	return m.policy.EffectiveTTL(ttl)
+}
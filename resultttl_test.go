@@ -0,0 +1,160 @@
+package toolcache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// ttlField reads a "ttl_ms" field from a JSON result.
+func ttlField(_ string, result []byte) (time.Duration, error) {
+	var v struct {
+		TTLMillis int64 `json:"ttl_ms"`
+	}
+	if err := json.Unmarshal(result, &v); err != nil {
+		return 0, err
+	}
+	return time.Duration(v.TTLMillis) * time.Millisecond, nil
+}
+
+func TestTTLFromResult_ShorterTTL(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithTTLFromResult(ttlField))
+	executor := &mockExecutor{result: []byte(`{"ttl_ms":1}`)}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 1 {
+		t.Fatalf("expected a hit within the result TTL, got %d calls", executor.calls)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("entry should expire after the result TTL, got %d calls", executor.calls)
+	}
+}
+
+func TestTTLFromResult_ClampedToMaxTTL(t *testing.T) {
+	var gotTTL time.Duration
+	cache := CacheFuncs{
+		SetFunc: func(_ context.Context, _ string, _ []byte, ttl time.Duration) error {
+			gotTTL = ttl
+			return nil
+		},
+	}
+	policy := Policy{DefaultTTL: time.Minute, MaxTTL: time.Hour}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), policy, nil, WithTTLFromResult(ttlField))
+	executor := &mockExecutor{result: []byte(`{"ttl_ms":86400000}`)}
+
+	_, _ = mw.Execute(context.Background(), "tool", 1, nil, executor.execute)
+	if gotTTL != time.Hour {
+		t.Errorf("Set TTL = %v, want MaxTTL", gotTTL)
+	}
+}
+
+func TestTTLFromResult_Fallbacks(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		wantTTL time.Duration
+	}{
+		{"parse error uses policy", `not json`, time.Minute},
+		{"zero means stale", `{"ttl_ms":0}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTTL time.Duration
+			cache := CacheFuncs{
+				SetFunc: func(_ context.Context, _ string, _ []byte, ttl time.Duration) error {
+					gotTTL = ttl
+					return nil
+				},
+			}
+			policy := Policy{DefaultTTL: time.Minute}
+			mw := NewCacheMiddleware(cache, NewDefaultKeyer(), policy, nil, WithTTLFromResult(ttlField))
+			executor := &mockExecutor{result: []byte(tt.result)}
+
+			_, _ = mw.Execute(context.Background(), "tool", 1, nil, executor.execute)
+			if gotTTL != tt.wantTTL {
+				t.Errorf("Set TTL = %v, want %v", gotTTL, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestEmptyResultTTL(t *testing.T) {
+	policy := Policy{DefaultTTL: time.Hour, EmptyResultTTL: time.Second}
+	var ttls []time.Duration
+	cache := CacheFuncs{
+		SetFunc: func(_ context.Context, _ string, _ []byte, ttl time.Duration) error {
+			ttls = append(ttls, ttl)
+			return nil
+		},
+	}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), policy, nil, WithTTLFromResult(ttlField))
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", "empty", nil, func(context.Context, string, any) ([]byte, error) {
+		return nil, nil
+	})
+	_, _ = mw.Execute(ctx, "tool", "full", nil, func(context.Context, string, any) ([]byte, error) {
+		return []byte(`{"ttl_ms":60000}`), nil
+	})
+	if len(ttls) != 2 || ttls[0] != time.Second || ttls[1] != time.Minute {
+		t.Errorf("Set TTLs = %v, want [1s 1m0s]", ttls)
+	}
+}
+
+func TestEmptyResultTTL_ZeroDisables(t *testing.T) {
+	policy := Policy{DefaultTTL: time.Hour}
+	mw := NewCacheMiddleware(NewMemoryCache(policy), NewDefaultKeyer(), policy, nil)
+	empty := &mockExecutor{result: []byte{}}
+	full := &mockExecutor{result: []byte("x")}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, _ = mw.Execute(ctx, "tool", "empty", nil, empty.execute)
+		_, _ = mw.Execute(ctx, "tool", "full", nil, full.execute)
+	}
+	if empty.calls != 2 {
+		t.Errorf("empty executor calls = %d, want 2 with EmptyResultTTL unset", empty.calls)
+	}
+	if full.calls != 1 {
+		t.Errorf("full executor calls = %d, want 1", full.calls)
+	}
+
+	results := mw.WarmAll(ctx, []WarmRequest{{ToolID: "tool", Input: "warm"}}, empty.execute)
+	if results[0].Status != WarmSkipped {
+		t.Errorf("warming an empty result = %v, want skipped", results[0].Status)
+	}
+}
+
+func TestTTLFromResult_WarmAll(t *testing.T) {
+	var ttls []time.Duration
+	cache := CacheFuncs{
+		GetFunc: func(context.Context, string) ([]byte, bool) { return nil, false },
+		SetFunc: func(_ context.Context, _ string, _ []byte, ttl time.Duration) error {
+			ttls = append(ttls, ttl)
+			return nil
+		},
+	}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithTTLFromResult(ttlField), WithWarmConcurrency(1))
+	executor := func(_ context.Context, _ string, input any) ([]byte, error) {
+		return []byte(input.(string)), nil
+	}
+
+	results := mw.WarmAll(context.Background(), []WarmRequest{
+		{ToolID: "tool", Input: `{"ttl_ms":2000}`},
+		{ToolID: "tool", Input: `{"ttl_ms":0}`},
+	}, executor)
+	if results[0].Status != WarmStored || results[1].Status != WarmSkipped {
+		t.Errorf("statuses = %s, %s; want stored, skipped", results[0].Status, results[1].Status)
+	}
+	if len(ttls) != 1 || ttls[0] != 2*time.Second {
+		t.Errorf("Set TTLs = %v, want [2s] from the result", ttls)
+	}
+}
@@ -0,0 +1,31 @@
+package toolcache
+
+import "context"
+
+// RevalidateFunc reports whether a cached value is still valid, in the
+// spirit of an HTTP conditional request. It should be much cheaper than
+// running the tool.
+type RevalidateFunc func(ctx context.Context, toolID, key string, value []byte) (stillValid bool, err error)
+
+// WithRevalidate consults fn on every cache hit. If fn reports the value is
+// no longer valid, or fails, the entry is deleted and the call proceeds as
+// a miss. Negatively cached errors are not revalidated.
+func WithRevalidate(fn RevalidateFunc) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.revalidate = fn
+	}
+}
+
+// stillValid runs the revalidation hook for a hit, deleting the entry if it
+// is stale.
+func (m *CacheMiddleware) stillValid(ctx context.Context, toolID, key string, value []byte) bool {
+	if m.revalidate == nil {
+		return true
+	}
+	valid, err := m.revalidate(ctx, toolID, key, value)
+	if err == nil && valid {
+		return true
+	}
+	_ = m.cache.Delete(ctx, key)
+	return false
+}
@@ -0,0 +1,73 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRevalidate_Valid(t *testing.T) {
+	var checked []string
+	revalidate := func(_ context.Context, toolID, key string, value []byte) (bool, error) {
+		checked = append(checked, string(value))
+		return true, nil
+	}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithRevalidate(revalidate))
+	executor := &mockExecutor{result: []byte("v1")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	got, err := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if err != nil || string(got) != "v1" {
+		t.Fatalf("expected cached v1, got %q, %v", got, err)
+	}
+	if executor.calls != 1 {
+		t.Errorf("valid entry should not re-execute, got %d calls", executor.calls)
+	}
+	if len(checked) != 1 || checked[0] != "v1" {
+		t.Errorf("revalidate saw %v, want [v1]", checked)
+	}
+}
+
+func TestRevalidate_Invalid(t *testing.T) {
+	for name, revalidate := range map[string]RevalidateFunc{
+		"stale": func(context.Context, string, string, []byte) (bool, error) { return false, nil },
+		"error": func(context.Context, string, string, []byte) (bool, error) { return true, errors.New("unreachable") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+				WithRevalidate(revalidate))
+			executor := &mockExecutor{result: []byte("v1")}
+			ctx := context.Background()
+
+			_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+			executor.result = []byte("v2")
+			got, _ := mw.Execute(ctx, "tool", 1, nil, executor.execute)
+			if string(got) != "v2" || executor.calls != 2 {
+				t.Errorf("invalid entry should re-execute, got %q after %d calls", got, executor.calls)
+			}
+			if stats := mw.Stats(); stats.Hits != 0 || stats.Misses != 2 {
+				t.Errorf("stats = %+v, want 0 hits and 2 misses", stats.ToolStats)
+			}
+		})
+	}
+}
+
+func TestRevalidate_InvalidClearsExecInfo(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithRevalidate(func(context.Context, string, string, []byte) (bool, error) { return false, nil }))
+	executor := func(context.Context, string, any) ([]byte, EntryMeta, error) {
+		return []byte("v"), EntryMeta{ContentType: "text/plain"}, nil
+	}
+	ctx := context.Background()
+
+	_, _, _ = mw.ExecuteWithInfo(ctx, "tool", 1, nil, executor)
+	_, info, _ := mw.ExecuteWithInfo(ctx, "tool", 1, nil, executor)
+	if info.Cached {
+		t.Error("revalidation failure should not report a cache hit")
+	}
+	if info.Meta.ContentType != "text/plain" {
+		t.Errorf("ContentType = %q, want fresh executor metadata", info.Meta.ContentType)
+	}
+}
@@ -0,0 +1,220 @@
+package toolcache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Default segment sizes for NewSegmentedCache.
+const (
+	DefaultProbationSize = 200
+	DefaultProtectedSize = 800
+)
+
+// SegmentedCacheOption configures a SegmentedCache.
+type SegmentedCacheOption func(*SegmentedCache)
+
+// WithProbationSize sets how many entries the probationary segment holds.
+// New entries enter it and are evicted from it first, so it bounds how
+// much a scan of one-off keys can displace. Values below 1 are treated
+// as 1.
+func WithProbationSize(n int) SegmentedCacheOption {
+	return func(c *SegmentedCache) {
+		c.probationSize = max(n, 1)
+	}
+}
+
+// WithProtectedSize sets how many entries the protected segment holds.
+// Entries are promoted into it on their second access. A size of 0
+// disables promotion, making the cache a plain LRU of the probation size.
+// Negative values are treated as 0.
+func WithProtectedSize(n int) SegmentedCacheOption {
+	return func(c *SegmentedCache) {
+		c.protectedSize = max(n, 0)
+	}
+}
+
+// WithSegmentedClock sets the time source used for entry expiry.
+// A nil clock restores the system clock.
+func WithSegmentedClock(clock Clock) SegmentedCacheOption {
+	return func(c *SegmentedCache) {
+		if clock == nil {
+			clock = systemClock{}
+		}
+		c.clock = clock
+	}
+}
+
+type segmentedEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	protected bool
+}
+
+// SegmentedCache is a bounded cache using segmented LRU eviction, a
+// 2Q-style scheme. New entries start in a small probationary segment and
+// are promoted to the protected segment only when accessed again; entries
+// falling out of the protected segment return to probation rather than
+// being evicted. A burst of keys read once, such as a scan, therefore
+// churns only the probationary segment and cannot evict entries that
+// have proven hot, as it would under plain LRU.
+type SegmentedCache struct {
+	mu            sync.Mutex
+	entries       map[string]*list.Element
+	probation     *list.List // front = most recently used
+	protected     *list.List // front = most recently used
+	probationSize int
+	protectedSize int
+	clock         Clock
+}
+
+// NewSegmentedCache creates a SegmentedCache holding at most
+// DefaultProbationSize + DefaultProtectedSize entries unless overridden
+// with WithProbationSize and WithProtectedSize.
+func NewSegmentedCache(opts ...SegmentedCacheOption) *SegmentedCache {
+	c := &SegmentedCache{
+		entries:       make(map[string]*list.Element),
+		probation:     list.New(),
+		protected:     list.New(),
+		probationSize: DefaultProbationSize,
+		protectedSize: DefaultProtectedSize,
+		clock:         systemClock{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get returns the value under key. A hit on a probationary entry promotes
+// it to the protected segment.
+func (c *SegmentedCache) Get(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*segmentedEntry)
+	if c.clock.Now().After(entry.expiresAt) {
+		c.removeLocked(elem)
+		return nil, false
+	}
+	c.touchLocked(elem)
+	return bytes.Clone(entry.value), true
+}
+
+// Set stores value under key. A new key enters the probationary segment,
+// evicting that segment's least recently used entry if it is full;
+// replacing an existing key counts as an access. Keys failing ValidateKey
+// are rejected.
+func (c *SegmentedCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.clock.Now().Add(ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*segmentedEntry)
+		entry.value, entry.expiresAt = bytes.Clone(value), expiresAt
+		c.touchLocked(elem)
+		return nil
+	}
+
+	entry := &segmentedEntry{key: key, value: bytes.Clone(value), expiresAt: expiresAt}
+	c.entries[key] = c.probation.PushFront(entry)
+	c.trimProbationLocked()
+	return nil
+}
+
+// Delete removes key.
+func (c *SegmentedCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+	return nil
+}
+
+// Flush removes every entry.
+func (c *SegmentedCache) Flush(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	c.probation.Init()
+	c.protected.Init()
+	return nil
+}
+
+// Len returns the number of entries in the probationary and protected
+// segments, including expired entries not yet removed.
+func (c *SegmentedCache) Len() (probation, protected int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.probation.Len(), c.protected.Len()
+}
+
+// touchLocked records an access to elem, promoting a probationary entry
+// and demoting the protected segment's least recently used entry back to
+// probation if that overfills it. Callers must hold c.mu.
+func (c *SegmentedCache) touchLocked(elem *list.Element) {
+	entry := elem.Value.(*segmentedEntry)
+	if entry.protected {
+		c.protected.MoveToFront(elem)
+		return
+	}
+	if c.protectedSize == 0 {
+		c.probation.MoveToFront(elem)
+		return
+	}
+
+	c.probation.Remove(elem)
+	entry.protected = true
+	c.entries[entry.key] = c.protected.PushFront(entry)
+
+	if c.protected.Len() > c.protectedSize {
+		demoted := c.protected.Remove(c.protected.Back()).(*segmentedEntry)
+		demoted.protected = false
+		c.entries[demoted.key] = c.probation.PushFront(demoted)
+		c.trimProbationLocked()
+	}
+}
+
+// trimProbationLocked evicts least recently used probationary entries
+// until the segment fits. Callers must hold c.mu.
+func (c *SegmentedCache) trimProbationLocked() {
+	for c.probation.Len() > c.probationSize {
+		c.removeLocked(c.probation.Back())
+	}
+}
+
+// removeLocked drops elem from its segment and the index.
+// Callers must hold c.mu.
+func (c *SegmentedCache) removeLocked(elem *list.Element) {
+	entry := elem.Value.(*segmentedEntry)
+	if entry.protected {
+		c.protected.Remove(elem)
+	} else {
+		c.probation.Remove(elem)
+	}
+	delete(c.entries, entry.key)
+}
+
+var (
+	_ Cache     = (*SegmentedCache)(nil)
+	_ Flushable = (*SegmentedCache)(nil)
+)
@@ -0,0 +1,170 @@
+package toolcache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// scanWorkload warms hot keys with two reads each, scans scan one-off
+// keys through the cache, and returns how many hot keys still hit.
+func scanWorkload(t *testing.T, c Cache, hot, scan int) int {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < hot; i++ {
+		key := fmt.Sprintf("hot-%d", i)
+		if err := c.Set(ctx, key, []byte("v"), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = c.Get(ctx, key)
+	}
+	for i := 0; i < scan; i++ {
+		key := fmt.Sprintf("scan-%d", i)
+		if _, ok := c.Get(ctx, key); !ok {
+			_ = c.Set(ctx, key, []byte("v"), time.Minute)
+		}
+	}
+
+	hits := 0
+	for i := 0; i < hot; i++ {
+		if _, ok := c.Get(ctx, fmt.Sprintf("hot-%d", i)); ok {
+			hits++
+		}
+	}
+	return hits
+}
+
+func TestSegmentedCache_ScanResistance(t *testing.T) {
+	const hot, scan = 50, 500
+
+	// Same total capacity; the plain LRU never promotes.
+	segmented := NewSegmentedCache(WithProbationSize(20), WithProtectedSize(80))
+	plain := NewSegmentedCache(WithProbationSize(100), WithProtectedSize(0))
+
+	if hits := scanWorkload(t, segmented, hot, scan); hits != hot {
+		t.Errorf("segmented LRU kept %d/%d hot keys through a scan", hits, hot)
+	}
+	if hits := scanWorkload(t, plain, hot, scan); hits != 0 {
+		t.Errorf("plain LRU kept %d/%d hot keys through a scan, want 0", hits, hot)
+	}
+}
+
+func TestSegmentedCache_PromotesOnSecondAccess(t *testing.T) {
+	c := NewSegmentedCache(WithProbationSize(2), WithProtectedSize(2))
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+	if probation, protected := c.Len(); probation != 1 || protected != 0 {
+		t.Fatalf("after Set: Len() = %d, %d, want 1, 0", probation, protected)
+	}
+	if got, ok := c.Get(ctx, "a"); !ok || string(got) != "1" {
+		t.Fatalf("Get(a) = %q, %v", got, ok)
+	}
+	if probation, protected := c.Len(); probation != 0 || protected != 1 {
+		t.Errorf("after Get: Len() = %d, %d, want 0, 1", probation, protected)
+	}
+
+	// New entries evict each other, not the promoted one.
+	for _, key := range []string{"b", "c", "d"} {
+		_ = c.Set(ctx, key, []byte(key), time.Minute)
+	}
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Error("protected entry evicted by new entries")
+	}
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("oldest probationary entry should be evicted")
+	}
+}
+
+func TestSegmentedCache_DemotesToProbation(t *testing.T) {
+	c := NewSegmentedCache(WithProbationSize(2), WithProtectedSize(1))
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", []byte("a"), time.Minute)
+	_, _ = c.Get(ctx, "a") // a protected
+	_ = c.Set(ctx, "b", []byte("b"), time.Minute)
+	_, _ = c.Get(ctx, "b") // b protected, a demoted
+
+	if probation, protected := c.Len(); probation != 1 || protected != 1 {
+		t.Fatalf("Len() = %d, %d, want 1, 1", probation, protected)
+	}
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Error("demoted entry should stay cached in probation")
+	}
+}
+
+func TestSegmentedCache_Expiry(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	c := NewSegmentedCache(WithSegmentedClock(clock))
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "k", []byte("v"), time.Minute)
+	clock.Advance(time.Minute)
+	if _, ok := c.Get(ctx, "k"); !ok {
+		t.Fatal("entry expired early")
+	}
+	clock.Advance(time.Second)
+	if _, ok := c.Get(ctx, "k"); ok {
+		t.Error("expired entry served")
+	}
+	if probation, protected := c.Len(); probation+protected != 0 {
+		t.Errorf("expired entry not removed: Len() = %d, %d", probation, protected)
+	}
+
+	_ = c.Set(ctx, "zero", []byte("v"), 0)
+	if _, ok := c.Get(ctx, "zero"); ok {
+		t.Error("zero TTL should not be stored")
+	}
+}
+
+func TestSegmentedCache_SetReplacesAndCopies(t *testing.T) {
+	c := NewSegmentedCache()
+	ctx := context.Background()
+
+	value := []byte("old")
+	_ = c.Set(ctx, "k", value, time.Minute)
+	value[0] = 'X'
+	_ = c.Set(ctx, "k", []byte("new"), time.Minute)
+
+	got, ok := c.Get(ctx, "k")
+	if !ok || string(got) != "new" {
+		t.Fatalf("Get() = %q, %v, want new", got, ok)
+	}
+	got[0] = 'X'
+	if again, _ := c.Get(ctx, "k"); string(again) != "new" {
+		t.Errorf("returned slice aliases the cache: %q", again)
+	}
+	if probation, protected := c.Len(); probation+protected != 1 {
+		t.Errorf("Len() = %d, %d, want a single entry", probation, protected)
+	}
+}
+
+func TestSegmentedCache_DeleteAndFlush(t *testing.T) {
+	c := NewSegmentedCache()
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		_ = c.Set(ctx, key, []byte(key), time.Minute)
+	}
+	_, _ = c.Get(ctx, "a")
+
+	_ = c.Delete(ctx, "a")
+	_ = c.Delete(ctx, "missing")
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Error("deleted entry served")
+	}
+	if err := c.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if probation, protected := c.Len(); probation+protected != 0 {
+		t.Errorf("Len() after Flush = %d, %d", probation, protected)
+	}
+}
+
+func TestSegmentedCache_SizeOptionsClamp(t *testing.T) {
+	c := NewSegmentedCache(WithProbationSize(0), WithProtectedSize(-1))
+	if c.probationSize != 1 || c.protectedSize != 0 {
+		t.Errorf("sizes = %d, %d, want 1, 0", c.probationSize, c.protectedSize)
+	}
+}
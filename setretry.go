@@ -0,0 +1,70 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Limits applied by WithSetRetry to keep retries off the critical path.
+const (
+	MaxSetRetries    = 5
+	MaxSetRetryDelay = 100 * time.Millisecond
+)
+
+// WithSetRetry retries failed cache writes up to retries more times, waiting
+// backoff before the first retry and doubling it each time. Retries run
+// inline, so retries is capped at MaxSetRetries and each wait at
+// MaxSetRetryDelay. Retrying stops early if ctx is done, and writes
+// rejected with ErrVersionConflict, ErrInvalidKey or ErrKeyTooLong are not
+// retried.
+//
+// Writes that still fail are reported to the Observer as EventSetFailed
+// and never returned to the caller.
+func WithSetRetry(retries int, backoff time.Duration) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.setRetries = min(max(retries, 0), MaxSetRetries)
+		m.setBackoff = min(max(backoff, 0), MaxSetRetryDelay)
+	}
+}
+
+// store writes a result to the cache, retrying as configured. It returns
+// the last error if the result could not be stored.
+func (m *CacheMiddleware) store(ctx context.Context, toolID, key string, value []byte, ttl time.Duration, info *ExecInfo) error {
+	delay := m.setBackoff
+	err := m.cacheSet(ctx, key, value, ttl, info)
+	for attempt := 0; err != nil && retryableSetError(err) && attempt < m.setRetries; attempt++ {
+		if !sleepContext(ctx, delay) {
+			break
+		}
+		delay = min(2*delay, MaxSetRetryDelay)
+		err = m.cacheSet(ctx, key, value, ttl, info)
+	}
+	if err != nil {
+		m.observe(ctx, Event{Kind: EventSetFailed, ToolID: toolID, Key: key, Err: err})
+		return err
+	}
+	m.traceStored(ctx, ttl)
+	return nil
+}
+
+// retryableSetError reports whether a failed write might succeed if
+// repeated. Invalid keys and version conflicts never will.
+func retryableSetError(err error) bool {
+	return !errors.Is(err, ErrVersionConflict) && !errors.Is(err, ErrInvalidKey) && !errors.Is(err, ErrKeyTooLong)
+}
+
+// sleepContext waits for d, returning false if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
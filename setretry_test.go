@@ -0,0 +1,154 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errBackendWrite = errors.New("backend write failed")
+
+// flakyCache fails the first failures Sets, then stores into a MemoryCache.
+type flakyCache struct {
+	*MemoryCache
+	failures int
+	sets     int
+}
+
+func (c *flakyCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.sets++
+	if c.sets <= c.failures {
+		return errBackendWrite
+	}
+	return c.MemoryCache.Set(ctx, key, value, ttl)
+}
+
+func TestSetRetry_RecoversFromTransientFailure(t *testing.T) {
+	cache := &flakyCache{MemoryCache: NewMemoryCache(DefaultPolicy()), failures: 1}
+	obs := &recordingObserver{}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithSetRetry(2, time.Millisecond), WithObserver(obs))
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	if _, err := mw.Execute(ctx, "tool", 1, nil, executor.execute); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	if executor.calls != 1 {
+		t.Errorf("retried Set should make the second call a hit, got %d calls", executor.calls)
+	}
+	if cache.sets != 2 {
+		t.Errorf("Set attempts = %d, want 2", cache.sets)
+	}
+	if events := obs.byKind(EventSetFailed); len(events) != 0 {
+		t.Errorf("recovered Set should not be reported, got %+v", events)
+	}
+}
+
+func TestSetRetry_PersistentFailureObserved(t *testing.T) {
+	cache := &flakyCache{MemoryCache: NewMemoryCache(DefaultPolicy()), failures: 100}
+	obs := &recordingObserver{}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithSetRetry(2, time.Millisecond), WithObserver(obs))
+	executor := &mockExecutor{result: []byte("v")}
+
+	got, err := mw.Execute(context.Background(), "tool", 1, nil, executor.execute)
+	if err != nil || string(got) != "v" {
+		t.Fatalf("Set failures must not reach the caller: %q, %v", got, err)
+	}
+	if cache.sets != 3 {
+		t.Errorf("Set attempts = %d, want 1 + 2 retries", cache.sets)
+	}
+	events := obs.byKind(EventSetFailed)
+	if len(events) != 1 || !errors.Is(events[0].Err, errBackendWrite) || events[0].ToolID != "tool" {
+		t.Errorf("expected one EventSetFailed, got %+v", events)
+	}
+	if got := mw.Stats().BytesStored; got != 0 {
+		t.Errorf("BytesStored = %d, want 0 for a failed Set", got)
+	}
+}
+
+func TestSetRetry_DisabledByDefault(t *testing.T) {
+	cache := &flakyCache{MemoryCache: NewMemoryCache(DefaultPolicy()), failures: 1}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := &mockExecutor{result: []byte("v")}
+
+	_, _ = mw.Execute(context.Background(), "tool", 1, nil, executor.execute)
+	if cache.sets != 1 {
+		t.Errorf("Set attempts = %d, want 1 without WithSetRetry", cache.sets)
+	}
+}
+
+func TestSetRetry_StopsOnContextDone(t *testing.T) {
+	cache := &flakyCache{MemoryCache: NewMemoryCache(DefaultPolicy()), failures: 100}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithSetRetry(MaxSetRetries, MaxSetRetryDelay))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _ = mw.Execute(ctx, "tool", 1, nil, func(context.Context, string, any) ([]byte, error) {
+		return []byte("v"), nil
+	})
+	if elapsed := time.Since(start); elapsed > 80*time.Millisecond {
+		t.Errorf("retries should stop when the context ends, took %v", elapsed)
+	}
+}
+
+func TestWithSetRetry_Caps(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), nil, DefaultPolicy(), nil,
+		WithSetRetry(100, time.Hour))
+	if mw.setRetries != MaxSetRetries || mw.setBackoff != MaxSetRetryDelay {
+		t.Errorf("retries = %d, backoff = %v; want caps", mw.setRetries, mw.setBackoff)
+	}
+}
+
+func TestSetRetry_WarmAll(t *testing.T) {
+	cache := &flakyCache{MemoryCache: NewMemoryCache(DefaultPolicy()), failures: 1}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithSetRetry(1, time.Millisecond))
+	executor := &mockExecutor{result: []byte("v")}
+
+	results := mw.WarmAll(context.Background(), []WarmRequest{{ToolID: "tool", Input: 1}}, executor.execute)
+	if results[0].Status != WarmStored || cache.sets != 2 {
+		t.Errorf("WarmAll() = %+v after %d Set attempts, want stored on the retry", results[0], cache.sets)
+	}
+
+	cache = &flakyCache{MemoryCache: NewMemoryCache(DefaultPolicy()), failures: 10}
+	obs := &recordingObserver{}
+	mw = NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil, WithObserver(obs))
+	results = mw.WarmAll(context.Background(), []WarmRequest{{ToolID: "tool", Input: 1}}, executor.execute)
+	if results[0].Status != WarmFailed || !errors.Is(results[0].Err, errBackendWrite) {
+		t.Errorf("WarmAll() = %+v, want the write error", results[0])
+	}
+	if events := obs.byKind(EventSetFailed); len(events) != 1 {
+		t.Errorf("failed warm-up write should be reported once, got %+v", events)
+	}
+}
+
+func TestSetRetry_SkipsInvalidKeys(t *testing.T) {
+	var sets int
+	cache := CacheFuncs{
+		SetFunc: func(context.Context, string, []byte, time.Duration) error {
+			sets++
+			return fmt.Errorf("%w: backend forbids ':'", ErrInvalidKey)
+		},
+	}
+	obs := &recordingObserver{}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithSetRetry(MaxSetRetries, MaxSetRetryDelay), WithObserver(obs))
+	executor := &mockExecutor{result: []byte("v")}
+
+	if _, err := mw.Execute(context.Background(), "tool", 1, nil, executor.execute); err != nil {
+		t.Fatal(err)
+	}
+	if sets != 1 {
+		t.Errorf("Set attempts = %d for a rejected key, want 1", sets)
+	}
+	if events := obs.byKind(EventSetFailed); len(events) != 1 {
+		t.Errorf("rejected Set should be reported once, got %+v", events)
+	}
+}
@@ -0,0 +1,71 @@
+package toolcache
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+)
+
+// EntrySize reports the value size of one cached entry.
+type EntrySize struct {
+	Key  string `json:"key"`
+	Size int    `json:"size"`
+}
+
+// LargestEntries returns the n largest non-expired entries by value size,
+// largest first, to help find tools producing oversized outputs. Ties are
+// ordered by key. Values are not copied; the scan keeps a heap of n
+// candidates under the read lock, so it costs O(entries * log n). Thunks
+// that have not been computed yet have no size and are left out.
+func (c *MemoryCache) LargestEntries(ctx context.Context, n int) ([]EntrySize, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+
+	now := c.clock.Now()
+
+	c.mu.RLock()
+	top := make(sizeHeap, 0, min(n, len(c.entries)))
+	for key, entry := range c.entries {
+		if c.staleLocked(key, entry, now) || entry.thunk != nil {
+			continue
+		}
+		candidate := EntrySize{Key: key, Size: len(entry.value)}
+		if len(top) < n {
+			heap.Push(&top, candidate)
+		} else if top.less(top[0], candidate) {
+			top[0] = candidate
+			heap.Fix(&top, 0)
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(top, func(i, j int) bool { return top.less(top[j], top[i]) })
+	return top, nil
+}
+
+// sizeHeap is a min-heap of entries, so the smallest candidate is evicted
+// first when a larger entry is found.
+type sizeHeap []EntrySize
+
+// less orders by size, breaking ties so that earlier keys rank larger.
+func (h sizeHeap) less(a, b EntrySize) bool {
+	if a.Size != b.Size {
+		return a.Size < b.Size
+	}
+	return a.Key > b.Key
+}
+
+func (h sizeHeap) Len() int           { return len(h) }
+func (h sizeHeap) Less(i, j int) bool { return h.less(h[i], h[j]) }
+func (h sizeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *sizeHeap) Push(x any)        { *h = append(*h, x.(EntrySize)) }
+func (h *sizeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
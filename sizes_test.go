@@ -0,0 +1,63 @@
+package toolcache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLargestEntries(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(clock))
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("k%02d", i), []byte(strings.Repeat("x", i)), time.Hour)
+	}
+	_ = cache.Set(ctx, "huge-expiring", make([]byte, 1000), time.Minute)
+	_ = cache.SetThunk(ctx, "lazy", func(context.Context) ([]byte, error) { return make([]byte, 500), nil }, time.Hour, time.Hour)
+	clock.Advance(2 * time.Minute)
+
+	got, err := cache.LargestEntries(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EntrySize{{"k19", 19}, {"k18", 18}, {"k17", 17}}
+	if !slices.Equal(got, want) {
+		t.Errorf("LargestEntries(3) = %v, want %v", got, want)
+	}
+
+	all, _ := cache.LargestEntries(ctx, 100)
+	if len(all) != 20 || all[19] != (EntrySize{"k00", 0}) {
+		t.Errorf("LargestEntries(100) returned %d entries ending %v", len(all), all[len(all)-1])
+	}
+}
+
+func TestLargestEntries_TiesAndEdges(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	for _, key := range []string{"c", "a", "b"} {
+		_ = cache.Set(ctx, key, []byte("same"), time.Hour)
+	}
+
+	got, _ := cache.LargestEntries(ctx, 2)
+	if want := []EntrySize{{"a", 4}, {"b", 4}}; !slices.Equal(got, want) {
+		t.Errorf("ties = %v, want %v", got, want)
+	}
+	if got, _ := cache.LargestEntries(ctx, 0); got != nil {
+		t.Errorf("LargestEntries(0) = %v, want nil", got)
+	}
+	if got, _ := cache.LargestEntries(ctx, math.MaxInt); len(got) != 3 || cap(got) != 3 {
+		t.Errorf("LargestEntries(MaxInt) = %v with cap %d, want all 3 entries", got, cap(got))
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := cache.LargestEntries(canceled, 1); err == nil {
+		t.Error("expected context error")
+	}
+}
@@ -0,0 +1,70 @@
+package toolcache
+
+import "strings"
+
+// SkipConfig declares a SkipRule in configuration rather than code. It
+// encodes naturally as JSON or YAML; build the rule with BuildSkipRule.
+//
+// A tool is skipped (not cached) when any of these holds:
+// - DenyTools: it is listed.
+// - DenyToolPrefixes: its ID starts with a listed prefix.
+// - AllowTools: the list is non-empty and the tool is not in it.
+// - UnsafeTags: one of its tags matches, case-insensitively.
+type SkipConfig struct {
+	// UnsafeTags are tags marking a tool as unsafe to cache. When nil,
+	// DefaultUnsafeTags is used; set it to an empty list to ignore tags.
+	UnsafeTags []string `json:"unsafe_tags,omitempty" yaml:"unsafe_tags,omitempty"`
+
+	// AllowTools, when non-empty, restricts caching to these tools.
+	AllowTools []string `json:"allow_tools,omitempty" yaml:"allow_tools,omitempty"`
+
+	// DenyTools are never cached.
+	DenyTools []string `json:"deny_tools,omitempty" yaml:"deny_tools,omitempty"`
+
+	// DenyToolPrefixes exclude whole namespaces, e.g. "admin:".
+	DenyToolPrefixes []string `json:"deny_tool_prefixes,omitempty" yaml:"deny_tool_prefixes,omitempty"`
+}
+
+// BuildSkipRule assembles the SkipRule described by cfg. The config is
+// copied, so later changes to cfg do not affect the rule.
+func BuildSkipRule(cfg SkipConfig) SkipRule {
+	unsafeTags := cfg.UnsafeTags
+	if unsafeTags == nil {
+		unsafeTags = DefaultUnsafeTags
+	}
+	unsafe := make(map[string]bool, len(unsafeTags))
+	for _, tag := range unsafeTags {
+		unsafe[strings.ToLower(tag)] = true
+	}
+	allow := toSet(cfg.AllowTools)
+	deny := toSet(cfg.DenyTools)
+	prefixes := append([]string(nil), cfg.DenyToolPrefixes...)
+
+	return func(toolID string, tags []string) bool {
+		if deny[toolID] {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(toolID, prefix) {
+				return true
+			}
+		}
+		if len(allow) > 0 && !allow[toolID] {
+			return true
+		}
+		for _, tag := range tags {
+			if unsafe[strings.ToLower(tag)] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
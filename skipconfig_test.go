@@ -0,0 +1,99 @@
+package toolcache
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildSkipRule(t *testing.T) {
+	type call struct {
+		toolID string
+		tags   []string
+		skip   bool
+	}
+	tests := []struct {
+		name  string
+		cfg   SkipConfig
+		calls []call
+	}{
+		{
+			name: "zero config matches DefaultSkipRule",
+			cfg:  SkipConfig{},
+			calls: []call{
+				{"t", []string{"read"}, false},
+				{"t", []string{"WRITE"}, true},
+				{"t", nil, false},
+			},
+		},
+		{
+			name: "custom unsafe tags",
+			cfg:  SkipConfig{UnsafeTags: []string{"Billing"}},
+			calls: []call{
+				{"t", []string{"billing"}, true},
+				{"t", []string{"write"}, false},
+			},
+		},
+		{
+			name: "empty unsafe tags ignores tags",
+			cfg:  SkipConfig{UnsafeTags: []string{}},
+			calls: []call{
+				{"t", []string{"write"}, false},
+			},
+		},
+		{
+			name: "deny lists",
+			cfg:  SkipConfig{DenyTools: []string{"ns:now"}, DenyToolPrefixes: []string{"admin:"}},
+			calls: []call{
+				{"ns:now", nil, true},
+				{"admin:reset", nil, true},
+				{"ns:search", nil, false},
+			},
+		},
+		{
+			name: "allow list",
+			cfg:  SkipConfig{AllowTools: []string{"ns:search"}},
+			calls: []call{
+				{"ns:search", nil, false},
+				{"ns:search", []string{"write"}, true},
+				{"ns:other", nil, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := BuildSkipRule(tt.cfg)
+			for _, c := range tt.calls {
+				if got := rule(c.toolID, c.tags); got != c.skip {
+					t.Errorf("rule(%q, %v) = %v, want %v", c.toolID, c.tags, got, c.skip)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildSkipRule_FromJSON(t *testing.T) {
+	doc := `{"unsafe_tags":["write"],"deny_tools":["ns:clock"],"deny_tool_prefixes":["admin:"]}`
+	var cfg SkipConfig
+	if err := json.Unmarshal([]byte(doc), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	rule := BuildSkipRule(cfg)
+
+	if !rule("ns:clock", nil) || !rule("admin:x", nil) || !rule("t", []string{"write"}) {
+		t.Error("configured skips not applied")
+	}
+	if rule("t", []string{"delete"}) {
+		t.Error("explicit unsafe_tags should replace the defaults")
+	}
+}
+
+func TestBuildSkipRule_CopiesConfig(t *testing.T) {
+	cfg := SkipConfig{DenyTools: []string{"a"}, DenyToolPrefixes: []string{"x:"}}
+	rule := BuildSkipRule(cfg)
+	cfg.DenyTools[0] = "b"
+	cfg.DenyToolPrefixes[0] = "y:"
+
+	if !rule("a", nil) || !rule("x:1", nil) || rule("b", nil) {
+		t.Error("rule should not observe later config changes")
+	}
+}
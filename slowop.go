@@ -0,0 +1,80 @@
+package toolcache
+
+import (
+	"context"
+	"time"
+)
+
+// SlowOpCache wraps a Cache and reports operations slower than a threshold
+// to an Observer as EventSlowCacheOp, surfacing latency spikes in remote
+// backends. Calls are otherwise passed through unchanged.
+type SlowOpCache struct {
+	cache     Cache
+	threshold time.Duration
+	observer  Observer
+}
+
+// NewSlowOpCache wraps cache, reporting operations that take longer than
+// threshold to observer. A nil observer reports nothing.
+func NewSlowOpCache(cache Cache, threshold time.Duration, observer Observer) *SlowOpCache {
+	return &SlowOpCache{cache: cache, threshold: threshold, observer: observer}
+}
+
+func (c *SlowOpCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	start := time.Now()
+	value, ok := c.cache.Get(ctx, key)
+	c.check(ctx, "get", key, start, nil)
+	return value, ok
+}
+
+func (c *SlowOpCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	start := time.Now()
+	err := c.cache.Set(ctx, key, value, ttl)
+	c.check(ctx, "set", key, start, err)
+	return err
+}
+
+func (c *SlowOpCache) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := c.cache.Delete(ctx, key)
+	c.check(ctx, "delete", key, start, err)
+	return err
+}
+
+// GetWithMeta calls the wrapped cache's GetWithMeta, or Get with empty
+// metadata if it is not a MetaCache.
+func (c *SlowOpCache) GetWithMeta(ctx context.Context, key string) ([]byte, EntryMeta, bool) {
+	mc, ok := c.cache.(MetaCache)
+	if !ok {
+		value, ok := c.Get(ctx, key)
+		return value, EntryMeta{}, ok
+	}
+	start := time.Now()
+	value, meta, ok := mc.GetWithMeta(ctx, key)
+	c.check(ctx, "get", key, start, nil)
+	return value, meta, ok
+}
+
+// SetWithMeta calls the wrapped cache's SetWithMeta, or Set dropping meta
+// if it is not a MetaCache.
+func (c *SlowOpCache) SetWithMeta(ctx context.Context, key string, value []byte, meta EntryMeta, ttl time.Duration) error {
+	mc, ok := c.cache.(MetaCache)
+	if !ok {
+		return c.Set(ctx, key, value, ttl)
+	}
+	start := time.Now()
+	err := mc.SetWithMeta(ctx, key, value, meta, ttl)
+	c.check(ctx, "set", key, start, err)
+	return err
+}
+
+func (c *SlowOpCache) check(ctx context.Context, op, key string, start time.Time, err error) {
+	if c.observer == nil {
+		return
+	}
+	if elapsed := time.Since(start); elapsed > c.threshold {
+		c.observer.Observe(ctx, Event{Kind: EventSlowCacheOp, Op: op, Key: key, Duration: elapsed, Err: err})
+	}
+}
+
+var _ MetaCache = (*SlowOpCache)(nil)
@@ -0,0 +1,82 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowCache delays Sets by setDelay and returns setErr.
+type slowCache struct {
+	*MemoryCache
+	setDelay time.Duration
+	setErr   error
+}
+
+func (c *slowCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	time.Sleep(c.setDelay)
+	if c.setErr != nil {
+		return c.setErr
+	}
+	return c.MemoryCache.Set(ctx, key, value, ttl)
+}
+
+func TestSlowOpCache_ReportsOnlyAboveThreshold(t *testing.T) {
+	backend := &slowCache{MemoryCache: NewMemoryCache(DefaultPolicy()), setDelay: 30 * time.Millisecond}
+	obs := &recordingObserver{}
+	cache := NewSlowOpCache(backend, 10*time.Millisecond, obs)
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "k", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := cache.Get(ctx, "k"); !ok || string(got) != "v" {
+		t.Fatalf("Get() = %q, %v", got, ok)
+	}
+	_ = cache.Delete(ctx, "k")
+
+	events := obs.byKind(EventSlowCacheOp)
+	if len(events) != 1 {
+		t.Fatalf("slow events = %+v, want only the Set", events)
+	}
+	ev := events[0]
+	if ev.Op != "set" || ev.Key != "k" || ev.Duration < 30*time.Millisecond || ev.Err != nil {
+		t.Errorf("event = %+v", ev)
+	}
+}
+
+func TestSlowOpCache_ReportsError(t *testing.T) {
+	boom := errors.New("backend down")
+	backend := &slowCache{MemoryCache: NewMemoryCache(DefaultPolicy()), setDelay: 5 * time.Millisecond, setErr: boom}
+	obs := &recordingObserver{}
+	cache := NewSlowOpCache(backend, time.Millisecond, obs)
+
+	if err := cache.Set(context.Background(), "k", nil, time.Minute); !errors.Is(err, boom) {
+		t.Fatalf("Set() = %v, want the backend error", err)
+	}
+	if events := obs.byKind(EventSlowCacheOp); len(events) != 1 || !errors.Is(events[0].Err, boom) {
+		t.Errorf("events = %+v, want the error attached", events)
+	}
+}
+
+func TestSlowOpCache_MiddlewareKeepsMeta(t *testing.T) {
+	backend := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(NewSlowOpCache(backend, time.Hour, nil), NewDefaultKeyer(), DefaultPolicy(), nil)
+	executor := func(context.Context, string, any) ([]byte, EntryMeta, error) {
+		return []byte("{}"), EntryMeta{ContentType: "application/json"}, nil
+	}
+	ctx := context.Background()
+
+	_, _, _ = mw.ExecuteWithInfo(ctx, "tool", 1, nil, executor)
+	_, info, _ := mw.ExecuteWithInfo(ctx, "tool", 1, nil, executor)
+	if !info.Cached || info.Meta.ContentType != "application/json" {
+		t.Errorf("info = %+v, want metadata preserved through the wrapper", info)
+	}
+}
+
+func TestEventSlowCacheOp_String(t *testing.T) {
+	if got := EventSlowCacheOp.String(); got != "slow_cache_op" {
+		t.Errorf("String() = %q", got)
+	}
+}
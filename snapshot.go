@@ -0,0 +1,296 @@
+package toolcache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// snapshotVersion is the current snapshot format version.
+const snapshotVersion = 1
+
+// gzipMagic is the two-byte header that starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+type snapshotFile struct {
+	Version   int             `json:"version"`
+	KeyFormat string          `json:"key_format,omitempty"`
+	Entries   []snapshotEntry `json:"entries"`
+}
+
+type snapshotEntry struct {
+	Key         string    `json:"key"`
+	Value       []byte    `json:"value"`
+	ContentType string    `json:"content_type,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+	Version     uint64    `json:"version,omitempty"`
+	Priority    int       `json:"priority,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	LastAccess  time.Time `json:"last_access,omitzero"`
+	AccessCount uint64    `json:"access_count,omitempty"`
+}
+
+// EntryInfo describes a cached entry without exposing its value.
+type EntryInfo struct {
+	Key       string    `json:"key"`
+	Size      int       `json:"size"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Fingerprint is a hex-encoded hash of the value, suitable for
+	// comparing values across caches without exposing them.
+	Fingerprint string `json:"fingerprint"`
+
+	// LastAccess and AccessCount report when the entry was last read and
+	// how many times. They are only populated under WithAccessTracking;
+	// LastAccess is zero for entries never read.
+	LastAccess  time.Time `json:"last_access,omitzero"`
+	AccessCount uint64    `json:"access_count,omitempty"`
+}
+
+// fingerprint returns a short content hash of value.
+func fingerprint(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:16])
+}
+
+// Snapshot returns metadata for all non-expired entries, sorted by key.
+// The result is a point-in-time copy taken under the read lock; later
+// writes are not reflected in it.
+func (c *MemoryCache) Snapshot(ctx context.Context) ([]EntryInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := c.clock.Now()
+
+	c.mu.RLock()
+	infos := make([]EntryInfo, 0, len(c.entries))
+	for key, entry := range c.entries {
+		if c.staleLocked(key, entry, now) || entry.thunk != nil {
+			continue
+		}
+		lastAccess, accessCount := entry.access.load()
+		infos = append(infos, EntryInfo{
+			Key:         key,
+			Size:        len(entry.value),
+			ExpiresAt:   entry.expiresAt,
+			Fingerprint: fingerprint(entry.value),
+			LastAccess:  lastAccess,
+			AccessCount: accessCount,
+		})
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos, nil
+}
+
+// SnapshotDiff reports how two snapshots differ, by key.
+type SnapshotDiff struct {
+	// OnlyInA lists keys present in a but not in b.
+	OnlyInA []string
+
+	// OnlyInB lists keys present in b but not in a.
+	OnlyInB []string
+
+	// Changed lists keys present in both whose value fingerprints differ.
+	Changed []string
+}
+
+// Empty reports whether the snapshots hold the same keys and values.
+func (d SnapshotDiff) Empty() bool {
+	return len(d.OnlyInA) == 0 && len(d.OnlyInB) == 0 && len(d.Changed) == 0
+}
+
+// DiffSnapshots compares two snapshots by key. Values are compared by
+// Fingerprint so no value bytes are needed; expiry differences are ignored.
+// All returned key lists are sorted.
+func DiffSnapshots(a, b []EntryInfo) SnapshotDiff {
+	inB := make(map[string]string, len(b))
+	for _, info := range b {
+		inB[info.Key] = info.Fingerprint
+	}
+
+	var diff SnapshotDiff
+	inA := make(map[string]bool, len(a))
+	for _, info := range a {
+		inA[info.Key] = true
+		fp, ok := inB[info.Key]
+		switch {
+		case !ok:
+			diff.OnlyInA = append(diff.OnlyInA, info.Key)
+		case fp != info.Fingerprint:
+			diff.Changed = append(diff.Changed, info.Key)
+		}
+	}
+	for _, info := range b {
+		if !inA[info.Key] {
+			diff.OnlyInB = append(diff.OnlyInB, info.Key)
+		}
+	}
+
+	sort.Strings(diff.OnlyInA)
+	sort.Strings(diff.OnlyInB)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// Range calls fn for each non-expired entry, in key order, until fn returns
+// false. Iteration works on a point-in-time view taken before the first
+// call, so fn (or other goroutines) may safely Set or Delete entries while
+// iterating. Entries deleted after the view was taken are still visited;
+// entries added afterwards are not.
+func (c *MemoryCache) Range(ctx context.Context, fn func(EntryInfo) bool) error {
+	infos, err := c.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !fn(info) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// WriteSnapshot writes all non-expired entries to w as JSON.
+func (c *MemoryCache) WriteSnapshot(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := c.clock.Now()
+	snap := snapshotFile{Version: snapshotVersion}
+	if c.keyFormat != nil {
+		snap.KeyFormat = c.keyFormat.KeyFormat()
+	}
+
+	c.mu.RLock()
+	for key, entry := range c.entries {
+		if c.staleLocked(key, entry, now) || entry.thunk != nil {
+			continue
+		}
+		value := entry.value
+		if entry.buf != nil {
+			// Pooled buffers may be reused once the lock is released.
+			value = bytes.Clone(value)
+		}
+		lastAccess, accessCount := entry.access.load()
+		snap.Entries = append(snap.Entries, snapshotEntry{
+			Key:         key,
+			Value:       value,
+			ContentType: entry.meta.ContentType,
+			ETag:        entry.meta.ETag,
+			Version:     entry.meta.Version,
+			Priority:    entry.meta.Priority,
+			ExpiresAt:   entry.expiresAt,
+			LastAccess:  lastAccess,
+			AccessCount: accessCount,
+		})
+	}
+	c.mu.RUnlock()
+
+	if err := json.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("toolcache: failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// WriteSnapshotGzip writes a gzip-compressed snapshot to w.
+func (c *MemoryCache) WriteSnapshotGzip(ctx context.Context, w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if err := c.WriteSnapshot(ctx, zw); err != nil {
+		_ = zw.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("toolcache: failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshot imports entries from a snapshot written by WriteSnapshot.
+// Entries that have expired since the snapshot was taken are skipped, and
+// existing entries with the same key are overwritten. It returns the number
+// of entries imported. With WithKeyFormat, a snapshot written under another
+// key format is refused with ErrKeyFormatMismatch and nothing is imported.
+func (c *MemoryCache) ReadSnapshot(ctx context.Context, r io.Reader) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var snap snapshotFile
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return 0, fmt.Errorf("toolcache: failed to read snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return 0, fmt.Errorf("toolcache: unsupported snapshot version %d", snap.Version)
+	}
+	if err := c.checkKeyFormat(snap.KeyFormat); err != nil {
+		return 0, err
+	}
+	for _, entry := range snap.Entries {
+		if err := ValidateKey(entry.Key); err != nil {
+			return 0, fmt.Errorf("toolcache: snapshot key %q: %w", entry.Key, err)
+		}
+	}
+
+	now := c.clock.Now()
+	imported := 0
+
+	c.mu.Lock()
+	for _, entry := range snap.Entries {
+		if c.expired(entry.ExpiresAt, now) {
+			continue
+		}
+		restored := &cacheEntry{
+			value: entry.Value,
+			meta: EntryMeta{
+				ContentType: entry.ContentType,
+				ETag:        entry.ETag,
+				Version:     entry.Version,
+				Priority:    entry.Priority,
+			},
+			expiresAt: entry.ExpiresAt,
+		}
+		restored.access.restore(entry.LastAccess, entry.AccessCount)
+		c.storeLocked(entry.Key, restored)
+		imported++
+	}
+	c.mu.Unlock()
+
+	return imported, nil
+}
+
+// ReadSnapshotGzip imports a snapshot that may be gzip-compressed.
+// Compression is detected from the gzip magic bytes, so plain snapshots
+// are accepted as well.
+func (c *MemoryCache) ReadSnapshotGzip(ctx context.Context, r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("toolcache: failed to read snapshot: %w", err)
+	}
+	if !bytes.Equal(header, gzipMagic) {
+		return c.ReadSnapshot(ctx, br)
+	}
+
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return 0, fmt.Errorf("toolcache: failed to read snapshot: %w", err)
+	}
+	defer zr.Close()
+
+	return c.ReadSnapshot(ctx, zr)
+}
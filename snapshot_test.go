@@ -0,0 +1,367 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func populatedCache(t *testing.T) *MemoryCache {
+	t.Helper()
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	entries := map[string]string{
+		"toolcache:a:1": "alpha",
+		"toolcache:b:2": "bravo",
+		"toolcache:c:3": strings.Repeat("charlie", 1000),
+	}
+	for key, value := range entries {
+		if err := cache.Set(ctx, key, []byte(value), 5*time.Minute); err != nil {
+			t.Fatalf("Set(%q) failed: %v", key, err)
+		}
+	}
+	return cache
+}
+
+func assertSameEntries(t *testing.T, src, dst *MemoryCache) {
+	t.Helper()
+	ctx := context.Background()
+	for key, entry := range src.entries {
+		got, ok := dst.Get(ctx, key)
+		if !ok {
+			t.Errorf("key %q missing after import", key)
+			continue
+		}
+		if !bytes.Equal(got, entry.value) {
+			t.Errorf("key %q = %q, want %q", key, got, entry.value)
+		}
+		if !dst.entries[key].expiresAt.Equal(entry.expiresAt) {
+			t.Errorf("key %q expiry = %v, want %v", key, dst.entries[key].expiresAt, entry.expiresAt)
+		}
+	}
+	if len(dst.entries) != len(src.entries) {
+		t.Errorf("imported %d entries, want %d", len(dst.entries), len(src.entries))
+	}
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := populatedCache(t)
+
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+
+	dst := NewMemoryCache(DefaultPolicy())
+	n, err := dst.ReadSnapshot(ctx, &buf)
+	if err != nil {
+		t.Fatalf("ReadSnapshot failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("ReadSnapshot imported %d entries, want 3", n)
+	}
+	assertSameEntries(t, src, dst)
+}
+
+func TestSnapshot_GzipRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := populatedCache(t)
+
+	var plain, compressed bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &plain); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	if err := src.WriteSnapshotGzip(ctx, &compressed); err != nil {
+		t.Fatalf("WriteSnapshotGzip failed: %v", err)
+	}
+	if !bytes.HasPrefix(compressed.Bytes(), gzipMagic) {
+		t.Fatal("gzip snapshot should start with gzip magic bytes")
+	}
+	if compressed.Len() >= plain.Len() {
+		t.Errorf("gzip snapshot (%d bytes) should be smaller than plain (%d bytes)", compressed.Len(), plain.Len())
+	}
+
+	dst := NewMemoryCache(DefaultPolicy())
+	n, err := dst.ReadSnapshotGzip(ctx, &compressed)
+	if err != nil {
+		t.Fatalf("ReadSnapshotGzip failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("ReadSnapshotGzip imported %d entries, want 3", n)
+	}
+	assertSameEntries(t, src, dst)
+}
+
+func TestSnapshot_GzipReaderAcceptsPlain(t *testing.T) {
+	ctx := context.Background()
+	src := populatedCache(t)
+
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+
+	dst := NewMemoryCache(DefaultPolicy())
+	if _, err := dst.ReadSnapshotGzip(ctx, &buf); err != nil {
+		t.Fatalf("ReadSnapshotGzip on plain snapshot failed: %v", err)
+	}
+	assertSameEntries(t, src, dst)
+}
+
+func TestSnapshot_SkipsExpired(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryCache(DefaultPolicy())
+	_ = src.Set(ctx, "short", []byte("short"), 50*time.Millisecond)
+	_ = src.Set(ctx, "long", []byte("long"), 5*time.Minute)
+
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	dst := NewMemoryCache(DefaultPolicy())
+	n, err := dst.ReadSnapshot(ctx, &buf)
+	if err != nil {
+		t.Fatalf("ReadSnapshot failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("ReadSnapshot imported %d entries, want 1", n)
+	}
+	if _, ok := dst.Get(ctx, "short"); ok {
+		t.Error("expired entry should not be imported")
+	}
+}
+
+func TestSnapshot_InvalidInput(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(DefaultPolicy())
+
+	if _, err := cache.ReadSnapshot(ctx, strings.NewReader("not json")); err == nil {
+		t.Error("expected error for malformed snapshot")
+	}
+	if _, err := cache.ReadSnapshot(ctx, strings.NewReader(`{"version":99}`)); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+
+	badKey := `{"version":1,"entries":[{"key":"bad\nkey","value":"eA==","expires_at":"2999-01-01T00:00:00Z"}]}`
+	_, err := cache.ReadSnapshot(ctx, strings.NewReader(badKey))
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+	if len(cache.entries) != 0 {
+		t.Error("invalid snapshot should not import any entries")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := cache.WriteSnapshot(canceled, &bytes.Buffer{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSnapshot_PreservesNilValues(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryCache(DefaultPolicy())
+	_ = src.Set(ctx, "nil", nil, 5*time.Minute)
+
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+
+	dst := NewMemoryCache(DefaultPolicy())
+	if _, err := dst.ReadSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("ReadSnapshot failed: %v", err)
+	}
+	got, ok := dst.Get(ctx, "nil")
+	if !ok {
+		t.Fatal("imported nil entry should be a hit")
+	}
+	if got != nil {
+		t.Errorf("imported nil entry = %q, want nil", got)
+	}
+}
+
+func TestSnapshot_EntryInfo(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(DefaultPolicy())
+	_ = cache.Set(ctx, "b", []byte("bravo"), time.Minute)
+	_ = cache.Set(ctx, "a", []byte("al"), time.Minute)
+	_ = cache.Set(ctx, "expired", []byte("x"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	infos, err := cache.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("Snapshot returned %d entries, want 2", len(infos))
+	}
+	if infos[0].Key != "a" || infos[0].Size != 2 {
+		t.Errorf("infos[0] = %+v, want key a size 2", infos[0])
+	}
+	if infos[1].Key != "b" || infos[1].Size != 5 {
+		t.Errorf("infos[1] = %+v, want key b size 5", infos[1])
+	}
+	if infos[0].ExpiresAt.IsZero() {
+		t.Error("ExpiresAt should be populated")
+	}
+}
+
+func TestRange_StopsEarly(t *testing.T) {
+	ctx := context.Background()
+	cache := populatedCache(t)
+
+	var visited []string
+	err := cache.Range(ctx, func(info EntryInfo) bool {
+		visited = append(visited, info.Key)
+		return len(visited) < 2
+	})
+	if err != nil {
+		t.Fatalf("Range failed: %v", err)
+	}
+	if len(visited) != 2 || visited[0] != "toolcache:a:1" || visited[1] != "toolcache:b:2" {
+		t.Errorf("visited = %v, want first two keys in order", visited)
+	}
+}
+
+func TestRange_DeleteDuringIteration(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(DefaultPolicy())
+	const n = 500
+	for i := 0; i < n; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("key-%03d", i), []byte("v"), time.Minute)
+	}
+
+	// A concurrent writer deletes and re-adds entries throughout iteration.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			key := fmt.Sprintf("key-%03d", i%n)
+			_ = cache.Delete(ctx, key)
+			_ = cache.Set(ctx, fmt.Sprintf("new-%d", i), []byte("v"), time.Minute)
+		}
+	}()
+
+	// The callback itself deletes every entry it visits.
+	seen := make(map[string]bool)
+	prev := ""
+	err := cache.Range(ctx, func(info EntryInfo) bool {
+		if seen[info.Key] {
+			t.Errorf("key %q visited twice", info.Key)
+		}
+		if info.Key <= prev {
+			t.Errorf("keys out of order: %q after %q", info.Key, prev)
+		}
+		seen[info.Key] = true
+		prev = info.Key
+		_ = cache.Delete(ctx, info.Key)
+		return true
+	})
+	close(stop)
+	wg.Wait()
+
+	if err != nil {
+		t.Fatalf("Range failed: %v", err)
+	}
+	if len(seen) == 0 {
+		t.Error("expected Range to visit entries")
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	ctx := context.Background()
+	a := NewMemoryCache(DefaultPolicy())
+	b := NewMemoryCache(DefaultPolicy())
+
+	_ = a.Set(ctx, "shared-same", []byte("v1"), time.Minute)
+	_ = b.Set(ctx, "shared-same", []byte("v1"), 2*time.Minute) // expiry differs only
+	_ = a.Set(ctx, "shared-changed", []byte("old"), time.Minute)
+	_ = b.Set(ctx, "shared-changed", []byte("new"), time.Minute)
+	_ = a.Set(ctx, "only-a", []byte("x"), time.Minute)
+	_ = b.Set(ctx, "only-b-2", []byte("y"), time.Minute)
+	_ = b.Set(ctx, "only-b-1", []byte("z"), time.Minute)
+
+	snapA, _ := a.Snapshot(ctx)
+	snapB, _ := b.Snapshot(ctx)
+
+	diff := DiffSnapshots(snapA, snapB)
+	if diff.Empty() {
+		t.Fatal("diff should not be empty")
+	}
+	if len(diff.OnlyInA) != 1 || diff.OnlyInA[0] != "only-a" {
+		t.Errorf("OnlyInA = %v", diff.OnlyInA)
+	}
+	if len(diff.OnlyInB) != 2 || diff.OnlyInB[0] != "only-b-1" || diff.OnlyInB[1] != "only-b-2" {
+		t.Errorf("OnlyInB = %v", diff.OnlyInB)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0] != "shared-changed" {
+		t.Errorf("Changed = %v", diff.Changed)
+	}
+}
+
+func TestDiffSnapshots_IdenticalAndDisjoint(t *testing.T) {
+	ctx := context.Background()
+	cache := populatedCache(t)
+	snap, _ := cache.Snapshot(ctx)
+
+	if diff := DiffSnapshots(snap, snap); !diff.Empty() {
+		t.Errorf("identical snapshots should have empty diff, got %+v", diff)
+	}
+
+	diff := DiffSnapshots(snap, nil)
+	if len(diff.OnlyInA) != len(snap) || len(diff.OnlyInB) != 0 || len(diff.Changed) != 0 {
+		t.Errorf("disjoint diff = %+v", diff)
+	}
+	diff = DiffSnapshots(nil, snap)
+	if len(diff.OnlyInB) != len(snap) || len(diff.OnlyInA) != 0 {
+		t.Errorf("disjoint diff = %+v", diff)
+	}
+}
+
+func TestSnapshot_FingerprintHidesValue(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(DefaultPolicy())
+	_ = cache.Set(ctx, "k", []byte("secret"), time.Minute)
+
+	snap, _ := cache.Snapshot(ctx)
+	if snap[0].Fingerprint == "" || strings.Contains(snap[0].Fingerprint, "secret") {
+		t.Errorf("fingerprint = %q", snap[0].Fingerprint)
+	}
+	if snap[0].Fingerprint != fingerprint([]byte("secret")) {
+		t.Error("fingerprint should be stable for equal values")
+	}
+}
+
+func TestSnapshot_PreservesContentType(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryCache(DefaultPolicy())
+	_ = src.SetWithMeta(ctx, "k", []byte("{}"), EntryMeta{ContentType: "application/json"}, time.Minute)
+
+	var buf bytes.Buffer
+	_ = src.WriteSnapshot(ctx, &buf)
+	dst := NewMemoryCache(DefaultPolicy())
+	if _, err := dst.ReadSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("ReadSnapshot failed: %v", err)
+	}
+	if _, meta, _ := dst.GetWithMeta(ctx, "k"); meta.ContentType != "application/json" {
+		t.Errorf("content type = %q after import", meta.ContentType)
+	}
+}
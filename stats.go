@@ -0,0 +1,260 @@
+package toolcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultMaxTrackedTools is the number of distinct tools tracked in
+// PerToolStats unless overridden with WithMaxTrackedTools.
+const DefaultMaxTrackedTools = 1000
+
+// OverflowToolID is the PerToolStats key aggregating counters for tools that
+// were evicted from per-tool tracking.
+const OverflowToolID = "_overflow"
+
+// ToolStats holds cumulative middleware counters.
+type ToolStats struct {
+	// Hits counts executions served from the cache.
+	Hits uint64
+
+	// Misses counts cacheable executions that ran the executor.
+	Misses uint64
+
+	// Skips counts executions that bypassed the cache (skip rules, key errors).
+	Skips uint64
+
+	// Errors counts executor errors, including short-circuited calls.
+	Errors uint64
+
+	// ShortCircuits counts calls rejected by an open executor breaker.
+	ShortCircuits uint64
+
+	// BytesServed totals the value bytes returned from the cache on hits.
+	BytesServed uint64
+
+	// TimeSaved estimates executor time avoided by hits: each hit credits
+	// the latency of the tool's most recent successful execution.
+	TimeSaved time.Duration
+
+	// BytesStored totals the value bytes the middleware wrote to the cache,
+	// including WarmAll. It measures write volume, not current residency:
+	// overwritten, expired and evicted entries are not subtracted.
+	BytesStored uint64
+
+	// NegativeHits counts the Hits that served a cached error.
+	NegativeHits uint64
+
+	// NegativeStores counts executor errors written to the negative
+	// cache.
+	NegativeStores uint64
+
+	// OversizedResults counts results not cached because they exceeded
+	// WithMaxResultBytes.
+	OversizedResults uint64
+
+	// Phases totals the time spent in each phase of Execute.
+	Phases PhaseTimings
+
+	// CircuitOpen reports whether the tool's executor breaker is open.
+	// It is only set in PerToolStats.
+	CircuitOpen bool
+}
+
+// PhaseTimings totals the time the middleware spent in each phase of
+// execution, to show where a call's overhead comes from.
+type PhaseTimings struct {
+	// Keying is the time spent deriving cache keys, including
+	// canonicalization.
+	Keying time.Duration
+
+	// CacheGet is the time spent reading the cache, including negative
+	// and revalidation lookups.
+	CacheGet time.Duration
+
+	// Executor is the time spent running (or waiting on a coalesced)
+	// executor.
+	Executor time.Duration
+
+	// CacheSet is the time spent transforming and writing results to the
+	// cache, including retries.
+	CacheSet time.Duration
+}
+
+func (p *PhaseTimings) add(o PhaseTimings) {
+	p.Keying += o.Keying
+	p.CacheGet += o.CacheGet
+	p.Executor += o.Executor
+	p.CacheSet += o.CacheSet
+}
+
+func (s *ToolStats) add(o ToolStats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Skips += o.Skips
+	s.Errors += o.Errors
+	s.ShortCircuits += o.ShortCircuits
+	s.BytesStored += o.BytesStored
+	s.OversizedResults += o.OversizedResults
+	s.NegativeHits += o.NegativeHits
+	s.NegativeStores += o.NegativeStores
+	s.BytesServed += o.BytesServed
+	s.TimeSaved += o.TimeSaved
+	s.Phases.add(o.Phases)
+}
+
+// Stats reports cumulative counters across all tools.
+type Stats struct {
+	// Name is the middleware name set with WithName, if any.
+	Name string
+
+	// NegativeEntries is the number of cached errors held in the separate
+	// negative cache, including expired ones not yet removed. It is only
+	// set with WithNegativeCacheSize.
+	NegativeEntries int
+
+	ToolStats
+}
+
+// WithMaxTrackedTools caps the number of distinct tools tracked in
+// PerToolStats. When a new tool arrives at the cap, the least recently
+// updated tool is evicted and its counters are folded into OverflowToolID.
+// Values below 1 are treated as 1.
+func WithMaxTrackedTools(n int) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		if n < 1 {
+			n = 1
+		}
+		m.stats.maxTools = n
+	}
+}
+
+type toolStatsEntry struct {
+	toolID      string
+	stats       ToolStats
+	lastLatency time.Duration
+}
+
+// statsRecorder tracks total and per-tool counters with bounded memory.
+type statsRecorder struct {
+	mu       sync.Mutex
+	maxTools int
+	total    ToolStats
+	overflow ToolStats
+	tools    map[string]*list.Element
+	lru      *list.List // front = most recently updated
+}
+
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{
+		maxTools: DefaultMaxTrackedTools,
+		tools:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (r *statsRecorder) record(toolID string, delta ToolStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.total.add(delta)
+	r.entry(toolID).stats.add(delta)
+}
+
+// recordMiss records delta for an execution that took latency, which is
+// remembered as the cost a later hit for the tool saves.
+func (r *statsRecorder) recordMiss(toolID string, delta ToolStats, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.total.add(delta)
+	e := r.entry(toolID)
+	e.stats.add(delta)
+	e.lastLatency = latency
+}
+
+// recordHit records a hit that served n bytes, crediting the tool's last
+// observed executor latency as time saved.
+func (r *statsRecorder) recordHit(toolID string, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := r.entry(toolID)
+	delta := ToolStats{Hits: 1, BytesServed: uint64(n), TimeSaved: e.lastLatency}
+	r.total.add(delta)
+	e.stats.add(delta)
+}
+
+// entry returns the tracked entry for toolID, creating it and evicting the
+// least recently updated tools into the overflow bucket as needed.
+// Callers must hold r.mu.
+func (r *statsRecorder) entry(toolID string) *toolStatsEntry {
+	if elem, ok := r.tools[toolID]; ok {
+		r.lru.MoveToFront(elem)
+		return elem.Value.(*toolStatsEntry)
+	}
+
+	for r.lru.Len() >= r.maxTools {
+		oldest := r.lru.Back()
+		evicted := r.lru.Remove(oldest).(*toolStatsEntry)
+		delete(r.tools, evicted.toolID)
+		r.overflow.add(evicted.stats)
+	}
+	e := &toolStatsEntry{toolID: toolID}
+	r.tools[toolID] = r.lru.PushFront(e)
+	return e
+}
+
+// reset zeroes all counters and forgets tracked tools.
+func (r *statsRecorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.total = ToolStats{}
+	r.overflow = ToolStats{}
+	clear(r.tools)
+	r.lru.Init()
+}
+
+func (r *statsRecorder) snapshot() (ToolStats, map[string]ToolStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	perTool := make(map[string]ToolStats, len(r.tools)+1)
+	for toolID, elem := range r.tools {
+		perTool[toolID] = elem.Value.(*toolStatsEntry).stats
+	}
+	if r.overflow != (ToolStats{}) {
+		perTool[OverflowToolID] = r.overflow
+	}
+	return r.total, perTool
+}
+
+// Stats returns cumulative counters across all tools.
+func (m *CacheMiddleware) Stats() Stats {
+	total, _ := m.stats.snapshot()
+	stats := Stats{Name: m.name, ToolStats: total}
+	if m.negativeCache != nil {
+		probation, protected := m.negativeCache.Len()
+		stats.NegativeEntries = probation + protected
+	}
+	return stats
+}
+
+// PerToolStats returns cumulative counters keyed by tool ID. At most
+// WithMaxTrackedTools tools are tracked individually; counters for evicted
+// tools are reported under OverflowToolID.
+func (m *CacheMiddleware) PerToolStats() map[string]ToolStats {
+	_, perTool := m.stats.snapshot()
+	m.markOpenCircuits(perTool)
+	return perTool
+}
+
+// ResetStats zeroes the cumulative counters reported by Stats and
+// PerToolStats, starting a fresh measurement window. Cached entries are
+// unaffected. Updates racing with the reset land either before it, and are
+// discarded, or after it, and are counted; none are partially applied.
+func (m *CacheMiddleware) ResetStats() {
+	m.stats.reset()
+}
@@ -0,0 +1,289 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// counters drops TimeSaved and Phases, which depend on measured latency,
+// so the remaining counters can be compared exactly.
+func counters(s ToolStats) ToolStats {
+	s.TimeSaved = 0
+	s.Phases = PhaseTimings{}
+	return s
+}
+
+func TestStats_CountsOutcomes(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	ctx := context.Background()
+	ok := &mockExecutor{result: []byte("ok")}
+	fail := &mockExecutor{err: errors.New("boom")}
+
+	_, _ = mw.Execute(ctx, "read", map[string]any{"q": 1}, nil, ok.execute)   // miss
+	_, _ = mw.Execute(ctx, "read", map[string]any{"q": 1}, nil, ok.execute)   // hit
+	_, _ = mw.Execute(ctx, "write", nil, []string{"write"}, ok.execute)       // skip
+	_, _ = mw.Execute(ctx, "read", map[string]any{"q": 2}, nil, fail.execute) // miss + error
+	_, _ = mw.Execute(ctx, "read", struct{}{}, nil, ok.execute)               // key error -> skip
+
+	want := ToolStats{Hits: 1, Misses: 2, Skips: 2, Errors: 1, BytesStored: 2, BytesServed: 2}
+	if got := counters(mw.Stats().ToolStats); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	perTool := mw.PerToolStats()
+	if got := counters(perTool["read"]); got != (ToolStats{Hits: 1, Misses: 2, Skips: 1, Errors: 1, BytesStored: 2, BytesServed: 2}) {
+		t.Errorf("read stats = %+v", got)
+	}
+	if got := counters(perTool["write"]); got != (ToolStats{Skips: 1}) {
+		t.Errorf("write stats = %+v", got)
+	}
+	if _, exists := perTool[OverflowToolID]; exists {
+		t.Error("overflow bucket should be absent when under the cap")
+	}
+}
+
+func TestStats_MaxTrackedToolsOverflow(t *testing.T) {
+	const maxTools = 3
+	const numTools = 10
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithMaxTrackedTools(maxTools))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	for i := 0; i < numTools; i++ {
+		toolID := fmt.Sprintf("tool-%d", i)
+		_, _ = mw.Execute(ctx, toolID, nil, nil, executor.execute) // miss
+		_, _ = mw.Execute(ctx, toolID, nil, nil, executor.execute) // hit
+	}
+
+	perTool := mw.PerToolStats()
+	if len(perTool) != maxTools+1 {
+		t.Fatalf("tracked %d entries, want %d tools + overflow", len(perTool), maxTools)
+	}
+
+	// Most recently used tools stay individually tracked.
+	for i := numTools - maxTools; i < numTools; i++ {
+		toolID := fmt.Sprintf("tool-%d", i)
+		if got := counters(perTool[toolID]); got != (ToolStats{Hits: 1, Misses: 1, BytesStored: 2, BytesServed: 2}) {
+			t.Errorf("%s stats = %+v", toolID, got)
+		}
+	}
+
+	evicted := uint64(numTools - maxTools)
+	if got := counters(perTool[OverflowToolID]); got != (ToolStats{Hits: evicted, Misses: evicted, BytesStored: 2 * evicted, BytesServed: 2 * evicted}) {
+		t.Errorf("overflow stats = %+v, want %d hits and misses", got, evicted)
+	}
+
+	// Overflow plus tracked tools always sum to the total.
+	var sum ToolStats
+	for _, s := range perTool {
+		sum.add(s)
+	}
+	if total := mw.Stats().ToolStats; sum != total {
+		t.Errorf("per-tool sum %+v != total %+v", sum, total)
+	}
+}
+
+func TestStats_RecentlyUpdatedToolSurvives(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithMaxTrackedTools(2))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "a", nil, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "b", nil, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "a", nil, nil, executor.execute) // touch a
+	_, _ = mw.Execute(ctx, "c", nil, nil, executor.execute) // evicts b
+
+	perTool := mw.PerToolStats()
+	if _, ok := perTool["a"]; !ok {
+		t.Error("recently updated tool a should still be tracked")
+	}
+	if _, ok := perTool["b"]; ok {
+		t.Error("least recently updated tool b should be evicted")
+	}
+	if perTool[OverflowToolID].Misses != 1 {
+		t.Errorf("overflow = %+v, want b's single miss", perTool[OverflowToolID])
+	}
+}
+
+func TestStats_BytesStoredPerTool(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	ctx := context.Background()
+	small := &mockExecutor{result: []byte("abc")}
+	large := &mockExecutor{result: make([]byte, 1000)}
+	fail := &mockExecutor{err: errors.New("boom")}
+
+	_, _ = mw.Execute(ctx, "small", 1, nil, small.execute)
+	_, _ = mw.Execute(ctx, "small", 2, nil, small.execute)
+	_, _ = mw.Execute(ctx, "small", 2, nil, small.execute) // hit: nothing written
+	_, _ = mw.Execute(ctx, "large", 1, nil, large.execute)
+	_, _ = mw.Execute(ctx, "large", 2, nil, fail.execute)
+	_, _ = mw.Execute(ctx, "write", 1, []string{"write"}, large.execute) // skipped
+
+	perTool := mw.PerToolStats()
+	if got := perTool["small"].BytesStored; got != 6 {
+		t.Errorf("small BytesStored = %d, want 6", got)
+	}
+	if got := perTool["large"].BytesStored; got != 1000 {
+		t.Errorf("large BytesStored = %d, want 1000", got)
+	}
+	if got := perTool["write"].BytesStored; got != 0 {
+		t.Errorf("skipped tool BytesStored = %d, want 0", got)
+	}
+	if got := mw.Stats().BytesStored; got != 1006 {
+		t.Errorf("total BytesStored = %d, want 1006", got)
+	}
+}
+
+func TestStats_TimeAndBytesSaved(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	ctx := context.Background()
+	slow := func(context.Context, string, any) ([]byte, error) {
+		time.Sleep(20 * time.Millisecond)
+		return []byte("12345"), nil
+	}
+
+	_, _ = mw.Execute(ctx, "slow", 1, nil, slow) // miss
+	if got := mw.Stats().TimeSaved; got != 0 {
+		t.Errorf("TimeSaved after a miss = %v, want 0", got)
+	}
+
+	for range 3 {
+		_, _ = mw.Execute(ctx, "slow", 1, nil, slow) // hit
+	}
+	stats := mw.PerToolStats()["slow"]
+	if stats.TimeSaved < 60*time.Millisecond {
+		t.Errorf("TimeSaved = %v, want at least 3 x 20ms", stats.TimeSaved)
+	}
+	if stats.BytesServed != 15 {
+		t.Errorf("BytesServed = %d, want 15", stats.BytesServed)
+	}
+	if total := mw.Stats(); total.TimeSaved != stats.TimeSaved || total.BytesServed != 15 {
+		t.Errorf("totals = %+v, want them to match the tool", total.ToolStats)
+	}
+}
+
+func TestStats_Reset(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithMaxTrackedTools(1))
+	ctx := context.Background()
+	executor := &mockExecutor{result: []byte("ok")}
+
+	_, _ = mw.Execute(ctx, "a", 1, nil, executor.execute) // miss
+	_, _ = mw.Execute(ctx, "b", 1, nil, executor.execute) // miss, evicts "a" into overflow
+	mw.ResetStats()
+
+	if got := mw.Stats().ToolStats; got != (ToolStats{}) {
+		t.Errorf("Stats() after reset = %+v, want zero", got)
+	}
+	if got := mw.PerToolStats(); len(got) != 0 {
+		t.Errorf("PerToolStats() after reset = %+v, want empty", got)
+	}
+
+	_, _ = mw.Execute(ctx, "b", 1, nil, executor.execute)
+	if executor.calls != 2 {
+		t.Errorf("reset must not drop cached data, executor calls = %d", executor.calls)
+	}
+	if got := counters(mw.PerToolStats()["b"]); got != (ToolStats{Hits: 1, BytesServed: 2}) {
+		t.Errorf("b stats after reset = %+v, want one hit", got)
+	}
+}
+
+func TestStats_ResetConcurrentWithUpdates(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	ctx := context.Background()
+	exec := func(context.Context, string, any) ([]byte, error) { return []byte("ok"), nil }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				_, _ = mw.Execute(ctx, fmt.Sprintf("tool-%d", j%5), j%10, nil, exec)
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		mw.ResetStats()
+	}
+	wg.Wait()
+
+	total := mw.Stats()
+	var sum uint64
+	for _, s := range mw.PerToolStats() {
+		sum += s.Hits + s.Misses
+	}
+	if sum != total.Hits+total.Misses {
+		t.Errorf("per-tool sum %d != total %d after concurrent resets", sum, total.Hits+total.Misses)
+	}
+}
+
+func TestStats_PhaseTimings(t *testing.T) {
+	const delay = 10 * time.Millisecond
+	backend := NewMemoryCache(DefaultPolicy())
+	cache := CacheFuncs{
+		GetFunc: func(ctx context.Context, key string) ([]byte, bool) {
+			time.Sleep(delay)
+			return backend.Get(ctx, key)
+		},
+		SetFunc: func(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+			time.Sleep(2 * delay)
+			return backend.Set(ctx, key, value, ttl)
+		},
+	}
+	keyer := KeyerFunc(func(toolID string, input any) (string, error) {
+		time.Sleep(3 * delay)
+		return NewDefaultKeyer().Key(toolID, input)
+	})
+	exec := func(context.Context, string, any) ([]byte, error) {
+		time.Sleep(4 * delay)
+		return []byte("ok"), nil
+	}
+	mw := NewCacheMiddleware(cache, keyer, DefaultPolicy(), nil)
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "t", 1, nil, exec) // miss: every phase
+	_, _ = mw.Execute(ctx, "t", 1, nil, exec) // hit: keying and get only
+
+	got := mw.PerToolStats()["t"].Phases
+	if got.Keying < 6*delay {
+		t.Errorf("Keying = %v, want at least %v", got.Keying, 6*delay)
+	}
+	if got.CacheGet < 2*delay {
+		t.Errorf("CacheGet = %v, want at least %v", got.CacheGet, 2*delay)
+	}
+	if got.Executor < 4*delay || got.Executor >= 8*delay {
+		t.Errorf("Executor = %v, want one execution of %v", got.Executor, 4*delay)
+	}
+	if got.CacheSet < 2*delay || got.CacheSet >= 4*delay {
+		t.Errorf("CacheSet = %v, want one write of %v", got.CacheSet, 2*delay)
+	}
+	if total := mw.Stats().Phases; total != got {
+		t.Errorf("total phases = %+v, want them to match the tool %+v", total, got)
+	}
+}
+
+func TestStats_PhaseTimingsUncached(t *testing.T) {
+	const delay = 10 * time.Millisecond
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	exec := func(context.Context, string, any) ([]byte, error) {
+		time.Sleep(delay)
+		return []byte("ok"), nil
+	}
+
+	_, _ = mw.Execute(context.Background(), "write", 1, []string{"write"}, exec)
+
+	got := mw.PerToolStats()["write"].Phases
+	if got.Executor < delay {
+		t.Errorf("Executor = %v, want at least %v", got.Executor, delay)
+	}
+	if got.Keying != 0 || got.CacheGet != 0 || got.CacheSet != 0 {
+		t.Errorf("skipped call recorded cache phases: %+v", got)
+	}
+}
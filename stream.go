@@ -0,0 +1,32 @@
+package toolcache
+
+import "context"
+
+// StreamExecutor runs a tool that reports incremental progress. It passes
+// each progress chunk to emit as it becomes available and returns the
+// final, complete result. emit must not be called after it returns.
+type StreamExecutor func(ctx context.Context, toolID string, input any, emit func(chunk []byte)) ([]byte, error)
+
+// ExecuteStream behaves like Execute for tools that stream progress. Each
+// chunk the executor emits is passed to onChunk, which may be nil, as it
+// arrives; only the final result is cached, once the executor returns
+// without error. A stream that fails part way is never cached, even with
+// WithPartialResultTTL, and returns a nil result alongside the error.
+//
+// On a cache hit, or when the call shares the result of a concurrent
+// identical call, the executor does not run and onChunk is not called;
+// callers receive only the final result.
+func (m *CacheMiddleware) ExecuteStream(ctx context.Context, toolID string, input any, tags []string, executor StreamExecutor, onChunk func(chunk []byte)) ([]byte, error) {
+	emit := func([]byte) {}
+	if onChunk != nil {
+		emit = onChunk
+	}
+	wrapped := func(ctx context.Context, toolID string, input any) ([]byte, error) {
+		result, err := executor(ctx, toolID, input, emit)
+		if err != nil && !isDoNotCache(err) {
+			return nil, err
+		}
+		return result, err
+	}
+	return m.execute(ctx, toolID, input, tags, wrapped, nil)
+}
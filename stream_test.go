@@ -0,0 +1,119 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// chunkedTool streams chunks and then returns final, or fails with err
+// after emitting failAfter chunks when err is set.
+type chunkedTool struct {
+	chunks    []string
+	final     string
+	err       error
+	failAfter int
+	calls     int
+}
+
+func (s *chunkedTool) execute(_ context.Context, _ string, _ any, emit func([]byte)) ([]byte, error) {
+	s.calls++
+	for i, chunk := range s.chunks {
+		if s.err != nil && i == s.failAfter {
+			return []byte("partial"), s.err
+		}
+		emit([]byte(chunk))
+	}
+	return []byte(s.final), nil
+}
+
+func TestExecuteStream_CachesFinalResult(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	ctx := context.Background()
+	tool := &chunkedTool{chunks: []string{"10%", "50%", "100%"}, final: "done"}
+
+	var got []string
+	result, err := mw.ExecuteStream(ctx, "build", 1, nil, tool.execute, func(chunk []byte) {
+		got = append(got, string(chunk))
+	})
+	if err != nil || string(result) != "done" {
+		t.Fatalf("ExecuteStream() = %q, %v", result, err)
+	}
+	if !slices.Equal(got, tool.chunks) {
+		t.Errorf("chunks = %v, want %v", got, tool.chunks)
+	}
+
+	got = nil
+	result, err = mw.ExecuteStream(ctx, "build", 1, nil, tool.execute, func(chunk []byte) {
+		got = append(got, string(chunk))
+	})
+	if err != nil || string(result) != "done" {
+		t.Fatalf("cached ExecuteStream() = %q, %v", result, err)
+	}
+	if tool.calls != 1 {
+		t.Errorf("executor calls = %d, want the final result served from cache", tool.calls)
+	}
+	if len(got) != 0 {
+		t.Errorf("a hit should not replay chunks, got %v", got)
+	}
+
+	// The stream and a plain Execute share the cached final result.
+	plain, err := mw.Execute(ctx, "build", 1, nil, (&mockExecutor{result: []byte("other")}).execute)
+	if err != nil || string(plain) != "done" {
+		t.Errorf("Execute() = %q, %v, want the streamed result", plain, err)
+	}
+}
+
+func TestExecuteStream_ErrorMidStreamNotCached(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithPartialResultTTL(time.Minute))
+	ctx := context.Background()
+	boom := errors.New("connection reset")
+	tool := &chunkedTool{chunks: []string{"a", "b", "c"}, final: "done", err: boom, failAfter: 2}
+
+	var got []string
+	result, err := mw.ExecuteStream(ctx, "build", 1, nil, tool.execute, func(chunk []byte) {
+		got = append(got, string(chunk))
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want %v", err, boom)
+	}
+	if result != nil {
+		t.Errorf("result = %q, want nil for a failed stream", result)
+	}
+	if !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("chunks = %v, want those emitted before the failure", got)
+	}
+
+	tool.err = nil
+	result, err = mw.ExecuteStream(ctx, "build", 1, nil, tool.execute, nil)
+	if err != nil || string(result) != "done" {
+		t.Fatalf("retry = %q, %v", result, err)
+	}
+	if tool.calls != 2 {
+		t.Errorf("executor calls = %d, want the failed stream to leave nothing cached", tool.calls)
+	}
+}
+
+func TestExecuteStream_DoNotCache(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	ctx := context.Background()
+	calls := 0
+	exec := func(_ context.Context, _ string, _ any, emit func([]byte)) ([]byte, error) {
+		calls++
+		emit([]byte("tick"))
+		return []byte("volatile"), ErrDoNotCache
+	}
+
+	for range 2 {
+		result, err := mw.ExecuteStream(ctx, "clock", nil, nil, exec, nil)
+		if err != nil || string(result) != "volatile" {
+			t.Fatalf("ExecuteStream() = %q, %v", result, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("executor calls = %d, want ErrDoNotCache results left uncached", calls)
+	}
+}
@@ -0,0 +1,75 @@
+package toolcache
+
+import "context"
+
+// SwapContents atomically replaces every entry in c with the entries of
+// next, for blue/green refreshes: build next off to the side, e.g. with
+// Set or ReadSnapshot, then swap it in. Readers see either all of the old
+// entries or all of the new ones, never a mix. The entries are moved, not
+// copied, leaving next empty.
+//
+// Entries keep their values, metadata, expiry and dependencies from next.
+// Pins stay with c, which also applies its own WithMaxEntries limit to the
+// new entries, keeping next's recency order if next has one. Old entries
+// whose keys next does not hold are reported as EvictReasonDeleted.
+// Entries moved from a pooled next into a cache without WithEntryPooling
+// are not recycled again.
+// Do not swap two caches into each other concurrently.
+func (c *MemoryCache) SwapContents(ctx context.Context, next *MemoryCache) error {
+	if next == nil {
+		return ErrNilCache
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if next == c {
+		return nil
+	}
+
+	next.mu.Lock()
+	entries, dependents := next.entries, next.dependents
+	lists, priorities := next.lru.lists, next.lru.priorities
+	next.entries = make(map[string]*cacheEntry)
+	next.dependents = nil
+	next.lru.lists, next.lru.priorities = nil, nil
+	next.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.pools() {
+		// Entries from a pooled next must not be recycled here: c's
+		// readers use values after releasing the lock.
+		for _, entry := range entries {
+			entry.buf = nil
+		}
+	}
+	old := c.entries
+	c.entries, c.dependents = entries, dependents
+	c.lru.lists, c.lru.priorities = nil, nil
+	if c.maxEntries > 0 {
+		if next.maxEntries > 0 {
+			c.lru.lists, c.lru.priorities = lists, priorities
+		} else {
+			c.relinkLocked()
+		}
+		c.evictOverflowLocked(nil)
+	}
+
+	for key, entry := range old {
+		if _, kept := c.entries[key]; !kept {
+			c.emitEvict(key, EvictReasonDeleted)
+		}
+		c.releaseLocked(entry)
+	}
+	return nil
+}
+
+// relinkLocked rebuilds the recency lists for the current entries, in no
+// particular order. Callers must hold c.mu for writing.
+func (c *MemoryCache) relinkLocked() {
+	for key, entry := range c.entries {
+		entry.key = key
+		c.lru.pushFront(entry)
+	}
+}
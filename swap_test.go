@@ -0,0 +1,240 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// filledCache returns a cache holding keys with value tag.
+func filledCache(t *testing.T, tag string, keys []string, opts ...MemoryCacheOption) *MemoryCache {
+	t.Helper()
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), opts...)
+	for _, key := range keys {
+		if err := c.Set(context.Background(), key, []byte(tag), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestSwapContents(t *testing.T) {
+	ctx := context.Background()
+	c := filledCache(t, "old", []string{"a", "b", "c"}, WithEvictionEvents(8))
+	next := filledCache(t, "new", []string{"b", "c", "d"})
+
+	if err := c.SwapContents(ctx, next); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := c.KeysWithPrefix(ctx, ""); !slices.Equal(keys, []string{"b", "c", "d"}) {
+		t.Errorf("keys after swap = %v", keys)
+	}
+	for _, key := range []string{"b", "c", "d"} {
+		if got, _ := c.Get(ctx, key); string(got) != "new" {
+			t.Errorf("Get(%q) = %q, want new", key, got)
+		}
+	}
+	if next.Len() != 0 {
+		t.Errorf("next holds %d entries after the swap, want 0", next.Len())
+	}
+
+	// Only keys that disappeared are reported.
+	select {
+	case ev := <-c.EvictionEvents():
+		if ev.Key != "a" || ev.Reason != EvictReasonDeleted {
+			t.Errorf("event = %s/%s, want a/deleted", ev.Key, ev.Reason)
+		}
+	default:
+		t.Error("no eviction event for the dropped key")
+	}
+	select {
+	case ev := <-c.EvictionEvents():
+		t.Errorf("unexpected event %s/%s", ev.Key, ev.Reason)
+	default:
+	}
+
+	// next stays usable.
+	_ = next.Set(ctx, "x", []byte("v"), time.Minute)
+	if _, ok := c.Get(ctx, "x"); ok {
+		t.Error("writes to next after the swap must not reach c")
+	}
+}
+
+func TestSwapContents_CarriesMetadataAndDeps(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(DefaultPolicy())
+	next := NewMemoryCache(DefaultPolicy())
+	_ = next.SetWithMeta(ctx, "doc", []byte("v"), EntryMeta{ContentType: "text/plain", Version: 3}, time.Minute)
+	_ = next.SetWithDeps(ctx, "derived", []byte("v"), time.Minute, []string{"doc"})
+
+	if err := c.SwapContents(ctx, next); err != nil {
+		t.Fatal(err)
+	}
+	if _, meta, _ := c.GetWithMeta(ctx, "doc"); meta.ContentType != "text/plain" || meta.Version != 3 {
+		t.Errorf("meta = %+v", meta)
+	}
+	_ = c.Delete(ctx, "doc")
+	if _, ok := c.Get(ctx, "derived"); ok {
+		t.Error("dependencies should move with the entries")
+	}
+}
+
+func TestSwapContents_MaxEntries(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithMaxEntries(2))
+	next := filledCache(t, "new", []string{"a", "b", "c"}, WithMaxEntries(10))
+	_, _ = next.Get(ctx, "a")
+
+	if err := c.SwapContents(ctx, next); err != nil {
+		t.Fatal(err)
+	}
+	if got := lruKeys(t, c); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("recency order = %v, want next's order trimmed to the limit", got)
+	}
+
+	unordered := filledCache(t, "new", []string{"x", "y", "z"})
+	if err := c.SwapContents(ctx, unordered); err != nil {
+		t.Fatal(err)
+	}
+	if got := lruKeys(t, c); len(got) != 2 {
+		t.Errorf("entries = %v, want 2", got)
+	}
+}
+
+func TestSwapContents_Errors(t *testing.T) {
+	c := filledCache(t, "old", []string{"a"})
+	if err := c.SwapContents(context.Background(), nil); !errors.Is(err, ErrNilCache) {
+		t.Errorf("SwapContents(nil) = %v, want ErrNilCache", err)
+	}
+	if err := c.SwapContents(context.Background(), c); err != nil || c.Len() != 1 {
+		t.Errorf("swapping with itself = %v, Len %d", err, c.Len())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.SwapContents(ctx, NewMemoryCache(DefaultPolicy())); !errors.Is(err, context.Canceled) || c.Len() != 1 {
+		t.Errorf("canceled swap = %v, Len %d", err, c.Len())
+	}
+}
+
+// TestSwapContents_ReadersSeeCompleteSets swaps between two generations
+// while readers list the cache, checking each listing is exactly one
+// generation with that generation's values.
+func TestSwapContents_ReadersSeeCompleteSets(t *testing.T) {
+	for name, opts := range map[string][]MemoryCacheOption{
+		"default": nil,
+		"pooled":  {WithEntryPooling()},
+		"bounded": {WithMaxEntries(100)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			generation := func(g int) []string {
+				keys := make([]string, 20)
+				for i := range keys {
+					keys[i] = fmt.Sprintf("g%d-%02d", g%2, i)
+				}
+				return keys
+			}
+			c := filledCache(t, "0", generation(0), opts...)
+
+			var stop atomic.Bool
+			var wg sync.WaitGroup
+			for range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for !stop.Load() {
+						infos, _ := c.Snapshot(ctx)
+						if len(infos) != 20 {
+							t.Errorf("reader saw %d entries, want a complete set of 20", len(infos))
+							return
+						}
+						prefix := infos[0].Key[:2]
+						for _, info := range infos {
+							if info.Key[:2] != prefix {
+								t.Errorf("reader saw a mix of generations: %s and %s", prefix, info.Key)
+								return
+							}
+						}
+						if value, ok := c.Get(ctx, infos[0].Key); ok && len(value) == 0 {
+							t.Error("reader saw an empty value")
+							return
+						}
+					}
+				}()
+			}
+
+			for g := 1; g <= 200; g++ {
+				next := filledCache(t, fmt.Sprint(g), generation(g), opts...)
+				if err := c.SwapContents(ctx, next); err != nil {
+					t.Fatal(err)
+				}
+			}
+			stop.Store(true)
+			wg.Wait()
+
+			if got, _ := c.Get(ctx, "g0-00"); string(got) != "200" {
+				t.Errorf("final value = %q, want the last generation", got)
+			}
+		})
+	}
+}
+
+// TestSwapContents_PooledIntoUnpooled moves pooled entries into a cache
+// without pooling, whose readers use values after releasing the lock, so
+// the moved entries must not be recycled.
+func TestSwapContents_PooledIntoUnpooled(t *testing.T) {
+	ctx := context.Background()
+	keys := []string{"a", "b", "c", "d"}
+	c := NewMemoryCache(DefaultPolicy())
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				for _, key := range keys {
+					value, ok := c.Get(ctx, key)
+					if ok && (len(value) < 2 || string(value[:2]) != key+"=") {
+						t.Errorf("Get(%q) = %q, want a value for %q", key, value, key)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for g := range 200 {
+		next := NewMemoryCacheWithOptions(DefaultPolicy(), WithEntryPooling())
+		for _, key := range keys {
+			_ = next.Set(ctx, key, fmt.Appendf(nil, "%s=%d", key, g), time.Minute)
+		}
+		if err := c.SwapContents(ctx, next); err != nil {
+			t.Fatal(err)
+		}
+		_ = c.Delete(ctx, keys[g%len(keys)])
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	// A string sharing a moved value stays intact after its entry is
+	// removed and pooled caches keep allocating.
+	next := NewMemoryCacheWithOptions(DefaultPolicy(), WithEntryPooling())
+	_ = next.Set(ctx, "s", []byte("original"), time.Minute)
+	_ = c.SwapContents(ctx, next)
+	got, _ := c.GetString(ctx, "s")
+	_ = c.Delete(ctx, "s")
+	for range 10 {
+		_ = next.Set(ctx, "s", []byte("reused!!"), time.Minute)
+		_ = next.Delete(ctx, "s")
+	}
+	if got != "original" {
+		t.Errorf("GetString after Delete = %q, want original", got)
+	}
+}
@@ -0,0 +1,57 @@
+package toolcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// WithTagsInKey makes tags part of the cache key, for tools whose results
+// legitimately differ by tag (e.g. "read" vs "read-verbose"). Only the
+// listed tags are considered, compared case-insensitively; with no
+// arguments every tag is. Tag order and duplicates do not matter. Calls
+// carrying none of the considered tags keep the key they had without the
+// option, so existing entries stay valid. By default tags are not keyed.
+//
+// The option applies to derived keys (Execute, ExecuteWithInfo, ExecuteRaw,
+// WarmAll and PrioritizeWarm), not to keys passed to ExecuteWithKey.
+func WithTagsInKey(tags ...string) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.keyTags = make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			m.keyTags[strings.ToLower(tag)] = true
+		}
+	}
+}
+
+// tagKey mixes the considered tags into key. It returns key unchanged
+// when tags are not keyed or none of them are considered.
+func (m *CacheMiddleware) tagKey(key string, tags []string) string {
+	if m.keyTags == nil || len(tags) == 0 {
+		return key
+	}
+
+	selected := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(tag)
+		if len(m.keyTags) == 0 || m.keyTags[tag] {
+			selected = append(selected, tag)
+		}
+	}
+	if len(selected) == 0 {
+		return key
+	}
+	sort.Strings(selected)
+
+	h := sha256.New()
+	for i, tag := range selected {
+		if i > 0 && tag == selected[i-1] {
+			continue
+		}
+		h.Write([]byte(tag))
+		h.Write([]byte{0})
+	}
+	var sum [sha256.Size]byte
+	return key + ":" + hex.EncodeToString(h.Sum(sum[:0])[:KeyHashBytes])
+}
@@ -0,0 +1,74 @@
+package toolcache
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTagsInKey_SeparatesTagVariants(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithTagsInKey("verbose"))
+	ctx := context.Background()
+
+	plain := &mockExecutor{result: []byte("short")}
+	verbose := &mockExecutor{result: []byte("long")}
+	for i := 0; i < 2; i++ {
+		got, _ := mw.Execute(ctx, "read", "doc", []string{"read"}, plain.execute)
+		if string(got) != "short" {
+			t.Errorf("plain call %d = %q", i, got)
+		}
+		got, _ = mw.Execute(ctx, "read", "doc", []string{"read", "Verbose"}, verbose.execute)
+		if string(got) != "long" {
+			t.Errorf("verbose call %d = %q", i, got)
+		}
+	}
+	if plain.calls != 1 || verbose.calls != 1 {
+		t.Errorf("each variant should be cached separately: plain=%d verbose=%d", plain.calls, verbose.calls)
+	}
+}
+
+func TestTagsInKey_KeyShape(t *testing.T) {
+	mw := NewCacheMiddleware(nil, nil, DefaultPolicy(), nil, WithTagsInKey("a", "B"))
+	base, _ := NewDefaultKeyer().Key("tool", 1)
+
+	if got := mw.tagKey(base, []string{"other"}); got != base {
+		t.Errorf("unconsidered tags changed the key: %s", got)
+	}
+	ab := mw.tagKey(base, []string{"a", "b"})
+	if ab == base || !strings.HasPrefix(ab, ToolKeyPrefix("tool")) {
+		t.Errorf("tagged key = %s, want a distinct key under the tool prefix", ab)
+	}
+	if got := mw.tagKey(base, []string{"B", "x", "A", "a"}); got != ab {
+		t.Errorf("order, case and duplicates should not matter: %s vs %s", got, ab)
+	}
+	if mw.tagKey(base, []string{"a"}) == ab {
+		t.Error("different tag sets should give different keys")
+	}
+}
+
+func TestTagsInKey_AllTagsAndDefault(t *testing.T) {
+	base := "toolcache:tool:0123"
+	all := NewCacheMiddleware(nil, nil, DefaultPolicy(), nil, WithTagsInKey())
+	if all.tagKey(base, []string{"anything"}) == base {
+		t.Error("WithTagsInKey() should consider every tag")
+	}
+	def := NewCacheMiddleware(nil, nil, DefaultPolicy(), nil)
+	if def.tagKey(base, []string{"anything"}) != base {
+		t.Error("tags must not affect keys by default")
+	}
+}
+
+func TestTagsInKey_WarmMatchesExecute(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithTagsInKey("verbose"))
+	warm := &mockExecutor{result: []byte("long")}
+	ctx := context.Background()
+
+	mw.WarmAll(ctx, []WarmRequest{{ToolID: "read", Input: "doc", Tags: []string{"verbose"}}}, warm.execute)
+	executor := &mockExecutor{result: []byte("fresh")}
+	got, _ := mw.Execute(ctx, "read", "doc", []string{"verbose"}, executor.execute)
+	if string(got) != "long" || executor.calls != 0 {
+		t.Errorf("warmed tagged entry not served: %q, %d calls", got, executor.calls)
+	}
+}
@@ -0,0 +1,79 @@
+package toolcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ThunkFunc recomputes a value that was cached lazily with SetThunk.
+type ThunkFunc func(ctx context.Context) ([]byte, error)
+
+// thunk is a deferred value. Concurrent readers share one computation.
+type thunk struct {
+	compute    ThunkFunc
+	promoteTTL time.Duration
+
+	once  sync.Once
+	value []byte
+	err   error
+}
+
+// SetThunk stores compute under key instead of a value, for results that
+// are expensive to hold but rarely read again. The thunk lives for ttl. The
+// first Get within that window runs compute with the reader's context and
+// promotes the result to a regular entry that lives for promoteTTL; if
+// promoteTTL is not positive the value is returned but not kept. If compute
+// fails, the read misses and the thunk is removed.
+//
+// Unmaterialized thunks are omitted from Snapshot, Range and WriteSnapshot.
+func (c *MemoryCache) SetThunk(_ context.Context, key string, compute ThunkFunc, ttl, promoteTTL time.Duration) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if ttl <= 0 || compute == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.storeLocked(key, &cacheEntry{
+		expiresAt: c.clock.Now().Add(ttl),
+		thunk:     &thunk{compute: compute, promoteTTL: promoteTTL},
+	})
+	c.mu.Unlock()
+
+	return nil
+}
+
+// materialize runs entry's thunk and replaces it with the computed value.
+func (c *MemoryCache) materialize(ctx context.Context, key string, entry *cacheEntry) (*cacheEntry, bool) {
+	t := entry.thunk
+	t.once.Do(func() {
+		t.value, t.err = t.compute(ctx)
+	})
+
+	var promoted *cacheEntry
+	if t.err == nil && t.promoteTTL > 0 {
+		promoted = &cacheEntry{value: t.value, expiresAt: c.clock.Now().Add(t.promoteTTL)}
+	}
+
+	c.mu.Lock()
+	// Only replace the thunk we observed; a concurrent Set may have
+	// replaced it in the meantime.
+	if c.entries[key] == entry {
+		if promoted != nil {
+			c.putLocked(key, promoted)
+		} else {
+			c.removeLocked(key, entry)
+		}
+	}
+	c.mu.Unlock()
+
+	if t.err != nil {
+		return nil, false
+	}
+	if promoted == nil {
+		promoted = &cacheEntry{value: t.value}
+	}
+	return promoted, true
+}
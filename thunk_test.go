@@ -0,0 +1,134 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache_SetThunkMaterializesOnGet(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+
+	var calls int
+	compute := func(context.Context) ([]byte, error) {
+		calls++
+		return []byte("expensive"), nil
+	}
+	_ = cache.SetThunk(ctx, "k", compute, time.Minute, time.Minute)
+	if calls != 0 {
+		t.Fatal("SetThunk should not compute eagerly")
+	}
+
+	got, ok := cache.Get(ctx, "k")
+	if !ok || string(got) != "expensive" {
+		t.Fatalf("Get() = %q, %v", got, ok)
+	}
+
+	// The value was promoted, so later reads do not recompute.
+	got, ok = cache.Get(ctx, "k")
+	if !ok || string(got) != "expensive" || calls != 1 {
+		t.Errorf("second Get() = %q, %v after %d computations", got, ok, calls)
+	}
+	if s, ok := cache.GetString(ctx, "k"); !ok || s != "expensive" {
+		t.Errorf("GetString() = %q, %v", s, ok)
+	}
+}
+
+func TestMemoryCache_SetThunkPromoteTTL(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	compute := func(context.Context) ([]byte, error) { return []byte("v"), nil }
+
+	_ = cache.SetThunk(ctx, "short", compute, time.Minute, time.Millisecond)
+	if _, ok := cache.Get(ctx, "short"); !ok {
+		t.Fatal("expected thunk to materialize")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.Get(ctx, "short"); ok {
+		t.Error("promoted value should expire after promoteTTL")
+	}
+
+	_ = cache.SetThunk(ctx, "once", compute, time.Minute, 0)
+	if _, ok := cache.Get(ctx, "once"); !ok {
+		t.Fatal("expected thunk to materialize")
+	}
+	if _, ok := cache.Get(ctx, "once"); ok {
+		t.Error("non-positive promoteTTL should not keep the value")
+	}
+}
+
+func TestMemoryCache_SetThunkExpires(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	var called bool
+	compute := func(context.Context) ([]byte, error) {
+		called = true
+		return []byte("v"), nil
+	}
+
+	_ = cache.SetThunk(ctx, "k", compute, time.Millisecond, time.Minute)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.Get(ctx, "k"); ok || called {
+		t.Error("expired thunk should miss without computing")
+	}
+}
+
+func TestMemoryCache_SetThunkError(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	var calls int
+	compute := func(context.Context) ([]byte, error) {
+		calls++
+		return nil, errors.New("recompute failed")
+	}
+
+	_ = cache.SetThunk(ctx, "k", compute, time.Minute, time.Minute)
+	if _, ok := cache.Get(ctx, "k"); ok {
+		t.Error("failed thunk should miss")
+	}
+	if _, ok := cache.Get(ctx, "k"); ok || calls != 1 {
+		t.Errorf("failed thunk should be removed, got %d computations", calls)
+	}
+}
+
+func TestMemoryCache_SetThunkConcurrentReaders(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	var calls atomic.Int32
+	compute := func(context.Context) ([]byte, error) {
+		calls.Add(1)
+		time.Sleep(time.Millisecond)
+		return []byte("v"), nil
+	}
+	_ = cache.SetThunk(ctx, "k", compute, time.Minute, time.Minute)
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, ok := cache.Get(ctx, "k"); !ok || string(got) != "v" {
+				t.Errorf("Get() = %q, %v", got, ok)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Errorf("computed %d times, want 1", got)
+	}
+}
+
+func TestMemoryCache_SetThunkOmittedFromSnapshot(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	_ = cache.SetThunk(ctx, "k", func(context.Context) ([]byte, error) { return []byte("v"), nil }, time.Minute, time.Minute)
+
+	infos, _ := cache.Snapshot(ctx)
+	if len(infos) != 0 {
+		t.Errorf("unmaterialized thunk should not appear in Snapshot: %+v", infos)
+	}
+}
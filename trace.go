@@ -0,0 +1,149 @@
+package toolcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// SpanName is the name of the span a Tracer starts for each call.
+const SpanName = "toolcache.Execute"
+
+// Tracer starts a span around each Execute, ExecuteWithInfo,
+// ExecuteWithKey, ExecuteRaw and ExecuteStream call. It is a shim for
+// OpenTelemetry and similar libraries, which toolcache does not import:
+// an adapter typically calls trace.Tracer.Start in StartSpan and sets the
+// SpanAttributes as span attributes in Span.End.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: the returned context is passed on to the executor, so child
+// spans it starts nest under the call's span.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer. End is called exactly once, when
+// the call returns.
+type Span interface {
+	End(attrs SpanAttributes)
+}
+
+// SpanAttributes describes how the middleware handled a traced call.
+type SpanAttributes struct {
+	// Middleware is the name set with WithName.
+	Middleware string
+
+	ToolID string
+
+	// Status is "hit" when the result or a cached error was served from
+	// the cache, including results shared with a concurrent identical
+	// call; "miss" when the executor ran for a cacheable call; and "skip"
+	// when the call bypassed the cache. It is empty if the call failed
+	// before reaching the cache, e.g. with ErrNilCache.
+	Status string
+
+	// KeyHash is the hex SHA-256 of the cache key, so spans can be
+	// correlated without exposing inputs embedded in keys. It is empty
+	// for skips.
+	KeyHash string
+
+	// TTL is the TTL the result was stored with, or 0 if it was not
+	// stored.
+	TTL time.Duration
+
+	// Err is the error returned to the caller.
+	Err error
+}
+
+// WithTracer starts a span for every call through the middleware; see
+// Tracer. A nil tracer disables tracing, which is the default.
+func WithTracer(t Tracer) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.tracer = t
+	}
+}
+
+// callTraceKey is the context key of a call's callTrace. It carries the
+// middleware so a nested call through another middleware never records
+// into this one's span.
+type callTraceKey struct{ m *CacheMiddleware }
+
+// callTrace collects the attributes of a traced call. It is locked because
+// the executor may start concurrent work, such as WarmAll, with the call's
+// context.
+type callTrace struct {
+	mu     sync.Mutex
+	status string
+	key    string
+	ttl    time.Duration
+}
+
+func noopEndSpan(error) {}
+
+// startSpan starts a span for a call to toolID, returning the context to
+// run the call with and a function that ends the span with the call's
+// error.
+func (m *CacheMiddleware) startSpan(ctx context.Context, toolID string) (context.Context, func(error)) {
+	if m.tracer == nil {
+		return ctx, noopEndSpan
+	}
+	ctx, span := m.tracer.StartSpan(ctx, SpanName)
+	t := &callTrace{}
+	ctx = context.WithValue(ctx, callTraceKey{m}, t)
+	return ctx, func(err error) {
+		span.End(t.attributes(m.name, toolID, err))
+	}
+}
+
+// callTrace returns the trace of the call ctx belongs to, or nil if the
+// call is not traced.
+func (m *CacheMiddleware) callTrace(ctx context.Context) *callTrace {
+	if m.tracer == nil {
+		return nil
+	}
+	t, _ := ctx.Value(callTraceKey{m}).(*callTrace)
+	return t
+}
+
+// traceOp records a hit, miss or skip on ctx's span. The first one decides
+// the status; writes are recorded by traceStored.
+func (m *CacheMiddleware) traceOp(ctx context.Context, result OpResult, key string) {
+	t := m.callTrace(ctx)
+	if t == nil || result == OpSet {
+		return
+	}
+	t.mu.Lock()
+	if t.status == "" {
+		t.status, t.key = result.String(), key
+	}
+	t.mu.Unlock()
+}
+
+// traceStored records on ctx's span that the result was stored with ttl.
+func (m *CacheMiddleware) traceStored(ctx context.Context, ttl time.Duration) {
+	if t := m.callTrace(ctx); t != nil {
+		t.mu.Lock()
+		t.ttl = ttl
+		t.mu.Unlock()
+	}
+}
+
+func (t *callTrace) attributes(middleware, toolID string, err error) SpanAttributes {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	attrs := SpanAttributes{
+		Middleware: middleware,
+		ToolID:     toolID,
+		Status:     t.status,
+		TTL:        t.ttl,
+		Err:        err,
+	}
+	if t.key != "" {
+		sum := sha256.Sum256([]byte(t.key))
+		attrs.KeyHash = hex.EncodeToString(sum[:])
+	}
+	return attrs
+}
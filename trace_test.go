@@ -0,0 +1,162 @@
+package toolcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fakeTracer records ended spans.
+type fakeTracer struct {
+	mu    sync.Mutex
+	names []string
+	spans []SpanAttributes
+}
+
+type fakeSpan struct {
+	tracer *fakeTracer
+}
+
+type fakeSpanKey struct{}
+
+func (t *fakeTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	t.names = append(t.names, name)
+	t.mu.Unlock()
+	return context.WithValue(ctx, fakeSpanKey{}, name), fakeSpan{tracer: t}
+}
+
+func (s fakeSpan) End(attrs SpanAttributes) {
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, attrs)
+	s.tracer.mu.Unlock()
+}
+
+func (t *fakeTracer) ended() []SpanAttributes {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]SpanAttributes(nil), t.spans...)
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTracer_HitMissSkip(t *testing.T) {
+	tracer := &fakeTracer{}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithTracer(tracer), WithName("primary"))
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", 1, []string{"write"}, executor.execute)
+
+	key, _ := NewDefaultKeyer().Key("tool", 1)
+	want := []SpanAttributes{
+		{Middleware: "primary", ToolID: "tool", Status: "miss", KeyHash: hashKey(key), TTL: DefaultPolicy().DefaultTTL},
+		{Middleware: "primary", ToolID: "tool", Status: "hit", KeyHash: hashKey(key)},
+		{Middleware: "primary", ToolID: "tool", Status: "skip"},
+	}
+	got := tracer.ended()
+	if len(got) != len(want) {
+		t.Fatalf("ended %d spans, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("span %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	for _, name := range tracer.names {
+		if name != SpanName {
+			t.Errorf("span name = %q, want %q", name, SpanName)
+		}
+	}
+}
+
+func TestTracer_Errors(t *testing.T) {
+	tracer := &fakeTracer{}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), negativePolicy(), nil,
+		WithTracer(tracer), WithCacheableError(isPermanent))
+	executor := &mockExecutor{err: errValidation}
+	ctx := context.Background()
+
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+	_, _ = mw.Execute(ctx, "tool", 1, nil, executor.execute)
+
+	got := tracer.ended()
+	if len(got) != 2 {
+		t.Fatalf("ended %d spans, want 2", len(got))
+	}
+	if got[0].Status != "miss" || got[0].TTL != 0 || !errors.Is(got[0].Err, errValidation) {
+		t.Errorf("failed miss span = %+v", got[0])
+	}
+	if got[1].Status != "hit" || !errors.Is(got[1].Err, ErrCachedError) {
+		t.Errorf("cached error span = %+v, want a hit carrying the error", got[1])
+	}
+
+	nilCache := NewCacheMiddleware(nil, nil, DefaultPolicy(), nil, WithTracer(tracer))
+	_, _ = nilCache.Execute(ctx, "tool", 1, nil, executor.execute)
+	if last := tracer.ended()[2]; last.Status != "" || !errors.Is(last.Err, ErrNilCache) {
+		t.Errorf("nil cache span = %+v", last)
+	}
+}
+
+func TestTracer_EntryPoints(t *testing.T) {
+	tracer := &fakeTracer{}
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil, WithTracer(tracer))
+	executor := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	_, _ = mw.ExecuteWithKey(ctx, "tool", "custom-key", nil, nil, executor.execute)
+	_, _ = mw.ExecuteRaw(ctx, "raw", []byte(`{"a":1}`), nil, executor.execute)
+	_, _, _ = mw.ExecuteWithInfo(ctx, "info", 1, nil, func(context.Context, string, any) ([]byte, EntryMeta, error) {
+		return []byte("v"), EntryMeta{}, nil
+	})
+	_, _ = mw.ExecuteStream(ctx, "stream", 1, nil, func(context.Context, string, any, func([]byte)) ([]byte, error) {
+		return []byte("v"), nil
+	}, nil)
+
+	got := tracer.ended()
+	if len(got) != 4 {
+		t.Fatalf("ended %d spans, want one per call", len(got))
+	}
+	if got[0].KeyHash != hashKey("custom-key") {
+		t.Errorf("ExecuteWithKey KeyHash = %q", got[0].KeyHash)
+	}
+	for i, toolID := range []string{"tool", "raw", "info", "stream"} {
+		if got[i].ToolID != toolID || got[i].Status != "miss" {
+			t.Errorf("span %d = %+v, want a miss for %s", i, got[i], toolID)
+		}
+	}
+}
+
+func TestTracer_ExecutorSeesSpanContext(t *testing.T) {
+	tracer := &fakeTracer{}
+	inner := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	outer := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil, WithTracer(tracer))
+	leaf := &mockExecutor{result: []byte("v")}
+	ctx := context.Background()
+
+	// Prime the untraced inner cache so the nested call is a hit.
+	_, _ = inner.Execute(ctx, "leaf", 1, nil, leaf.execute)
+	_, err := outer.Execute(ctx, "tool", 1, nil, func(ctx context.Context, _ string, _ any) ([]byte, error) {
+		if ctx.Value(fakeSpanKey{}) != SpanName {
+			t.Error("executor context does not carry the span")
+		}
+		return inner.Execute(ctx, "leaf", 1, nil, leaf.execute)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := tracer.ended()
+	if len(got) != 1 || got[0].Status != "miss" {
+		t.Errorf("spans = %+v, want one miss unaffected by the nested hit", got)
+	}
+}
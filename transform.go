@@ -0,0 +1,45 @@
+package toolcache
+
+// ResultTransform rewrites an executor result before it is cached, for
+// example to minify JSON or strip volatile fields so entries are canonical.
+type ResultTransform func(toolID string, result []byte) ([]byte, error)
+
+// TransformMode selects which callers see a transformed result.
+type TransformMode int
+
+const (
+	// TransformReturn caches the transformed result and also returns it to
+	// the caller, so misses and hits look the same. This is the default.
+	TransformReturn TransformMode = iota
+
+	// TransformCacheOnly caches the transformed result but returns the
+	// original to the caller that ran the executor. Later cache hits still
+	// receive the transformed form.
+	TransformCacheOnly
+)
+
+// WithResultTransform applies fn to successful executor results before they
+// are stored. If fn fails, the original result is returned uncached.
+func WithResultTransform(fn ResultTransform, mode TransformMode) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		m.transform = fn
+		m.transformMode = mode
+	}
+}
+
+// transformResult returns the bytes to store and the bytes to return for
+// result. On a transform error nothing should be stored and returned is
+// the original result.
+func (m *CacheMiddleware) transformResult(toolID string, result []byte) (stored, returned []byte, err error) {
+	if m.transform == nil {
+		return result, result, nil
+	}
+	transformed, err := m.transform(toolID, result)
+	if err != nil {
+		return nil, result, err
+	}
+	if m.transformMode == TransformCacheOnly {
+		return transformed, result, nil
+	}
+	return transformed, transformed, nil
+}
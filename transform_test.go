@@ -0,0 +1,112 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func compactJSON(_ string, result []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, result); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func TestResultTransform_Return(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(cache, keyer, DefaultPolicy(), nil,
+		WithResultTransform(compactJSON, TransformReturn))
+	executor := &mockExecutor{result: []byte("{ \"a\": 1 }")}
+	ctx := context.Background()
+
+	got, err := mw.Execute(ctx, "tool", "in", nil, executor.execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"a":1}` {
+		t.Errorf("returned %q, want transformed", got)
+	}
+
+	key, _ := keyer.Key("tool", "in")
+	stored, _ := cache.Get(ctx, key)
+	if string(stored) != `{"a":1}` {
+		t.Errorf("stored %q, want transformed", stored)
+	}
+}
+
+func TestResultTransform_CacheOnly(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(cache, keyer, DefaultPolicy(), nil,
+		WithResultTransform(compactJSON, TransformCacheOnly))
+	original := "{ \"a\": 1 }"
+	executor := &mockExecutor{result: []byte(original)}
+	ctx := context.Background()
+
+	got, _ := mw.Execute(ctx, "tool", "in", nil, executor.execute)
+	if string(got) != original {
+		t.Errorf("returned %q, want original", got)
+	}
+
+	key, _ := keyer.Key("tool", "in")
+	stored, _ := cache.Get(ctx, key)
+	if string(stored) != `{"a":1}` {
+		t.Errorf("stored %q, want transformed", stored)
+	}
+}
+
+func TestResultTransform_ErrorSkipsCaching(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(cache, keyer, DefaultPolicy(), nil,
+		WithResultTransform(compactJSON, TransformReturn))
+	executor := &mockExecutor{result: []byte("not json")}
+	ctx := context.Background()
+
+	got, err := mw.Execute(ctx, "tool", "in", nil, executor.execute)
+	if err != nil || string(got) != "not json" {
+		t.Fatalf("expected original result, got %q, %v", got, err)
+	}
+
+	key, _ := keyer.Key("tool", "in")
+	if _, ok := cache.Get(ctx, key); ok {
+		t.Error("failed transform should not be cached")
+	}
+}
+
+func TestResultTransform_Warm(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	keyer := NewDefaultKeyer()
+	errBad := errors.New("bad")
+	transform := func(toolID string, result []byte) ([]byte, error) {
+		if toolID == "bad" {
+			return nil, errBad
+		}
+		return compactJSON(toolID, result)
+	}
+	mw := NewCacheMiddleware(cache, keyer, DefaultPolicy(), nil,
+		WithResultTransform(transform, TransformReturn))
+	executor := func(context.Context, string, any) ([]byte, error) {
+		return []byte("{ \"a\": 1 }"), nil
+	}
+	ctx := context.Background()
+
+	results := mw.WarmAll(ctx, []WarmRequest{{ToolID: "good"}, {ToolID: "bad"}}, executor)
+	if results[0].Status != WarmStored {
+		t.Errorf("good status = %v", results[0].Status)
+	}
+	if results[1].Status != WarmFailed || !errors.Is(results[1].Err, errBad) {
+		t.Errorf("bad result = %+v", results[1])
+	}
+
+	key, _ := keyer.Key("good", nil)
+	stored, _ := cache.Get(ctx, key)
+	if string(stored) != `{"a":1}` {
+		t.Errorf("warmed value %q, want transformed", stored)
+	}
+}
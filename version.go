@@ -0,0 +1,33 @@
+package toolcache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrVersionConflict is returned by MemoryCache.SetWithMeta when a
+// versioned write does not supersede the live entry. With
+// EntryMeta.Version set, a write is accepted if the key has no live
+// entry, the live entry is unversioned, or the write's version is higher;
+// a write with the same version succeeds only if it stores the same
+// value, so replays are idempotent. Anything else is rejected and leaves
+// the entry unchanged, making the highest version win regardless of the
+// order in which concurrent writers arrive. Unversioned writes (Version
+// 0) always overwrite.
+var ErrVersionConflict = errors.New("toolcache: version conflict")
+
+// checkVersionLocked applies the ErrVersionConflict rule to a write of
+// value at version over old, the entry currently stored under key.
+// Callers must hold c.mu for writing.
+func (c *MemoryCache) checkVersionLocked(key string, old *cacheEntry, value []byte, version uint64, now time.Time) error {
+	if version == 0 || old == nil || old.thunk != nil || c.staleLocked(key, old, now) {
+		return nil
+	}
+	current := old.meta.Version
+	if current == 0 || version > current || (version == current && bytes.Equal(old.value, value)) {
+		return nil
+	}
+	return fmt.Errorf("%w: key %q is at version %d, write has version %d", ErrVersionConflict, key, current, version)
+}
@@ -0,0 +1,168 @@
+package toolcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVersion_ConflictRule(t *testing.T) {
+	for name, opts := range map[string][]MemoryCacheOption{
+		"default": nil,
+		"pooled":  {WithEntryPooling()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewMemoryCacheWithOptions(DefaultPolicy(), opts...)
+			ctx := context.Background()
+			set := func(value string, version uint64) error {
+				return c.SetWithMeta(ctx, "k", []byte(value), EntryMeta{Version: version}, time.Hour)
+			}
+
+			if err := set("v2", 2); err != nil {
+				t.Fatalf("first write: %v", err)
+			}
+			if err := set("v1", 1); !errors.Is(err, ErrVersionConflict) {
+				t.Errorf("older write = %v, want ErrVersionConflict", err)
+			}
+			if err := set("other", 2); !errors.Is(err, ErrVersionConflict) {
+				t.Errorf("same version, different value = %v, want ErrVersionConflict", err)
+			}
+			if err := set("v2", 2); err != nil {
+				t.Errorf("replayed write = %v, want nil", err)
+			}
+			if got, meta, _ := c.GetWithMeta(ctx, "k"); string(got) != "v2" || meta.Version != 2 {
+				t.Errorf("after rejected writes = %q at version %d, want v2 at 2", got, meta.Version)
+			}
+
+			if err := set("v3", 3); err != nil {
+				t.Errorf("newer write: %v", err)
+			}
+			if err := set("plain", 0); err != nil {
+				t.Errorf("unversioned write: %v", err)
+			}
+			if err := set("v1", 1); err != nil {
+				t.Errorf("write over unversioned entry: %v", err)
+			}
+			if got, meta, _ := c.GetWithMeta(ctx, "k"); string(got) != "v1" || meta.Version != 1 {
+				t.Errorf("GetWithMeta() = %q at version %d, want v1 at 1", got, meta.Version)
+			}
+		})
+	}
+}
+
+func TestVersion_ExpiredEntryDoesNotConflict(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	c := NewMemoryCacheWithOptions(DefaultPolicy(), WithClock(clock))
+	ctx := context.Background()
+
+	_ = c.SetWithMeta(ctx, "k", []byte("new"), EntryMeta{Version: 5}, time.Minute)
+	clock.Advance(time.Hour)
+	if err := c.SetWithMeta(ctx, "k", []byte("old"), EntryMeta{Version: 1}, time.Minute); err != nil {
+		t.Errorf("write over expired entry = %v, want nil", err)
+	}
+
+	// Pinned entries stay live past their TTL, so they still conflict.
+	_ = c.Pin(ctx, "k")
+	clock.Advance(time.Hour)
+	if err := c.SetWithMeta(ctx, "k", []byte("older"), EntryMeta{Version: 1}, time.Minute); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("write over pinned entry = %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestVersion_ConcurrentSetsHighestWins(t *testing.T) {
+	for name, opts := range map[string][]MemoryCacheOption{
+		"default": nil,
+		"pooled":  {WithEntryPooling()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewMemoryCacheWithOptions(DefaultPolicy(), opts...)
+			ctx := context.Background()
+			const writers = 64
+
+			var (
+				wg        sync.WaitGroup
+				mu        sync.Mutex
+				conflicts int
+			)
+			for v := uint64(1); v <= writers; v++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					value := []byte(fmt.Sprintf("v%d", v))
+					err := c.SetWithMeta(ctx, "k", value, EntryMeta{Version: v}, time.Hour)
+					if err != nil {
+						if !errors.Is(err, ErrVersionConflict) {
+							t.Errorf("SetWithMeta(v%d) = %v", v, err)
+						}
+						mu.Lock()
+						conflicts++
+						mu.Unlock()
+					}
+					// The stored version never goes backwards.
+					if _, meta, ok := c.GetWithMeta(ctx, "k"); !ok || meta.Version < v && err == nil {
+						t.Errorf("after accepted v%d, stored version = %d", v, meta.Version)
+					}
+				}()
+			}
+			wg.Wait()
+
+			got, meta, ok := c.GetWithMeta(ctx, "k")
+			if !ok || meta.Version != writers || !bytes.Equal(got, []byte(fmt.Sprintf("v%d", writers))) {
+				t.Errorf("final entry = %q at version %d, want v%d", got, meta.Version, writers)
+			}
+			if conflicts >= writers {
+				t.Errorf("conflicts = %d, want fewer than %d", conflicts, writers)
+			}
+		})
+	}
+}
+
+func TestVersion_SnapshotRoundTrip(t *testing.T) {
+	src := NewMemoryCache(DefaultPolicy())
+	ctx := context.Background()
+	_ = src.SetWithMeta(ctx, "k", []byte("v"), EntryMeta{Version: 7}, time.Hour)
+
+	var buf bytes.Buffer
+	if err := src.WriteSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"version":7`) {
+		t.Errorf("snapshot missing version: %s", buf.String())
+	}
+	dst := NewMemoryCache(DefaultPolicy())
+	if _, err := dst.ReadSnapshot(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.SetWithMeta(ctx, "k", []byte("stale"), EntryMeta{Version: 6}, time.Hour); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("write older than restored version = %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestVersion_SetRetrySkipsConflicts(t *testing.T) {
+	sets := 0
+	cache := CacheFuncs{
+		SetFunc: func(context.Context, string, []byte, time.Duration) error {
+			sets++
+			return fmt.Errorf("store: %w", ErrVersionConflict)
+		},
+	}
+	obs := &recordingObserver{}
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithSetRetry(3, time.Millisecond), WithObserver(obs))
+	executor := &mockExecutor{result: []byte("v")}
+
+	if _, err := mw.Execute(context.Background(), "tool", 1, nil, executor.execute); err != nil {
+		t.Fatal(err)
+	}
+	if sets != 1 {
+		t.Errorf("Set attempts = %d, want 1", sets)
+	}
+	if events := obs.byKind(EventSetFailed); len(events) != 1 || !errors.Is(events[0].Err, ErrVersionConflict) {
+		t.Errorf("EventSetFailed = %+v, want one version conflict", events)
+	}
+}
@@ -0,0 +1,221 @@
+package toolcache
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// DefaultWarmConcurrency is the number of executors WarmAll runs at once
+// unless overridden with WithWarmConcurrency.
+const DefaultWarmConcurrency = 4
+
+// WithWarmConcurrency bounds how many executors WarmAll runs concurrently.
+// Values below 1 are treated as 1.
+func WithWarmConcurrency(n int) MiddlewareOption {
+	return func(m *CacheMiddleware) {
+		if n < 1 {
+			n = 1
+		}
+		m.warmConcurrency = n
+	}
+}
+
+// WarmRequest describes a tool invocation whose result should be prewarmed.
+type WarmRequest struct {
+	ToolID string
+	Input  any
+	Tags   []string
+}
+
+// WarmStatus describes what WarmAll did for a single request.
+type WarmStatus int
+
+const (
+	// WarmStored means the executor ran and its result was cached.
+	WarmStored WarmStatus = iota
+
+	// WarmFresh means a fresh entry already existed; the executor was not run.
+	WarmFresh
+
+	// WarmSkipped means the request is not cacheable (skip rule, zero TTL,
+	// or a key that fails validation or WithOversizedKeyMode) and the
+	// executor was not run, or the result was not cached: the executor
+	// returned ErrDoNotCache, an empty result that the policy does not
+	// cache, a result that WithTTLFromResult reports as already stale, or
+	// a result over WithMaxResultBytes, or less than
+	// WithMinRemainingDeadline was left on the context.
+	WarmSkipped
+
+	// WarmFailed means the executor returned an error or the result could
+	// not be stored, even with WithSetRetry; see WarmResult.Err.
+	WarmFailed
+)
+
+func (s WarmStatus) String() string {
+	switch s {
+	case WarmStored:
+		return "stored"
+	case WarmFresh:
+		return "fresh"
+	case WarmSkipped:
+		return "skipped"
+	case WarmFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// WarmResult reports the outcome of warming one WarmRequest.
+type WarmResult struct {
+	Request WarmRequest
+	Key     string
+	Status  WarmStatus
+	Err     error
+}
+
+// WarmAll populates the cache for requests, running executors concurrently
+// (bounded by WithWarmConcurrency). It honors skip rules, stores results
+// with the TTL Execute would use, and does not re-execute requests that
+// already have a fresh entry.
+//
+// Results are returned in the same order as requests.
+func (m *CacheMiddleware) WarmAll(ctx context.Context, requests []WarmRequest, executor ToolExecutor) []WarmResult {
+	results := make([]WarmResult, len(requests))
+	sem := make(chan struct{}, m.warmConcurrency)
+	var wg sync.WaitGroup
+
+	for i, req := range requests {
+		results[i] = WarmResult{Request: req, Status: WarmSkipped}
+
+		if m.cache == nil {
+			results[i].Status = WarmFailed
+			results[i].Err = ErrNilCache
+			continue
+		}
+
+		if m.shouldSkip(ctx, req.ToolID, req.Tags) {
+			continue
+		}
+		if m.policy.EffectiveTTL(0) <= 0 {
+			continue
+		}
+		key, err := m.safeKey(req.ToolID, req.Input)
+		if err == nil {
+			key, err = m.checkKey(m.tagKey(key, req.Tags))
+		}
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		results[i].Key = key
+
+		if _, ok := m.cache.Get(ctx, key); ok {
+			results[i].Status = WarmFresh
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Status = WarmFailed
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(res *WarmResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			value, err := m.runExecutor(ctx, res.Request.ToolID, res.Request.Input, executor)
+			if isDoNotCache(err) {
+				res.Status = WarmSkipped
+				return
+			}
+			if err != nil {
+				res.Status = WarmFailed
+				res.Err = err
+				return
+			}
+			storeTTL := m.resultTTL(res.Request.ToolID, value)
+			if storeTTL <= 0 {
+				res.Status = WarmSkipped
+				return
+			}
+			value, _, err = m.transformResult(res.Request.ToolID, value)
+			if err != nil {
+				res.Status = WarmFailed
+				res.Err = err
+				return
+			}
+			if m.oversizedResult(res.Request.ToolID, value) {
+				res.Status = WarmSkipped
+				return
+			}
+			if m.deadlineTooShort(ctx) {
+				res.Status = WarmSkipped
+				return
+			}
+			if err := m.store(ctx, res.Request.ToolID, res.Key, value, storeTTL, nil); err != nil {
+				res.Status = WarmFailed
+				res.Err = err
+				return
+			}
+			m.stats.record(res.Request.ToolID, ToolStats{BytesStored: uint64(len(value))})
+			m.logOp(ctx, OpSet, res.Request.ToolID, res.Key)
+			res.Status = WarmStored
+		}(&results[i])
+	}
+
+	wg.Wait()
+	return results
+}
+
+// KeyAccessCounts tallies cache accesses (hits and misses) per key from an
+// operation log, as returned by RecentOps. Skips and sets are not counted.
+// Pass the result to PrioritizeWarm.
+func KeyAccessCounts(ops []OpRecord) map[string]uint64 {
+	counts := make(map[string]uint64)
+	for _, op := range ops {
+		if op.Key != "" && (op.Result == OpHit || op.Result == OpMiss) {
+			counts[op.Key]++
+		}
+	}
+	return counts
+}
+
+// PrioritizeWarm returns requests reordered so those whose keys were
+// accessed most often, per counts, come first. WarmAll starts executors in
+// request order, so hot keys are warmed before cold ones. Requests with
+// equal counts keep their relative order; requests whose key cannot be
+// derived or would not be cached are treated as never accessed. requests
+// is not modified.
+func (m *CacheMiddleware) PrioritizeWarm(requests []WarmRequest, counts map[string]uint64) []WarmRequest {
+	type ranked struct {
+		req   WarmRequest
+		count uint64
+	}
+	ranks := make([]ranked, len(requests))
+	for i, req := range requests {
+		ranks[i].req = req
+		key, err := m.safeKey(req.ToolID, req.Input)
+		if err == nil {
+			key, err = m.checkKey(m.tagKey(key, req.Tags))
+		}
+		if err == nil && key != "" {
+			ranks[i].count = counts[key]
+		}
+	}
+	sort.SliceStable(ranks, func(i, j int) bool { return ranks[i].count > ranks[j].count })
+
+	ordered := make([]WarmRequest, len(ranks))
+	for i, r := range ranks {
+		ordered[i] = r.req
+	}
+	return ordered
+}
@@ -0,0 +1,220 @@
+package toolcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWarmAll_OnlyExecutesMissing(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(cache, keyer, DefaultPolicy(), nil)
+	ctx := context.Background()
+
+	// Pre-populate one entry
+	existingKey, _ := keyer.Key("search", map[string]any{"q": "cached"})
+	_ = cache.Set(ctx, existingKey, []byte("old"), time.Minute)
+
+	var mu sync.Mutex
+	executed := map[string]int{}
+	executor := func(_ context.Context, toolID string, input any) ([]byte, error) {
+		q := input.(map[string]any)["q"].(string)
+		mu.Lock()
+		executed[q]++
+		mu.Unlock()
+		return []byte("warm:" + q), nil
+	}
+
+	requests := []WarmRequest{
+		{ToolID: "search", Input: map[string]any{"q": "cached"}, Tags: []string{"read"}},
+		{ToolID: "search", Input: map[string]any{"q": "a"}, Tags: []string{"read"}},
+		{ToolID: "search", Input: map[string]any{"q": "b"}, Tags: []string{"read"}},
+		{ToolID: "delete", Input: map[string]any{"q": "unsafe"}, Tags: []string{"delete"}},
+	}
+
+	results := mw.WarmAll(ctx, requests, executor)
+	if len(results) != len(requests) {
+		t.Fatalf("got %d results, want %d", len(results), len(requests))
+	}
+
+	wantStatus := []WarmStatus{WarmFresh, WarmStored, WarmStored, WarmSkipped}
+	for i, res := range results {
+		if res.Status != wantStatus[i] {
+			t.Errorf("result %d status = %s, want %s", i, res.Status, wantStatus[i])
+		}
+		if res.Err != nil {
+			t.Errorf("result %d unexpected error: %v", i, res.Err)
+		}
+	}
+
+	if executed["cached"] != 0 || executed["unsafe"] != 0 {
+		t.Errorf("fresh and skipped requests should not execute: %v", executed)
+	}
+	if executed["a"] != 1 || executed["b"] != 1 {
+		t.Errorf("missing requests should execute once: %v", executed)
+	}
+
+	if v, ok := cache.Get(ctx, results[1].Key); !ok || string(v) != "warm:a" {
+		t.Errorf("warmed entry = %q ok=%v", v, ok)
+	}
+	if v, _ := cache.Get(ctx, existingKey); string(v) != "old" {
+		t.Errorf("fresh entry should be untouched, got %q", v)
+	}
+}
+
+func TestWarmAll_ReportsErrors(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil)
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	executor := func(_ context.Context, _ string, input any) ([]byte, error) {
+		if input.(map[string]any)["fail"] == true {
+			return nil, boom
+		}
+		return []byte("ok"), nil
+	}
+
+	results := mw.WarmAll(ctx, []WarmRequest{
+		{ToolID: "t", Input: map[string]any{"fail": true}},
+		{ToolID: "t", Input: map[string]any{"fail": false}},
+		{ToolID: "t", Input: struct{}{}},
+	}, executor)
+
+	if results[0].Status != WarmFailed || !errors.Is(results[0].Err, boom) {
+		t.Errorf("result 0 = %s/%v, want failed/boom", results[0].Status, results[0].Err)
+	}
+	if results[1].Status != WarmStored {
+		t.Errorf("result 1 = %s, want stored", results[1].Status)
+	}
+	if results[2].Status != WarmSkipped || results[2].Err == nil {
+		t.Errorf("result 2 = %s/%v, want skipped with key error", results[2].Status, results[2].Err)
+	}
+}
+
+func TestWarmAll_NoCachePolicySkips(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(NoCachePolicy()), NewDefaultKeyer(), NoCachePolicy(), nil)
+	executor := &mockExecutor{result: []byte("x")}
+
+	results := mw.WarmAll(context.Background(), []WarmRequest{{ToolID: "t", Input: nil}}, executor.execute)
+	if results[0].Status != WarmSkipped {
+		t.Errorf("status = %s, want skipped", results[0].Status)
+	}
+	if executor.calls != 0 {
+		t.Errorf("executor should not run with caching disabled, got %d calls", executor.calls)
+	}
+}
+
+func TestWarmAll_BoundedConcurrency(t *testing.T) {
+	const limit = 2
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithWarmConcurrency(limit))
+
+	var inFlight, peak atomic.Int32
+	executor := func(_ context.Context, _ string, _ any) ([]byte, error) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		inFlight.Add(-1)
+		return []byte("ok"), nil
+	}
+
+	requests := make([]WarmRequest, 10)
+	for i := range requests {
+		requests[i] = WarmRequest{ToolID: "t", Input: map[string]any{"i": i}}
+	}
+	for _, res := range mw.WarmAll(context.Background(), requests, executor) {
+		if res.Status != WarmStored {
+			t.Errorf("status = %s, want stored", res.Status)
+		}
+	}
+	if got := peak.Load(); got > limit {
+		t.Errorf("peak concurrency = %d, want <= %d", got, limit)
+	}
+}
+
+func TestPrioritizeWarm(t *testing.T) {
+	keyer := NewDefaultKeyer()
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), keyer, DefaultPolicy(), nil)
+	key := func(q string) string {
+		k, _ := keyer.Key("search", map[string]any{"q": q})
+		return k
+	}
+
+	requests := []WarmRequest{
+		{ToolID: "search", Input: map[string]any{"q": "cold"}},
+		{ToolID: "search", Input: map[string]any{"q": "warm"}},
+		{ToolID: "search", Input: struct{}{}}, // key error
+		{ToolID: "search", Input: map[string]any{"q": "hot"}},
+		{ToolID: "search", Input: map[string]any{"q": "also-cold"}},
+	}
+	counts := map[string]uint64{key("hot"): 50, key("warm"): 7}
+
+	got := mw.PrioritizeWarm(requests, counts)
+	want := []any{"hot", "warm", "cold", nil, "also-cold"}
+	for i, req := range got {
+		q := any(nil)
+		if m, ok := req.Input.(map[string]any); ok {
+			q = m["q"]
+		}
+		if q != want[i] {
+			t.Errorf("position %d = %v, want %v", i, q, want[i])
+		}
+	}
+	if requests[0].Input.(map[string]any)["q"] != "cold" {
+		t.Error("PrioritizeWarm must not reorder its input")
+	}
+}
+
+func TestKeyAccessCounts(t *testing.T) {
+	ops := []OpRecord{
+		{Result: OpMiss, Key: "a"},
+		{Result: OpSet, Key: "a"},
+		{Result: OpHit, Key: "a"},
+		{Result: OpHit, Key: "b"},
+		{Result: OpSkip},
+	}
+	got := KeyAccessCounts(ops)
+	if len(got) != 2 || got["a"] != 2 || got["b"] != 1 {
+		t.Errorf("KeyAccessCounts() = %v, want a:2 b:1", got)
+	}
+}
+
+func TestPrioritizeWarm_FromOpLog(t *testing.T) {
+	mw := NewCacheMiddleware(NewMemoryCache(DefaultPolicy()), NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithOpLog(100))
+	executor := &mockExecutor{result: []byte("ok")}
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, _ = mw.Execute(ctx, "t", "popular", nil, executor.execute)
+	}
+	_, _ = mw.Execute(ctx, "t", "rare", nil, executor.execute)
+
+	requests := []WarmRequest{{ToolID: "t", Input: "rare"}, {ToolID: "t", Input: "popular"}}
+	got := mw.PrioritizeWarm(requests, KeyAccessCounts(mw.RecentOps()))
+	if got[0].Input != "popular" {
+		t.Errorf("first request = %v, want the most accessed key", got[0].Input)
+	}
+}
+
+func TestWarmAll_MinRemainingDeadline(t *testing.T) {
+	cache := NewMemoryCache(DefaultPolicy())
+	mw := NewCacheMiddleware(cache, NewDefaultKeyer(), DefaultPolicy(), nil,
+		WithMinRemainingDeadline(time.Second))
+	executor := &mockExecutor{result: []byte("v")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	results := mw.WarmAll(ctx, []WarmRequest{{ToolID: "tool", Input: 1}}, executor.execute)
+	if results[0].Status != WarmSkipped || cache.Len() != 0 {
+		t.Errorf("WarmAll() = %+v with %d entries, want the store skipped near the deadline", results[0], cache.Len())
+	}
+}